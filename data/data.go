@@ -191,6 +191,8 @@ type Move struct {
 	Score int
 	Move  int
 	Depth int
+	// Ponder is the reply expected to Move, NoMove if there is none
+	Ponder int
 }
 
 type SearchInfo struct {
@@ -207,6 +209,13 @@ type SearchInfo struct {
 	PostThinking bool
 
 	Node int64
+	// NodeLimit stops the search once a worker has visited this many nodes,
+	// zero searches without a limit
+	NodeLimit int64
+	// SearchMoves restricts the root to these moves, nil searches every move
+	SearchMoves []int
+	// ExcludeMoves are root moves that are not searched
+	ExcludeMoves []int
 
 	Quit      int
 	Stopped   bool
@@ -350,9 +359,15 @@ func initSquareMask() [BoardSize]uint64 {
 	return sqm
 }
 
+// keySeed fixes the hash keys between runs so a saved transposition table
+// can be loaded again
+const keySeed = 0x2545F4914F6CDD1D
+
+var keyRand = rand.New(rand.NewSource(keySeed))
+
 // GenerateRandomUint64 returns a random uint64
 func generateRandomUint64() uint64 {
-	return rand.Uint64()
+	return keyRand.Uint64()
 }
 
 // setPieceKeys sets the keys to a random uint64
@@ -4,6 +4,7 @@ import (
 	"fmt"
 
 	"github.com/AdamGriffiths31/ChessEngine/data"
+	"github.com/AdamGriffiths31/ChessEngine/util"
 	"github.com/AdamGriffiths31/ChessEngine/validate"
 )
 
@@ -24,6 +25,11 @@ func FirstSquare(b uint64) int {
 	return index64[(((b-1)^b)*0x03f79d71b4cb0a89)>>58]
 }
 
+// KingDistance returns the number of king moves between two 64 based squares
+func KingDistance(from, to int) int {
+	return util.Max(util.Abs(from%8-to%8), util.Abs(from/8-to/8))
+}
+
 // SetPieceAtSquare updates the bitboard for the corresponding
 // piece to an active occupancy
 func (b *Bitboard) SetPieceAtSquare(sq64, piece int) {
@@ -251,6 +257,32 @@ func (b *Bitboard) GetPieces(side, piece int) uint64 {
 	panic(fmt.Errorf("GetPieces: could not find bitboard for %v %v", piece, side))
 }
 
+// AttackersTo returns side's pieces attacking sq64, looking out from the
+// square rather than building the attacks of every piece
+func (b *Bitboard) AttackersTo(sq64, side int) uint64 {
+	return b.attackersTo(sq64, side, b.Pieces)
+}
+
+// attackersTo is AttackersTo with only the occupied pieces on the board, so
+// sliders can be seen through pieces already exchanged off
+func (b *Bitboard) attackersTo(sq64, side int, occupied uint64) uint64 {
+	mask := uint64(1) << sq64
+	if side == data.White {
+		pawns := b.AllBlackPawnAttacks(mask) & b.WhitePawn
+		return (pawns |
+			PreCalculatedKnightMoves[sq64]&b.WhiteKnight |
+			PreCalculatedKingMoves[sq64]&b.WhiteKing |
+			data.GetBishopAttacks(occupied, sq64)&(b.WhiteBishop|b.WhiteQueen) |
+			data.GetRookAttacks(occupied, sq64)&(b.WhiteRook|b.WhiteQueen)) & occupied
+	}
+	pawns := b.AllWhitePawnAttacks(mask) & b.BlackPawn
+	return (pawns |
+		PreCalculatedKnightMoves[sq64]&b.BlackKnight |
+		PreCalculatedKingMoves[sq64]&b.BlackKing |
+		data.GetBishopAttacks(occupied, sq64)&(b.BlackBishop|b.BlackQueen) |
+		data.GetRookAttacks(occupied, sq64)&(b.BlackRook|b.BlackQueen)) & occupied
+}
+
 // AllWhitePawnAttacks returns all white pawn attacks for the given bitboard
 func (b *Bitboard) AllWhitePawnAttacks(bitboard uint64) uint64 {
 	return ((bitboard & ^data.FileAMask) << 7) | ((bitboard & ^data.FileHMask) << 9)
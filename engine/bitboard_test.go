@@ -1,6 +1,10 @@
 package engine
 
-import "testing"
+import (
+	"testing"
+
+	"github.com/AdamGriffiths31/ChessEngine/data"
+)
 
 func TestCountBits(t *testing.T) {
 	var b = Bitboard{Pieces: 0x0101010101010101}
@@ -15,3 +19,42 @@ func TestCountBitsEmpty(t *testing.T) {
 		t.Errorf("Expected 8 but got %v", b.CountBits(b.Pieces))
 	}
 }
+
+func TestKingDistance(t *testing.T) {
+	tests := []struct {
+		from, to, want int
+	}{
+		{0, 0, 0},
+		{0, 63, 7},
+		{63, 0, 7},
+		{4, 60, 7},
+		{27, 18, 1},
+		{7, 13, 2},
+	}
+	for _, test := range tests {
+		if got := KingDistance(test.from, test.to); got != test.want {
+			t.Errorf("%v to %v: expected %v but got %v", test.from, test.to, test.want, got)
+		}
+	}
+}
+
+func TestAttackersTo(t *testing.T) {
+	// the queens on a1 and b8 are blocked by the pawns on d4 and d6
+	game := ParseFen("kq2r2b/5n2/3p4/8/2NP1K2/8/8/Q3R3 w - - 0 1")
+	b := &game.Position().Board
+	squares := func(sqs ...int) uint64 {
+		var bb uint64
+		for _, sq := range sqs {
+			bb |= uint64(1) << data.Square120ToSquare64[sq]
+		}
+		return bb
+	}
+	e5 := data.Square120ToSquare64[data.E5]
+
+	if got, want := b.AttackersTo(e5, data.White), squares(data.C4, data.D4, data.F4, data.E1); got != want {
+		t.Errorf("Expected white attackers %x but got %x", want, got)
+	}
+	if got, want := b.AttackersTo(e5, data.Black), squares(data.D6, data.F7, data.H8, data.E8); got != want {
+		t.Errorf("Expected black attackers %x but got %x", want, got)
+	}
+}
@@ -0,0 +1,91 @@
+package engine
+
+import (
+	"math/bits"
+
+	"github.com/AdamGriffiths31/ChessEngine/data"
+)
+
+// MoveGivesCheck checks if the move attacks the enemy king, either directly
+// or by uncovering a slider, using the bitboards as they would be after the
+// move rather than making it
+func (p *Position) MoveGivesCheck(move int) bool {
+	from := data.Square120ToSquare64[data.FromSquare(move)]
+	to := data.Square120ToSquare64[data.ToSquare(move)]
+	fromBB, toBB := uint64(1)<<from, uint64(1)<<to
+
+	piece := p.Board.PieceAt(from)
+	if promoted := data.Promoted(move); promoted != data.Empty {
+		piece = promoted
+	}
+
+	b := &p.Board
+	var king, pawns, knights, diagonal, straight uint64
+	if p.Side == data.White {
+		king = b.BlackKing
+		pawns, knights = b.WhitePawn, b.WhiteKnight
+		diagonal, straight = b.WhiteBishop|b.WhiteQueen, b.WhiteRook|b.WhiteQueen
+	} else {
+		king = b.WhiteKing
+		pawns, knights = b.BlackPawn, b.BlackKnight
+		diagonal, straight = b.BlackBishop|b.BlackQueen, b.BlackRook|b.BlackQueen
+	}
+	pawns &^= fromBB
+	knights &^= fromBB
+	diagonal &^= fromBB
+	straight &^= fromBB
+	occupied := b.Pieces&^fromBB | toBB
+
+	switch pieceKind(piece) {
+	case data.WP:
+		pawns |= toBB
+	case data.WN:
+		knights |= toBB
+	case data.WB:
+		diagonal |= toBB
+	case data.WR:
+		straight |= toBB
+	case data.WQ:
+		diagonal |= toBB
+		straight |= toBB
+	}
+
+	if move&data.MFLAGEP != 0 {
+		if p.Side == data.White {
+			occupied &^= toBB >> 8
+		} else {
+			occupied &^= toBB << 8
+		}
+	}
+	if move&data.MFLAGGCA != 0 {
+		rookFrom, rookTo := castleRookSquares(data.ToSquare(move))
+		straight = straight&^(uint64(1)<<rookFrom) | uint64(1)<<rookTo
+		occupied = occupied&^(uint64(1)<<rookFrom) | uint64(1)<<rookTo
+	}
+
+	if p.Side == data.White && b.AllWhitePawnAttacks(pawns)&king != 0 {
+		return true
+	}
+	if p.Side == data.Black && b.AllBlackPawnAttacks(pawns)&king != 0 {
+		return true
+	}
+	sq := bits.TrailingZeros64(king)
+	return PreCalculatedKnightMoves[sq]&knights != 0 ||
+		data.GetBishopAttacks(occupied, sq)&diagonal != 0 ||
+		data.GetRookAttacks(occupied, sq)&straight != 0
+}
+
+// castleRookSquares returns the 64 based squares the rook moves between when
+// the king castles to the given square
+func castleRookSquares(kingTo int) (int, int) {
+	switch kingTo {
+	case data.G1:
+		return data.Square120ToSquare64[data.H1], data.Square120ToSquare64[data.F1]
+	case data.C1:
+		return data.Square120ToSquare64[data.A1], data.Square120ToSquare64[data.D1]
+	case data.G8:
+		return data.Square120ToSquare64[data.H8], data.Square120ToSquare64[data.F8]
+	default:
+		return data.Square120ToSquare64[data.A8], data.Square120ToSquare64[data.D8]
+	}
+}
@@ -0,0 +1,83 @@
+package engine
+
+import (
+	"testing"
+
+	"github.com/AdamGriffiths31/ChessEngine/data"
+	"github.com/AdamGriffiths31/ChessEngine/io"
+)
+
+func TestMoveGivesCheck(t *testing.T) {
+	tests := []struct {
+		fen  string
+		move string
+		want bool
+	}{
+		{"4k3/8/8/8/8/8/8/R3K3 w - - 0 1", "a1a8", true},
+		{"4k3/8/8/8/8/8/8/R3K3 w - - 0 1", "a1a7", false},
+		{"4k3/8/8/8/8/8/8/4K1N1 w - - 0 1", "g1f3", false},
+		{"4k3/8/8/8/8/8/8/4K1N1 w - - 0 1", "g1e2", false},
+		{"4k3/8/5N2/8/8/8/8/4K3 w - - 0 1", "f6d7", false},
+		{"4k3/8/8/6N1/8/8/8/4K3 w - - 0 1", "g5f7", false},
+		{"4k3/8/8/8/8/8/3P4/4K3 w - - 0 1", "d2d4", false},
+		{"4k3/8/8/8/3P4/8/8/4K3 w - - 0 1", "d4d5", false},
+		{"4k3/8/3P4/8/8/8/8/4K3 w - - 0 1", "d6d7", true},
+		{"4k3/8/8/8/8/8/4N3/4RK2 w - - 0 1", "e2c3", true},
+		{"4k3/8/8/8/8/8/4N3/4RK2 w - - 0 1", "e2g3", true},
+		{"4k3/8/8/8/8/2B5/3N4/5K2 w - - 0 1", "d2f3", false},
+		{"4k3/8/2N5/8/B7/8/8/5K2 w - - 0 1", "c6b4", true},
+		{"5k2/8/8/8/8/8/8/4K2R w K - 0 1", "e1g1", true},
+		{"3k4/8/8/8/8/8/8/R3K3 w Q - 0 1", "e1c1", true},
+		{"4k3/8/8/8/8/8/8/4K2R w K - 0 1", "e1g1", false},
+		{"1k6/2P5/8/8/8/8/8/4K3 w - - 0 1", "c7c8q", true},
+		{"k7/2P5/8/8/8/8/8/4K3 w - - 0 1", "c7c8r", true},
+		{"k7/2P5/8/8/8/8/8/4K3 w - - 0 1", "c7c8n", false},
+		{"3k4/2P5/8/8/8/8/8/4K3 w - - 0 1", "c7c8n", false},
+		{"4k3/2P5/8/8/8/8/8/4K3 w - - 0 1", "c7c8n", false},
+		{"8/8/8/k2pP2R/8/8/8/4K3 w - d6 0 1", "e5d6", true},
+		{"4k3/8/8/3pP3/8/8/8/4K3 w - d6 0 1", "e5d6", false},
+		{"4k3/8/8/8/8/8/8/4K3 b - - 0 1", "e8d8", false},
+		{"4k3/8/8/8/8/8/4q3/K7 b - - 0 1", "e2a2", true},
+		{"4k3/8/8/8/8/8/4q3/K7 b - - 0 1", "e2e3", false},
+	}
+	for _, test := range tests {
+		game := ParseFen(test.fen)
+		p := game.Position()
+		move := p.ParseMove([]byte(test.move))
+		if move == data.NoMove {
+			t.Fatalf("%v: %v is not a legal move", test.fen, test.move)
+		}
+		if got := p.MoveGivesCheck(move); got != test.want {
+			t.Errorf("%v %v: expected %v but got %v", test.fen, test.move, test.want, got)
+		}
+	}
+}
+
+func TestMoveGivesCheckMatchesMakeMove(t *testing.T) {
+	fens := []string{
+		data.StartFEN,
+		"r3k2r/p1ppqpb1/bn2pnp1/3PN3/1p2P3/2N2Q1p/PPPBBPPP/R3K2R w KQkq - 0 1",
+		"8/2p5/3p4/KP5r/1R3p1k/8/4P1P1/8 w - - 0 1",
+		"r3k2r/Pppp1ppp/1b3nbN/nP6/BBP1P3/q4N2/Pp1P2PP/R2Q1RK1 w kq - 0 1",
+		"rnbq1k1r/pp1Pbppp/2p5/8/2B5/8/PPP1NnPP/RNBQK2R w KQ - 1 8",
+	}
+	for _, fen := range fens {
+		game := ParseFen(fen)
+		p := game.Position()
+		ml := &MoveList{}
+		p.GenerateAllMoves(ml)
+		for i := 0; i < ml.Count; i++ {
+			move := ml.Moves[i].Move
+			got := p.MoveGivesCheck(move)
+			isAllowed, enPas, castlePerm, fifty := p.MakeMove(move)
+			if !isAllowed {
+				continue
+			}
+			want := p.IsKingAttacked(p.Side ^ 1)
+			p.TakeMoveBack(move, enPas, castlePerm, fifty)
+			if got != want {
+				t.Errorf("%v %v: expected %v but got %v", fen, io.PrintMove(move), got, want)
+			}
+		}
+	}
+}
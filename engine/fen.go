@@ -2,12 +2,14 @@ package engine
 
 import (
 	"fmt"
+	"strconv"
 	"strings"
 
 	"github.com/AdamGriffiths31/ChessEngine/data"
 )
 
-// ParseFen updates the Position with data from the fen string
+// ParseFen updates the Position with data from the fen string, the
+// repetition table is started again holding only this position
 func (p *Position) ParseFen(fen string) {
 	p.resetPosition()
 	parts := strings.Fields(fen)
@@ -15,8 +17,84 @@ func (p *Position) ParseFen(fen string) {
 	p.Side = determineSideToPlay(parts[1])
 	p.CastlePermission = parseCastlingAvailability(parts[2])
 	p.EnPassant = parseEnPassantTarget(parts[3])
+	if len(parts) > 4 {
+		p.FiftyMove = parseHalfMoveClock(parts[4])
+	}
+	if len(parts) > 5 {
+		p.GamePly = parseGamePly(parts[5], p.Side)
+	}
 
 	p.PositionKey = p.GeneratePositionKey()
+	p.PieceScore = p.GeneratePieceScore()
+	p.Positions = RepetitionTable{}
+	p.Positions.Add(p.PositionKey)
+}
+
+// ToFEN returns the fen string for the Position
+func (p *Position) ToFEN() string {
+	var sb strings.Builder
+	for rank := data.Rank8; rank >= data.Rank1; rank-- {
+		empty := 0
+		for file := data.FileA; file <= data.FileH; file++ {
+			piece := p.Board.PieceAt(rank*8 + file)
+			if piece == data.Empty {
+				empty++
+				continue
+			}
+			if empty > 0 {
+				sb.WriteByte(byte('0' + empty))
+				empty = 0
+			}
+			sb.WriteByte(fenPieceChars[piece])
+		}
+		if empty > 0 {
+			sb.WriteByte(byte('0' + empty))
+		}
+		if rank != data.Rank1 {
+			sb.WriteByte('/')
+		}
+	}
+
+	sb.WriteByte(' ')
+	sb.WriteString(data.SideChar[p.Side])
+	sb.WriteByte(' ')
+	sb.WriteString(castlingAvailability(p.CastlePermission))
+	sb.WriteByte(' ')
+	sb.WriteString(enPassantTarget(p.EnPassant))
+	fmt.Fprintf(&sb, " %d %d", p.FiftyMove, p.GamePly/2+1)
+	return sb.String()
+}
+
+// fenPieceChars maps each piece to its fen character
+const fenPieceChars = ".PNBRQKpnbrqk"
+
+// castlingAvailability returns the fen castling field for the permissions
+func castlingAvailability(perm int) string {
+	result := ""
+	if perm&data.WhiteKingCastle != 0 {
+		result += "K"
+	}
+	if perm&data.WhiteQueenCastle != 0 {
+		result += "Q"
+	}
+	if perm&data.BlackKingCastle != 0 {
+		result += "k"
+	}
+	if perm&data.BlackQueenCastle != 0 {
+		result += "q"
+	}
+	if result == "" {
+		return "-"
+	}
+	return result
+}
+
+// enPassantTarget returns the fen en passant field for the square
+func enPassantTarget(sq int) string {
+	if sq == data.Empty || sq == data.NoSquare || data.FilesBoard[sq] == data.OffBoard {
+		return "-"
+	}
+	return string([]byte{byte(data.FilesBoard[sq] + 'a'), byte(data.RanksBoard[sq] + '1')})
 }
 
 // resetPosition clears the Position down to default
@@ -24,19 +102,41 @@ func (p *Position) resetPosition() {
 	p.Board = Bitboard{}
 	p.Play = 0
 	p.CastlePermission = 0
-	p.EnPassant = 0
+	p.EnPassant = data.NoSquare
+	p.FiftyMove = 0
 	p.PositionKey = 0
+	p.GamePly = 0
+}
+
+// parseHalfMoveClock converts the half move clock, a clock that is not a
+// number is taken to be zero
+func parseHalfMoveClock(fen string) int {
+	clock, err := strconv.Atoi(fen)
+	if err != nil || clock < 0 {
+		return 0
+	}
+	return clock
+}
+
+// parseGamePly converts the full move number to the plies played
+func parseGamePly(fen string, side int) int {
+	fullMove, err := strconv.Atoi(fen)
+	if err != nil || fullMove < 1 {
+		return 0
+	}
+	return (fullMove-1)*2 + side
 }
 
-// parseEnPassantTarget determines the En Passant square
+// parseEnPassantTarget determines the En Passant square, NoSquare if there
+// is none as MakeMove leaves it
 func parseEnPassantTarget(fen string) int {
 	if fen[0] == '-' || len(fen) == 1 {
-		return data.Empty
+		return data.NoSquare
 	}
 	if sq, ok := data.NameToSquareMap[fen]; ok {
 		return sq
 	}
-	return data.Empty
+	return data.NoSquare
 }
 
 // parseCastlingAvailability determines the castling rights for
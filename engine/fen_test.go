@@ -0,0 +1,59 @@
+package engine
+
+import "testing"
+
+func TestToFEN(t *testing.T) {
+	fens := []string{
+		"rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1",
+		"r3k2r/p1ppqpb1/bn2pnp1/3PN3/1p2P3/2N2Q1p/PPPBBPPP/R3K2R w KQkq - 0 1",
+		"rnbqkbnr/ppp1pppp/8/8/3pP3/8/PPPP1PPP/RNBQKBNR b Kq e3 0 1",
+		"8/2p5/3p4/KP5r/1R3p1k/8/4P1P1/8 b - - 0 1",
+		"rnbqkbnr/ppp1p1pp/8/3pPp2/8/8/PPPP1PPP/RNBQKBNR w KQkq f6 0 3",
+		"r3k2r/8/8/8/8/8/8/R3K2R w Qk - 17 40",
+		"r3k2r/8/8/8/8/8/8/R3K2R b Kq - 3 22",
+		"4k2r/8/8/8/8/8/8/R3K3 w Qk - 0 9",
+		"8/8/8/2k5/3Pp3/8/8/4KQ2 b - d3 0 57",
+		"8/8/4k3/8/8/4K3/8/8 w - - 99 120",
+		"8/5k2/8/8/8/8/5K2/6R1 b - - 100 201",
+	}
+	for _, fen := range fens {
+		game := ParseFen(fen)
+		got := game.Position().ToFEN()
+		if got != fen {
+			t.Errorf("Expected %v but got %v", fen, got)
+		}
+		again := ParseFen(got)
+		if again.Position().PositionKey != game.Position().PositionKey || again.Position().ToFEN() != fen {
+			t.Errorf("%v: expected parsing the fen again to give the same position", fen)
+		}
+	}
+}
+
+func TestParseFenResetsHalfMoveClock(t *testing.T) {
+	game := ParseFen("8/8/4k3/8/8/4K3/8/8 w - - 42 60")
+	p := game.Position()
+	if p.FiftyMove != 42 {
+		t.Errorf("Expected a half move clock of 42 but got %v", p.FiftyMove)
+	}
+	p.ParseFen("8/8/4k3/8/8/4K3/8/8 w - -")
+	if p.FiftyMove != 0 {
+		t.Errorf("Expected a fen without clocks to reset the clock but got %v", p.FiftyMove)
+	}
+}
+
+func TestGamePly(t *testing.T) {
+	game := ParseFen("rnbqkbnr/pppppppp/8/8/4P3/8/PPPP1PPP/RNBQKBNR b KQkq e3 0 15")
+	p := game.Position()
+	if p.GamePly != 29 {
+		t.Errorf("Expected game ply 29 but got %v", p.GamePly)
+	}
+	move := p.ParseMove([]byte("e7e5"))
+	_, enPas, castle, fifty := p.MakeMove(move)
+	if p.GamePly != 30 {
+		t.Errorf("Expected game ply 30 but got %v", p.GamePly)
+	}
+	p.TakeMoveBack(move, enPas, castle, fifty)
+	if p.GamePly != 29 {
+		t.Errorf("Expected game ply 29 after take back but got %v", p.GamePly)
+	}
+}
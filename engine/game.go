@@ -1,9 +1,31 @@
 package engine
 
+import (
+	"fmt"
+
+	"github.com/AdamGriffiths31/ChessEngine/data"
+	"github.com/AdamGriffiths31/ChessEngine/pgn"
+)
+
 type Game struct {
 	position      *Position
 	moves         []Move
 	numberOfMoves uint16
+
+	// startFEN is the position the game was set up from, empty for the
+	// start position
+	startFEN string
+	played   []playedMove
+	undone   []int
+}
+
+// playedMove is a move played in the game with the state it changed, so it
+// can be taken back
+type playedMove struct {
+	move       int
+	enPas      int
+	castlePerm int
+	fifty      int
 }
 
 func NewGame(
@@ -11,12 +33,12 @@ func NewGame(
 	moves []Move,
 	numberOfMoves uint16) Game {
 	if position == nil {
-		position = &Position{PositionHistory: NewPositionHistory(), Positions: map[uint64]int{}}
+		position = &Position{PositionHistory: NewPositionHistory(), Positions: RepetitionTable{}}
 	}
 	return Game{
-		position,
-		moves,
-		numberOfMoves,
+		position:      position,
+		moves:         moves,
+		numberOfMoves: numberOfMoves,
 	}
 }
 
@@ -24,17 +46,135 @@ func (g *Game) Position() *Position {
 	return g.position
 }
 
+// PlayMove plays a legal move in the game so it can be undone, any undone
+// moves can no longer be redone. It returns false if the move is illegal
+func (g *Game) PlayMove(move int) bool {
+	p := g.position
+	played := playedMove{move: move, enPas: p.EnPassant, castlePerm: p.CastlePermission, fifty: p.FiftyMove}
+	if !p.MakeGameMove(move) {
+		return false
+	}
+	g.played = append(g.played, played)
+	g.undone = g.undone[:0]
+	return true
+}
+
+// Undo takes back the last move played, returning false if there is none
+func (g *Game) Undo() bool {
+	if len(g.played) == 0 {
+		return false
+	}
+	last := g.played[len(g.played)-1]
+	g.played = g.played[:len(g.played)-1]
+
+	p := g.position
+	p.Positions.Remove(p.PositionKey)
+	p.TakeMoveBack(last.move, last.enPas, last.castlePerm, last.fifty)
+	p.Play = 0
+	g.undone = append(g.undone, last.move)
+	return true
+}
+
+// Redo plays the last move taken back by Undo, returning false if there is
+// none
+func (g *Game) Redo() bool {
+	if len(g.undone) == 0 {
+		return false
+	}
+	move := g.undone[len(g.undone)-1]
+	undone := g.undone[:len(g.undone)-1]
+	if !g.PlayMove(move) {
+		return false
+	}
+	g.undone = undone
+	return true
+}
+
 func ParseFen(fen string) Game {
 	game := NewGame(nil, nil, 0)
 	game.position.ParseFen(fen)
+	if fen != data.StartFEN {
+		game.startFEN = fen
+	}
+	return game
+}
+
+// PGN returns the moves played with PlayMove as a PGN game, the result is
+// set if the game is over
+func (g *Game) PGN() pgn.Game {
+	fen := data.StartFEN
+	game := pgn.Game{Tags: map[string]string{}, Result: "*"}
+	if g.startFEN != "" {
+		fen = g.startFEN
+		game.Tags["FEN"] = fen
+		game.Tags["SetUp"] = "1"
+	}
+
+	replay := ParseFen(fen)
+	p := replay.Position()
+	for _, played := range g.played {
+		game.Moves = append(game.Moves, p.SAN(played.move))
+		p.MakeGameMove(played.move)
+	}
+
+	switch g.position.Outcome() {
+	case OutcomeNone:
+	case OutcomeCheckmate:
+		game.Result = "1-0"
+		if g.position.Side == data.White {
+			game.Result = "0-1"
+		}
+	default:
+		game.Result = "1/2-1/2"
+	}
+	game.Tags["Result"] = game.Result
 	return game
 }
 
+// LoadPGN replays a PGN game so its moves can be undone
+func LoadPGN(pg pgn.Game) (Game, error) {
+	fen := data.StartFEN
+	if tag, ok := pg.Tags["FEN"]; ok {
+		fen = tag
+	}
+	game := ParseFen(fen)
+	for i, san := range pg.Moves {
+		move := game.position.ParseSAN(san)
+		if move == data.NoMove || !game.PlayMove(move) {
+			return Game{}, fmt.Errorf("illegal move %v at ply %d", san, i+1)
+		}
+	}
+	return game, nil
+}
+
+// ParseFenWithMoves sets up a game from the fen and plays the moves, which
+// can be in coordinate notation or SAN, so they can be undone. It returns an
+// error naming the index of the first move that is not legal
+func ParseFenWithMoves(fen string, moves []string) (Game, error) {
+	game := ParseFen(fen)
+	for i, text := range moves {
+		move := game.position.ParseMove([]byte(text))
+		if move == data.NoMove {
+			move = game.position.ParseSAN(text)
+		}
+		if move == data.NoMove || !game.PlayMove(move) {
+			return Game{}, fmt.Errorf("illegal move %v at index %d", text, i)
+		}
+	}
+	return game, nil
+}
+
+// Copy returns an independent copy of the position that can be searched or
+// have moves made on it without changing p. The search history of repeated
+// positions starts empty and the continuation history, which belongs to the
+// search using p, is not shared
 func (p *Position) Copy() *Position {
-	copyMap := make(map[uint64]int, len(p.Positions))
+	copyMap := make(RepetitionTable, len(p.Positions))
 	for k, v := range p.Positions {
 		copyMap[k] = v
 	}
+	moveHistory := p.MoveHistory
+	moveHistory.Continuation = nil
 
 	newPos := &Position{
 		Board:            p.Board.copy(),
@@ -45,10 +185,14 @@ func (p *Position) Copy() *Position {
 		EnPassant:        p.EnPassant,
 		FailHighFirst:    p.FailHighFirst,
 		FailHigh:         p.FailHigh,
-		MoveHistory:      p.MoveHistory,
+		MoveHistory:      moveHistory,
 		FiftyMove:        p.FiftyMove,
 		PositionHistory:  NewPositionHistory(),
 		Positions:        copyMap,
+		PieceScores:      p.PieceScores,
+		PieceScore:       p.PieceScore,
+		GamePly:          p.GamePly,
+		VerifyHash:       p.VerifyHash,
 	}
 	return newPos
 }
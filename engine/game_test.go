@@ -0,0 +1,135 @@
+package engine
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/AdamGriffiths31/ChessEngine/data"
+	"github.com/AdamGriffiths31/ChessEngine/pgn"
+)
+
+func TestUndoRedo(t *testing.T) {
+	game := ParseFen("r3k2r/8/8/8/4p3/8/3P4/R3K2R w KQkq - 0 1")
+	p := game.Position()
+	var fens []string
+	for _, san := range []string{"d4", "exd3", "O-O", "O-O-O"} {
+		fens = append(fens, p.ToFEN())
+		if !game.PlayMove(p.ParseSAN(san)) {
+			t.Fatalf("Illegal move %v", san)
+		}
+	}
+	final, key := p.ToFEN(), p.PositionKey
+
+	for i := len(fens) - 1; i >= 0; i-- {
+		if !game.Undo() {
+			t.Fatalf("Expected a move to undo")
+		}
+		if p.ToFEN() != fens[i] {
+			t.Errorf("Expected %v but got %v", fens[i], p.ToFEN())
+		}
+	}
+	if game.Undo() {
+		t.Errorf("Expected nothing to undo")
+	}
+	if len(p.Positions) != 1 || p.Positions.Count(p.PositionKey) != 1 {
+		t.Errorf("Expected only the starting position counted but got %v", p.Positions)
+	}
+
+	for game.Redo() {
+	}
+	if p.ToFEN() != final || p.PositionKey != key {
+		t.Errorf("Expected %v but got %v", final, p.ToFEN())
+	}
+}
+
+func TestSaveAndLoadPGN(t *testing.T) {
+	tests := []struct {
+		fen    string
+		moves  []string
+		result string
+	}{
+		{data.StartFEN, []string{"e4", "e5", "Bc4", "Nc6", "Qh5", "Nf6", "Qxf7#"}, "1-0"},
+		{"r3k2r/8/8/8/4p3/8/3P4/R3K2R b KQkq - 0 12", []string{"O-O", "d4", "exd3"}, "*"},
+	}
+	for _, test := range tests {
+		game := ParseFen(test.fen)
+		for _, san := range test.moves {
+			game.PlayMove(game.Position().ParseSAN(san))
+		}
+
+		var sb strings.Builder
+		if err := pgn.WriteGame(&sb, game.PGN()); err != nil {
+			t.Fatal(err)
+		}
+		games, err := pgn.ReadGames(strings.NewReader(sb.String()))
+		if err != nil {
+			t.Fatal(err)
+		}
+		loaded, err := LoadPGN(games[0])
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if loaded.Position().ToFEN() != game.Position().ToFEN() {
+			t.Errorf("Expected %v but got %v", game.Position().ToFEN(), loaded.Position().ToFEN())
+		}
+		if len(loaded.played) != len(test.moves) {
+			t.Errorf("Expected %v moves but got %v", len(test.moves), len(loaded.played))
+		}
+		if games[0].Result != test.result {
+			t.Errorf("Expected result %v but got %v", test.result, games[0].Result)
+		}
+		for loaded.Undo() {
+		}
+		if start := ParseFen(test.fen); loaded.Position().ToFEN() != start.Position().ToFEN() {
+			t.Errorf("Expected undo back to %v but got %v", start.Position().ToFEN(), loaded.Position().ToFEN())
+		}
+	}
+}
+
+func TestCopyIsIndependent(t *testing.T) {
+	game := ParseFen("r3k2r/pppq1ppp/2n2n2/3pp3/3PP3/2N2N2/PPPQ1PPP/R3K2R w KQkq - 0 1")
+	p := game.Position()
+	p.MakeGameMove(p.ParseSAN("a3"))
+	p.MoveHistory.Continuation = &ContinuationHistory{}
+	fen := p.ToFEN()
+	key := p.PositionKey
+	positions := len(p.Positions)
+
+	clone := p.Copy()
+	if clone.ToFEN() != fen || clone.PositionKey != key {
+		t.Fatalf("Expected the copy to match %v but got %v", fen, clone.ToFEN())
+	}
+	if clone.MoveHistory.Continuation != nil {
+		t.Errorf("Expected the continuation history not to be shared")
+	}
+	for _, san := range []string{"O-O-O", "dxe5", "Nxe5", "Nxe5", "Qe6"} {
+		if !clone.MakeGameMove(clone.ParseSAN(san)) {
+			t.Fatalf("illegal move %v", san)
+		}
+	}
+	clone.Board.WhiteQueen = 0
+
+	if p.ToFEN() != fen || p.PositionKey != key || len(p.Positions) != positions {
+		t.Errorf("Expected the original to stay at %v but got %v", fen, p.ToFEN())
+	}
+}
+
+func TestParseFenWithMoves(t *testing.T) {
+	game, err := ParseFenWithMoves(data.StartFEN, []string{"e2e4", "e5", "Nf3", "b8c6", "Bb5", "a7a6"})
+	if err != nil {
+		t.Fatalf("Expected the moves to be legal but got %v", err)
+	}
+	want := "r1bqkbnr/1ppp1ppp/p1n5/1B2p3/4P3/5N2/PPPP1PPP/RNBQK2R w KQkq - 0 4"
+	if got := game.Position().ToFEN(); got != want {
+		t.Errorf("Expected %v but got %v", want, got)
+	}
+	if !game.Undo() || game.Position().ToFEN() != "r1bqkbnr/pppp1ppp/2n5/1B2p3/4P3/5N2/PPPP1PPP/RNBQK2R b KQkq - 3 3" {
+		t.Errorf("Expected the moves to be undoable but got %v", game.Position().ToFEN())
+	}
+
+	_, err = ParseFenWithMoves(data.StartFEN, []string{"e2e4", "e7e5", "e4e5", "Nf6"})
+	if err == nil || !strings.Contains(err.Error(), "e4e5 at index 2") {
+		t.Errorf("Expected e4e5 at index 2 to be illegal but got %v", err)
+	}
+}
@@ -0,0 +1,196 @@
+package engine
+
+import (
+	"errors"
+
+	"github.com/AdamGriffiths31/ChessEngine/data"
+)
+
+// Reasons CheckMove gives for rejecting a move
+var (
+	ErrBadMove           = errors.New("not a move in coordinate or algebraic notation")
+	ErrNoPiece           = errors.New("there is no piece on that square")
+	ErrNotYourPiece      = errors.New("that piece belongs to the other side")
+	ErrCannotMove        = errors.New("no piece can make that move")
+	ErrLeavesKingInCheck = errors.New("the move leaves the king in check")
+	ErrAmbiguous         = errors.New("more than one piece can make that move")
+)
+
+// LegalMoves returns every legal move for the side to move
+func (p *Position) LegalMoves() []int {
+	var moves []int
+	ml := &MoveList{}
+	p.GenerateAllMoves(ml)
+	for i := 0; i < ml.Count; i++ {
+		if p.isLegal(ml.Moves[i].Move) {
+			moves = append(moves, ml.Moves[i].Move)
+		}
+	}
+	return moves
+}
+
+// IsLegalMove checks a single move without generating every move, it must be
+// a move the piece on its from square can make and not leave the king in check
+func (p *Position) IsLegalMove(move int) bool {
+	return p.isPseudoLegal(move) && p.isLegal(move)
+}
+
+// isPseudoLegal checks the move could be generated for the side to move,
+// ignoring whether it leaves the king in check
+func (p *Position) isPseudoLegal(move int) bool {
+	from, to := data.FromSquare(move), data.ToSquare(move)
+	from64, to64 := data.Square120ToSquare64[from], data.Square120ToSquare64[to]
+	if move == data.NoMove || from64 >= 64 || to64 >= 64 {
+		return false
+	}
+	piece := p.Board.PieceAt(from64)
+	if piece == data.Empty || data.PieceCol[piece] != p.Side {
+		return false
+	}
+	if move&data.MFLAGGCA != 0 {
+		return p.isCastle(move)
+	}
+
+	target := p.Board.PieceAt(to64)
+	kind := pieceKind(piece)
+	if kind == data.WP {
+		return p.isPawnMove(move, from, to, target)
+	}
+	if move&(data.MFLAGEP|data.MFLAGPS|data.MFLAGPRO) != 0 || data.Captured(move) != target {
+		return false
+	}
+	if target != data.Empty && data.PieceCol[target] == p.Side {
+		return false
+	}
+
+	var attacks uint64
+	switch kind {
+	case data.WN:
+		attacks = PreCalculatedKnightMoves[from64]
+	case data.WB:
+		attacks = data.GetBishopAttacks(p.Board.Pieces, from64)
+	case data.WR:
+		attacks = data.GetRookAttacks(p.Board.Pieces, from64)
+	case data.WQ:
+		attacks = data.GetBishopAttacks(p.Board.Pieces, from64) | data.GetRookAttacks(p.Board.Pieces, from64)
+	case data.WK:
+		attacks = PreCalculatedKingMoves[from64]
+	}
+	return attacks&(uint64(1)<<to64) != 0
+}
+
+// isPawnMove checks the move is a push, double push, capture, en passant or
+// promotion the pawn on the from square can make
+func (p *Position) isPawnMove(move, from, to, target int) bool {
+	forward, startRank, lastRank := 10, data.Rank2, data.Rank8
+	if p.Side == data.Black {
+		forward, startRank, lastRank = -10, data.Rank7, data.Rank1
+	}
+
+	promoted := data.Promoted(move)
+	if (data.RanksBoard[to] == lastRank) != (promoted != data.Empty) {
+		return false
+	}
+	if promoted != data.Empty && (data.PieceCol[promoted] != p.Side || pieceKind(promoted) == data.WP || pieceKind(promoted) == data.WK) {
+		return false
+	}
+	diagonal := to == from+forward-1 || to == from+forward+1
+
+	switch {
+	case move&data.MFLAGEP != 0:
+		return move&data.MFLAGPS == 0 && diagonal && to == p.EnPassant && data.Captured(move) == data.Empty
+	case move&data.MFLAGPS != 0:
+		return data.RanksBoard[from] == startRank && to == from+2*forward && target == data.Empty &&
+			data.Captured(move) == data.Empty && p.Board.PieceAt(data.Square120ToSquare64[from+forward]) == data.Empty
+	case to == from+forward:
+		return target == data.Empty && data.Captured(move) == data.Empty
+	case diagonal:
+		return target != data.Empty && data.PieceCol[target] != p.Side && data.Captured(move) == target
+	}
+	return false
+}
+
+// isCastle checks the move is one of the castles the side to move can make
+func (p *Position) isCastle(move int) bool {
+	ml := &MoveList{}
+	if p.Side == data.White {
+		p.generateWhiteCastleMoves(ml)
+	} else {
+		p.generateBlackCastleMoves(ml)
+	}
+	for i := 0; i < ml.Count; i++ {
+		if ml.Moves[i].Move == move {
+			return true
+		}
+	}
+	return false
+}
+
+// CheckMove parses a move given in coordinate notation such as e2e4 or in
+// standard algebraic notation, returning the reason it cannot be played
+func (p *Position) CheckMove(text string) (int, error) {
+	if isCoordinateMove(text) {
+		return p.checkCoordinateMove(text)
+	}
+
+	candidates := p.sanCandidates(text)
+	if len(candidates) == 0 {
+		if len(text) < 2 {
+			return data.NoMove, ErrBadMove
+		}
+		return data.NoMove, ErrCannotMove
+	}
+	match := data.NoMove
+	for _, move := range candidates {
+		if !p.isLegal(move) {
+			continue
+		}
+		if match != data.NoMove {
+			return data.NoMove, ErrAmbiguous
+		}
+		match = move
+	}
+	if match == data.NoMove {
+		return data.NoMove, ErrLeavesKingInCheck
+	}
+	return match, nil
+}
+
+// checkCoordinateMove finds the reason a move in coordinate notation cannot
+// be played
+func (p *Position) checkCoordinateMove(text string) (int, error) {
+	from := data.FileRankToSquare(int(text[0]-'a'), int(text[1]-'1'))
+	piece := p.Board.PieceAt(data.Square120ToSquare64[from])
+	if piece == data.Empty {
+		return data.NoMove, ErrNoPiece
+	}
+	if data.PieceCol[piece] != p.Side {
+		return data.NoMove, ErrNotYourPiece
+	}
+	if len(text) == 4 {
+		// a pawn reaching the last rank is promoted to a queen unless another
+		// piece is given
+		text += "q"
+	}
+	move := p.ParseMove([]byte(text))
+	if move == data.NoMove {
+		return data.NoMove, ErrCannotMove
+	}
+	if !p.isLegal(move) {
+		return data.NoMove, ErrLeavesKingInCheck
+	}
+	return move, nil
+}
+
+// isCoordinateMove checks the text looks like e2e4 or e7e8q
+func isCoordinateMove(text string) bool {
+	if len(text) != 4 && len(text) != 5 {
+		return false
+	}
+	for i, lo := range "a1a1" {
+		if text[i] < byte(lo) || text[i] > byte(lo)+7 {
+			return false
+		}
+	}
+	return len(text) == 4 || text[4] == 'n' || text[4] == 'b' || text[4] == 'r' || text[4] == 'q'
+}
@@ -0,0 +1,156 @@
+package engine
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/AdamGriffiths31/ChessEngine/data"
+	"github.com/AdamGriffiths31/ChessEngine/io"
+)
+
+func TestLegalMovesStartPosition(t *testing.T) {
+	game := ParseFen(data.StartFEN)
+	p := game.Position()
+	moves := p.LegalMoves()
+	if len(moves) != 20 {
+		t.Fatalf("Expected 20 moves but got %v", len(moves))
+	}
+	sans := map[string]bool{}
+	for _, move := range moves {
+		sans[p.SAN(move)] = true
+	}
+	for _, san := range []string{"e4", "e3", "Nf3", "Na3"} {
+		if !sans[san] {
+			t.Errorf("Expected %v in %v", san, sans)
+		}
+	}
+}
+
+func TestCheckMove(t *testing.T) {
+	tests := []struct {
+		fen  string
+		move string
+		want string
+		err  error
+	}{
+		{data.StartFEN, "e2e4", "e2e4", nil},
+		{data.StartFEN, "Nf3", "g1f3", nil},
+		{data.StartFEN, "e3e4", "NoMove", ErrNoPiece},
+		{data.StartFEN, "e7e5", "NoMove", ErrNotYourPiece},
+		{data.StartFEN, "e2e5", "NoMove", ErrCannotMove},
+		{data.StartFEN, "Nd4", "NoMove", ErrCannotMove},
+		{data.StartFEN, "x", "NoMove", ErrBadMove},
+		{"4k3/4r3/8/8/8/8/4N3/4K3 w - - 0 1", "e2c3", "NoMove", ErrLeavesKingInCheck},
+		{"4k3/4r3/8/8/8/8/4N3/4K3 w - - 0 1", "Nc3", "NoMove", ErrLeavesKingInCheck},
+		{"4k3/8/8/8/8/5N2/8/1N2K3 w - - 0 1", "Nd2", "NoMove", ErrAmbiguous},
+		{"4k3/1P6/8/8/8/8/8/4K3 w - - 0 1", "b7b8", "b7b8q", nil},
+	}
+	for _, test := range tests {
+		game := ParseFen(test.fen)
+		move, err := game.Position().CheckMove(test.move)
+		if err != test.err || io.PrintMove(move) != test.want {
+			t.Errorf("%v %v: expected %v %v but got %v %v", test.fen, test.move, test.want, test.err, io.PrintMove(move), err)
+		}
+	}
+}
+
+func TestIsLegalMove(t *testing.T) {
+	tests := []struct {
+		name string
+		fen  string
+		move int
+		want bool
+	}{
+		{"pinned knight", "4k3/4r3/8/8/8/8/4N3/4K3 w - - 0 1", MakeMoveInt(data.E2, data.C3, data.Empty, data.Empty, 0), false},
+		{"king escape", "4k3/8/8/8/8/8/8/r3K3 w - - 0 1", MakeMoveInt(data.E1, data.E2, data.Empty, data.Empty, 0), true},
+		{"king stays in check", "4k3/8/8/8/8/8/8/r3K3 w - - 0 1", MakeMoveInt(data.E1, data.D1, data.Empty, data.Empty, 0), false},
+		{"en passant exposes king", "8/8/8/KPp4r/8/8/8/4k3 w - c6 0 1", MakeMoveInt(data.B5, data.C6, data.Empty, data.Empty, data.MFLAGEP), false},
+		{"en passant", "8/8/8/1Pp5/8/8/8/K3k3 w - c6 0 1", MakeMoveInt(data.B5, data.C6, data.Empty, data.Empty, data.MFLAGEP), true},
+		{"knight moving like a bishop", data.StartFEN, MakeMoveInt(data.G1, data.E3, data.Empty, data.Empty, 0), false},
+		{"blocked bishop", data.StartFEN, MakeMoveInt(data.F1, data.C4, data.Empty, data.Empty, 0), false},
+		{"opponent's piece", data.StartFEN, MakeMoveInt(data.E7, data.E5, data.Empty, data.Empty, data.MFLAGPS), false},
+		{"double push", data.StartFEN, MakeMoveInt(data.E2, data.E4, data.Empty, data.Empty, data.MFLAGPS), true},
+		{"wrong capture", "4k3/8/8/3p4/4P3/8/8/4K3 w - - 0 1", MakeMoveInt(data.E4, data.D5, data.BN, data.Empty, 0), false},
+		{"pawn capture", "4k3/8/8/3p4/4P3/8/8/4K3 w - - 0 1", MakeMoveInt(data.E4, data.D5, data.BP, data.Empty, 0), true},
+		{"missing promotion", "4k3/1P6/8/8/8/8/8/4K3 w - - 0 1", MakeMoveInt(data.B7, data.B8, data.Empty, data.Empty, 0), false},
+		{"promotion", "4k3/1P6/8/8/8/8/8/4K3 w - - 0 1", MakeMoveInt(data.B7, data.B8, data.Empty, data.WQ, 0), true},
+		{"castle through check", "4k3/8/8/8/8/8/5r2/4K2R w K - 0 1", MakeMoveInt(data.E1, data.G1, data.Empty, data.Empty, data.MFLAGGCA), false},
+		{"castle", "4k3/8/8/8/8/8/8/4K2R w K - 0 1", MakeMoveInt(data.E1, data.G1, data.Empty, data.Empty, data.MFLAGGCA), true},
+		{"no move", data.StartFEN, data.NoMove, false},
+	}
+	for _, test := range tests {
+		game := ParseFen(test.fen)
+		if got := game.Position().IsLegalMove(test.move); got != test.want {
+			t.Errorf("%v: expected %v but got %v", test.name, test.want, got)
+		}
+	}
+}
+
+func TestIsLegalMoveMatchesGenerator(t *testing.T) {
+	fens := []string{
+		data.StartFEN,
+		"r3k2r/p1ppqpb1/bn2pnp1/3PN3/1p2P3/2N2Q1p/PPPBBPPP/R3K2R w KQkq - 0 1",
+		"8/2p5/3p4/KP5r/1R3p1k/8/4P1P1/8 w - - 0 1",
+		"r3k2r/Pppp1ppp/1b3nbN/nP6/BBP1P3/q4N2/Pp1P2PP/R2Q1RK1 w kq - 0 1",
+		"rnbq1k1r/pp1Pbppp/2p5/8/2B5/8/PPP1NnPP/RNBQK2R w KQ - 1 8",
+	}
+	var moves []int
+	for _, fen := range fens {
+		for _, side := range []string{" w ", " b "} {
+			game := ParseFen(replaceSide(fen, side))
+			ml := &MoveList{}
+			game.Position().GenerateAllMoves(ml)
+			for i := 0; i < ml.Count; i++ {
+				moves = append(moves, ml.Moves[i].Move)
+			}
+		}
+	}
+
+	for _, fen := range fens {
+		game := ParseFen(fen)
+		p := game.Position()
+		legal := map[int]bool{}
+		for _, move := range p.LegalMoves() {
+			legal[move] = true
+		}
+		for _, move := range moves {
+			if got := p.IsLegalMove(move); got != legal[move] {
+				t.Errorf("%v %v: expected %v but got %v", fen, io.PrintMove(move), legal[move], got)
+			}
+		}
+	}
+}
+
+// replaceSide sets the side to move in the fen
+func replaceSide(fen, side string) string {
+	if i := strings.Index(fen, " w "); i >= 0 {
+		return fen[:i] + side + fen[i+3:]
+	}
+	return fen
+}
+
+func TestEnPassantDiagonalPin(t *testing.T) {
+	tests := []struct {
+		fen  string
+		move int
+	}{
+		{"7q/8/8/4pP2/8/8/1K6/4k3 w - e6 0 1", MakeMoveInt(data.F5, data.E6, data.Empty, data.Empty, data.MFLAGEP)},
+		{"4k3/1q6/8/3Pp3/8/8/6K1/8 w - e6 0 1", MakeMoveInt(data.D5, data.E6, data.Empty, data.Empty, data.MFLAGEP)},
+		{"8/6k1/8/8/3pP3/8/1Q6/4K3 b - e3 0 1", MakeMoveInt(data.D4, data.E3, data.Empty, data.Empty, data.MFLAGEP)},
+	}
+	for _, test := range tests {
+		game := ParseFen(test.fen)
+		p := game.Position()
+		if !p.isPseudoLegal(test.move) {
+			t.Fatalf("%v: expected %v to be pseudo legal", test.fen, io.PrintMove(test.move))
+		}
+		if p.IsLegalMove(test.move) {
+			t.Errorf("%v: expected %v to be illegal", test.fen, io.PrintMove(test.move))
+		}
+		for _, move := range p.LegalMoves() {
+			if move == test.move {
+				t.Errorf("%v: expected %v not to be generated as legal", test.fen, io.PrintMove(test.move))
+			}
+		}
+	}
+}
@@ -0,0 +1,42 @@
+package engine
+
+import "github.com/AdamGriffiths31/ChessEngine/data"
+
+// Mirror returns a new position with the ranks flipped, the colours of the
+// pieces swapped and the other side to move, so each side has the position
+// the other had
+func (p *Position) Mirror() *Position {
+	m := &Position{
+		Side:            p.Side ^ 1,
+		FiftyMove:       p.FiftyMove,
+		EnPassant:       data.NoSquare,
+		PositionHistory: NewPositionHistory(),
+		Positions:       RepetitionTable{},
+		PieceScores:     p.PieceScores,
+	}
+	for bb := p.Board.Pieces; bb != 0; bb &= bb - 1 {
+		sq := FirstSquare(bb)
+		m.Board.SetPieceAtSquare(sq^56, swapColour(p.Board.PieceAt(sq)))
+	}
+	// the move number is kept with the other side to move
+	m.GamePly = p.GamePly + 1
+	if p.Side == data.Black {
+		m.GamePly = p.GamePly - 1
+	}
+	m.CastlePermission = (p.CastlePermission&3)<<2 | (p.CastlePermission>>2)&3
+	if p.EnPassant != data.NoSquare && p.EnPassant != data.Empty {
+		m.EnPassant = data.Square64ToSquare120[data.Square120ToSquare64[p.EnPassant]^56]
+	}
+	m.PositionKey = m.GeneratePositionKey()
+	m.PieceScore = m.GeneratePieceScore()
+	m.Positions.Add(m.PositionKey)
+	return m
+}
+
+// swapColour returns the same type of piece for the other colour
+func swapColour(piece int) int {
+	if piece >= data.BP {
+		return piece - (data.BP - data.WP)
+	}
+	return piece + (data.BP - data.WP)
+}
@@ -0,0 +1,35 @@
+package engine
+
+import (
+	"testing"
+
+	"github.com/AdamGriffiths31/ChessEngine/data"
+)
+
+func TestMirror(t *testing.T) {
+	tests := []struct {
+		fen    string
+		mirror string
+	}{
+		{data.StartFEN, "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR b KQkq - 0 1"},
+		{"r3k2r/p1ppqpb1/bn2pnp1/3PN3/1p2P3/2N2Q1p/PPPBBPPP/R3K2R w Kq - 0 10", "r3k2r/pppbbppp/2n2q1P/1P2p3/3pn3/BN2PNP1/P1PPQPB1/R3K2R b Qk - 0 10"},
+		{"4k3/8/8/3pP3/8/8/8/4K3 w - d6 0 40", "4k3/8/8/8/3Pp3/8/8/4K3 b - d3 0 40"},
+	}
+	for _, test := range tests {
+		game := ParseFen(test.fen)
+		p := game.Position()
+		m := p.Mirror()
+		if m.ToFEN() != test.mirror {
+			t.Errorf("%v: expected %v but got %v", test.fen, test.mirror, m.ToFEN())
+		}
+		if m.PositionKey != m.GeneratePositionKey() {
+			t.Errorf("%v: expected the key to match the mirrored board", test.fen)
+		}
+		if back := m.Mirror(); back.ToFEN() != p.ToFEN() {
+			t.Errorf("%v: expected mirroring twice to give the position back but got %v", test.fen, back.ToFEN())
+		}
+		if p.ToFEN() != test.fen {
+			t.Errorf("%v: expected the original to be unchanged but got %v", test.fen, p.ToFEN())
+		}
+	}
+}
@@ -9,6 +9,9 @@ func MakeMoveInt(f, t, ca, pro, fl int) int {
 
 // ParseMove parses a move from a string
 func (p *Position) ParseMove(move []byte) int {
+	if len(move) < 4 {
+		return data.NoMove
+	}
 	if move[1] > '8' || move[1] < '1' {
 		return data.NoMove
 	}
@@ -33,6 +36,9 @@ func (p *Position) ParseMove(move []byte) int {
 		if data.FromSquare(userMove) == from && data.ToSquare(userMove) == to {
 			promPce := data.Promoted(userMove)
 			if promPce != data.Empty {
+				if len(move) < 5 {
+					continue
+				}
 				if data.PieceRookQueen[promPce] == data.True && data.PieceBishopQueen[promPce] == data.False && move[4] == 'r' {
 					return userMove
 				} else if data.PieceRookQueen[promPce] == data.False && data.PieceBishopQueen[promPce] == data.True && move[4] == 'b' {
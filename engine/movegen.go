@@ -11,6 +11,10 @@ import (
 type MoveList struct {
 	Moves [300]Move
 	Count int
+	// prevPiece and prevTo are the piece and to square of the move that
+	// reached the position, used to order quiet moves
+	prevPiece int
+	prevTo    int
 }
 
 type Move struct {
@@ -24,6 +28,7 @@ func init() {
 
 func (p *Position) GenerateAllMoves(ml *MoveList) {
 	ml.Count = 0
+	ml.prevPiece, ml.prevTo = p.previousMove()
 	if p.Side == data.White {
 		p.generateWhitePawnMoves(ml)
 		p.generateSliderMoves(ml, data.WR, true)
@@ -209,13 +214,15 @@ func (p *Position) addCaptureMove(move int, moveList *MoveList) {
 func (p *Position) addQuiteMove(move int, moveList *MoveList) {
 	moveList.Moves[moveList.Count].Move = move
 	piece := p.Board.PieceAt(data.Square120ToSquare64[data.FromSquare(move)])
-	switch move {
-	case p.MoveHistory.Killers[0][p.Play]:
-		moveList.Moves[moveList.Count].Score = 900000
-	case p.MoveHistory.Killers[1][p.Play]:
-		moveList.Moves[moveList.Count].Score = 800000
-	default:
-		moveList.Moves[moveList.Count].Score = p.MoveHistory.History[piece][data.ToSquare(move)]
+	to := data.ToSquare(move)
+	prevPiece, prevTo := moveList.prevPiece, moveList.prevTo
+	if slot := p.MoveHistory.KillerIndex(move, p.Play); slot != -1 {
+		moveList.Moves[moveList.Count].Score = 900000 - slot*100000
+	} else if prevPiece != data.Empty && p.MoveHistory.CounterMoves[prevPiece][prevTo] == move {
+		moveList.Moves[moveList.Count].Score = p.MoveHistory.countermoveBonus()
+	} else {
+		moveList.Moves[moveList.Count].Score = p.MoveHistory.History[piece][to] +
+			p.MoveHistory.continuationScore(prevPiece, prevTo, piece, to)
 	}
 	moveList.Count++
 }
@@ -359,63 +366,21 @@ func (p *Position) generateBlackCastleMoves(moveList *MoveList) {
 	}
 }
 
+// SquaresUnderAttack checks if any of side's pieces attack sq64
 func (p *Position) SquaresUnderAttack(side int, sq64 int) bool {
-	mask := uint64(1) << sq64
-	var attacked uint64
-	var enemyBishop, enemyQueen, enemyRook, enemyKnight, enemyKing, enemyPawn uint64
-	var king int
-	if side == data.Black {
-		enemyBishop = p.Board.BlackBishop
-		enemyQueen = p.Board.BlackQueen
-		enemyRook = p.Board.BlackRook
-		enemyKnight = p.Board.BlackKnight
-		enemyKing = p.Board.BlackKing
-		enemyPawn = p.Board.BlackPawn
-		king = data.BK
-	} else {
-		enemyBishop = p.Board.WhiteBishop
-		enemyQueen = p.Board.WhiteQueen
-		enemyRook = p.Board.WhiteRook
-		enemyKnight = p.Board.WhiteKnight
-		enemyKing = p.Board.WhiteKing
-		enemyPawn = p.Board.WhitePawn
-		king = data.WK
-	}
-
-	attacked |= p.getBishopAttackedSquares(enemyBishop)
-	if (attacked & mask) != 0 {
-		return true
-	}
-
-	attacked |= p.getRookAttackedSquares(enemyRook)
-	if (attacked & mask) != 0 {
-		return true
-	}
-
-	attacked |= p.getQueenAttackedSquares(enemyQueen)
-	if (attacked & mask) != 0 {
-		return true
-	}
-
-	attacked |= p.getKnightAttackedSquares(enemyKnight)
-	if (attacked & mask) != 0 {
-		return true
-	}
-
-	if king == data.BK {
-		attacked |= p.getBlackPawnAttackedSquares(enemyPawn)
-
-	} else {
-		attacked |= p.getWhitePawnAttackedSquares(enemyPawn)
-	}
-	if (attacked & mask) != 0 {
-		return true
+	if sq64 >= 64 {
+		return false
 	}
+	return p.AttackersTo(sq64, side, p.Board.Pieces) != 0
+}
 
-	attacked |= p.getKingAttackedSquares(enemyKing)
-	return (attacked & mask) != 0
+// AttackersTo returns side's pieces attacking sq64 with only the occupied
+// pieces on the board
+func (p *Position) AttackersTo(sq64, side int, occupied uint64) uint64 {
+	return p.Board.attackersTo(sq64, side, occupied)
 }
 
+// IsKingAttacked checks if side attacks the other side's king
 func (p *Position) IsKingAttacked(side int) bool {
 	var king uint64
 	if side == data.White {
@@ -0,0 +1,120 @@
+package engine
+
+import (
+	"math/rand"
+	"sort"
+	"testing"
+
+	"github.com/AdamGriffiths31/ChessEngine/data"
+)
+
+var captureFens = []string{
+	data.StartFEN,
+	"r3k2r/p1ppqpb1/bn2pnp1/3PN3/1p2P3/2N2Q1p/PPPBBPPP/R3K2R w KQkq - 0 1",
+	"r3k2r/p1ppqpb1/bn2pnp1/3PN3/1p2P3/2N2Q1p/PPPBBPPP/R3K2R b KQkq - 0 1",
+	"r2q1rk1/pP1p2pp/Q4n2/bbp1p3/Np6/1B3NBn/pPPP1PPP/R3K2R b KQ - 0 1",
+	"rnbqkb1r/pp1p1pPp/8/2p1pP2/1P1P4/3P3P/P1P1P3/RNBQKBNR w KQkq e6 0 1",
+	"8/2p5/3p4/KP5r/1R3p1k/8/4P1P1/8 w - - 0 1",
+	"n1n5/PPPk4/8/8/8/8/4Kppp/5N1N b - - 0 1",
+}
+
+func TestGenerateAllCapturesMatchesFilteredMoves(t *testing.T) {
+	for _, fen := range captureFens {
+		game := ParseFen(fen)
+		p := game.Position()
+
+		all := &MoveList{}
+		p.GenerateAllMoves(all)
+		var want []int
+		for i := 0; i < all.Count; i++ {
+			if move := all.Moves[i].Move; move&data.MFLAGCAP != 0 {
+				want = append(want, move)
+			}
+		}
+
+		captures := &MoveList{}
+		p.GenerateAllCaptures(captures)
+		var got []int
+		for i := 0; i < captures.Count; i++ {
+			got = append(got, captures.Moves[i].Move)
+		}
+
+		sort.Ints(want)
+		sort.Ints(got)
+		if len(got) != len(want) {
+			t.Fatalf("%v: expected %v captures but got %v", fen, len(want), len(got))
+		}
+		for i := range want {
+			if got[i] != want[i] {
+				t.Errorf("%v: expected capture %v but got %v", fen, want[i], got[i])
+			}
+		}
+	}
+}
+
+func TestSquaresUnderAttackMatchesScan(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	for n := 0; n < 20; n++ {
+		game := ParseFen(referenceFens[n%len(referenceFens)])
+		p := game.Position()
+		for ply := 0; ply < 60; ply++ {
+			b := newReferenceBoard(p)
+			for sq := 0; sq < 64; sq++ {
+				for side := data.White; side <= data.Black; side++ {
+					got := p.SquaresUnderAttack(side, sq)
+					if scan := p.attackedByScan(side, sq); got != scan {
+						t.Fatalf("%v: expected %v attacked by %v to be %v but got %v", p.ToFEN(), sq, side, scan, got)
+					}
+					if ref := b.attacked(data.Square64ToSquare120[sq], side); got != ref {
+						t.Fatalf("%v: expected %v attacked by %v to be %v but got %v", p.ToFEN(), sq, side, ref, got)
+					}
+				}
+			}
+
+			moves := referenceLegalMoves(p)
+			if len(moves) == 0 {
+				break
+			}
+			p.MakeMove(moves[r.Intn(len(moves))])
+			p.Play = 0
+			p.PositionHistory.RemovePositionHistory()
+		}
+	}
+}
+
+// attackedByScan builds the attacks of every one of side's pieces and checks
+// if they include sq64
+func (p *Position) attackedByScan(side, sq64 int) bool {
+	b := &p.Board
+	attacked := p.getBishopAttackedSquares(b.WhiteBishop) | p.getRookAttackedSquares(b.WhiteRook) |
+		p.getQueenAttackedSquares(b.WhiteQueen) | p.getKnightAttackedSquares(b.WhiteKnight) |
+		p.getKingAttackedSquares(b.WhiteKing) | p.getWhitePawnAttackedSquares(b.WhitePawn)
+	if side == data.Black {
+		attacked = p.getBishopAttackedSquares(b.BlackBishop) | p.getRookAttackedSquares(b.BlackRook) |
+			p.getQueenAttackedSquares(b.BlackQueen) | p.getKnightAttackedSquares(b.BlackKnight) |
+			p.getKingAttackedSquares(b.BlackKing) | p.getBlackPawnAttackedSquares(b.BlackPawn)
+	}
+	return attacked&(uint64(1)<<sq64) != 0
+}
+
+func BenchmarkGenerateAllMoves(b *testing.B) {
+	benchmarkGenerate(b, (*Position).GenerateAllMoves)
+}
+
+func BenchmarkGenerateAllCaptures(b *testing.B) {
+	benchmarkGenerate(b, (*Position).GenerateAllCaptures)
+}
+
+func benchmarkGenerate(b *testing.B, generate func(*Position, *MoveList)) {
+	var positions []*Position
+	for _, fen := range captureFens {
+		game := ParseFen(fen)
+		positions = append(positions, game.Position())
+	}
+	ml := &MoveList{}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		generate(positions[i%len(positions)], ml)
+	}
+}
@@ -0,0 +1,180 @@
+package engine
+
+import (
+	"github.com/AdamGriffiths31/ChessEngine/data"
+)
+
+// killerSlots returns how many killer moves are kept per ply
+func (m *MoveHistory) killerSlots() int {
+	if m.KillerSlots <= 0 {
+		return DefaultKillers
+	}
+	if m.KillerSlots > MaxKillers {
+		return MaxKillers
+	}
+	return m.KillerSlots
+}
+
+// StoreKiller records a quiet move that caused a beta cutoff at the given ply
+// in the first slot, a move already stored is moved up rather than duplicated
+// otherwise the oldest killer is evicted to make room
+func (m *MoveHistory) StoreKiller(move, ply int) {
+	if ply >= data.MaxDepth {
+		return
+	}
+	last := m.KillerIndex(move, ply)
+	if last == -1 {
+		last = m.killerSlots() - 1
+	}
+	for i := last; i > 0; i-- {
+		m.Killers[i][ply] = m.Killers[i-1][ply]
+	}
+	m.Killers[0][ply] = move
+}
+
+// KillerIndex returns the slot the move is stored in as a killer at the given
+// ply or -1 if it is not a killer
+func (m *MoveHistory) KillerIndex(move, ply int) int {
+	if ply >= data.MaxDepth {
+		return -1
+	}
+	for i := 0; i < m.killerSlots(); i++ {
+		if m.Killers[i][ply] == move {
+			return i
+		}
+	}
+	return -1
+}
+
+// SetMove records the move made to reach the given ply
+func (m *MoveHistory) SetMove(move, ply int) {
+	if ply >= data.MaxDepth {
+		return
+	}
+	m.Moves[ply] = move
+}
+
+// PreviousMove returns the move made to reach the given ply
+func (m *MoveHistory) PreviousMove(ply int) int {
+	if ply <= 0 || ply >= data.MaxDepth {
+		return data.NoMove
+	}
+	return m.Moves[ply]
+}
+
+// StoreCountermove records move as the reply to the previous move, which
+// moved piece to the square prevTo
+func (m *MoveHistory) StoreCountermove(piece, prevTo, move int) {
+	m.CounterMoves[piece][prevTo] = move
+}
+
+// countermoveBonus returns the ordering score for a countermove
+func (m *MoveHistory) countermoveBonus() int {
+	if m.CountermoveBonus == 0 {
+		return DefaultCountermoveBonus
+	}
+	return m.CountermoveBonus
+}
+
+// UpdateContinuation adds bonus to the continuation score of the reply piece
+// to the square to after the previous piece moved to prevTo, pulled back
+// the same way as AddHistory so it can never pass the history max
+func (m *MoveHistory) UpdateContinuation(prevPiece, prevTo, piece, to, bonus int) {
+	if m.Continuation == nil {
+		return
+	}
+	score := &m.Continuation[prevPiece][data.Square120ToSquare64[prevTo]][piece][data.Square120ToSquare64[to]]
+	*score = int32(gravity(int(*score), bonus, m.historyMax()))
+}
+
+// continuationScore returns the weighted continuation score of the reply
+func (m *MoveHistory) continuationScore(prevPiece, prevTo, piece, to int) int {
+	if m.Continuation == nil || prevPiece == data.Empty {
+		return 0
+	}
+	weight := m.ContinuationWeight
+	if weight == 0 {
+		weight = DefaultContinuationWeight
+	}
+	score := m.Continuation[prevPiece][data.Square120ToSquare64[prevTo]][piece][data.Square120ToSquare64[to]]
+	return int(score) * weight / 100
+}
+
+// Age divides every score by 2^shift so older searches count for less
+func (c *ContinuationHistory) Age(shift uint) {
+	for a := range c {
+		for b := range c[a] {
+			for d := range c[a][b] {
+				for e := range c[a][b][d] {
+					c[a][b][d][e] >>= shift
+				}
+			}
+		}
+	}
+}
+
+// previousMove returns the piece moved by the move that reached this ply and
+// the square it moved to, the piece is data.Empty at the root
+func (p *Position) previousMove() (piece, to int) {
+	prev := p.MoveHistory.PreviousMove(p.Play)
+	if prev == data.NoMove {
+		return data.Empty, 0
+	}
+	to = data.ToSquare(prev)
+	return p.Board.PieceAt(data.Square120ToSquare64[to]), to
+}
+
+// SetHistoryDecay sets the factor AgeHistory divides the scores by, a factor
+// of 1 keeps the scores and 0 uses DefaultHistoryDecay
+func (m *MoveHistory) SetHistoryDecay(factor int) {
+	m.HistoryDecay = factor
+}
+
+// AgeHistory divides every history score by the decay factor so moves from
+// earlier searches still help ordering but count for less
+func (m *MoveHistory) AgeHistory() {
+	decay := m.HistoryDecay
+	if decay <= 0 {
+		decay = DefaultHistoryDecay
+	}
+	for piece := range m.History {
+		for sq := range m.History[piece] {
+			m.History[piece][sq] /= decay
+		}
+	}
+}
+
+// AddHistory adds bonus to the history score of piece moving to the square,
+// scores are pulled back as they near the max so they can never pass it
+func (m *MoveHistory) AddHistory(piece, to, bonus int) {
+	m.History[piece][to] = gravity(m.History[piece][to], bonus, m.historyMax())
+}
+
+// gravity returns score with bonus added, the bonus shrinks as the score
+// nears max in its direction so the score stays within max of zero
+func gravity(score, bonus, max int) int {
+	if bonus > max {
+		bonus = max
+	} else if bonus < -max {
+		bonus = -max
+	}
+	abs := bonus
+	if abs < 0 {
+		abs = -abs
+	}
+	score += bonus - score*abs/max
+	if score > max {
+		score = max
+	} else if score < -max {
+		score = -max
+	}
+	return score
+}
+
+// historyMax returns the largest magnitude a history score can reach
+func (m *MoveHistory) historyMax() int {
+	if m.HistoryMax <= 0 {
+		return DefaultHistoryMax
+	}
+	return m.HistoryMax
+}
@@ -0,0 +1,208 @@
+package engine
+
+import (
+	"testing"
+
+	"github.com/AdamGriffiths31/ChessEngine/data"
+)
+
+func TestStoreKillerDefaultSlots(t *testing.T) {
+	var m MoveHistory
+	m.StoreKiller(1, 3)
+	m.StoreKiller(2, 3)
+	m.StoreKiller(3, 3)
+
+	if m.KillerIndex(1, 3) != -1 {
+		t.Errorf("Expected the oldest killer to be evicted but found it in slot %v", m.KillerIndex(1, 3))
+	}
+
+	if m.KillerIndex(3, 3) != 0 || m.KillerIndex(2, 3) != 1 {
+		t.Errorf("Expected killers in slots 0 and 1 but got %v and %v", m.KillerIndex(3, 3), m.KillerIndex(2, 3))
+	}
+}
+
+func TestStoreKillerPastMaxDepth(t *testing.T) {
+	var m MoveHistory
+	m.StoreKiller(1, data.MaxDepth-1)
+	if m.KillerIndex(1, data.MaxDepth-1) != 0 {
+		t.Errorf("Expected a killer at the last ply")
+	}
+
+	// quiescence can go past the deepest ply of the table
+	m.StoreKiller(2, data.MaxDepth)
+	if m.KillerIndex(2, data.MaxDepth) != -1 {
+		t.Errorf("Expected no killer past the last ply")
+	}
+}
+
+func TestStoreKillerThreeSlots(t *testing.T) {
+	m := MoveHistory{KillerSlots: 3}
+	m.StoreKiller(1, 5)
+	m.StoreKiller(2, 5)
+	m.StoreKiller(3, 5)
+
+	for move := 1; move <= 3; move++ {
+		if m.KillerIndex(move, 5) == -1 {
+			t.Errorf("Expected %v to be a killer", move)
+		}
+	}
+
+	m.StoreKiller(4, 5)
+	if m.KillerIndex(1, 5) != -1 {
+		t.Errorf("Expected the oldest killer to be evicted but found it in slot %v", m.KillerIndex(1, 5))
+	}
+
+	if m.KillerIndex(4, 5) != 0 {
+		t.Errorf("Expected the newest killer in slot 0 but got %v", m.KillerIndex(4, 5))
+	}
+
+	if m.KillerIndex(4, 6) != -1 {
+		t.Errorf("Expected killers to be stored per ply")
+	}
+}
+
+// moveScore returns the ordering score given to the move
+func moveScore(p *Position, move int) int {
+	ml := &MoveList{}
+	p.GenerateAllMoves(ml)
+	for i := 0; i < ml.Count; i++ {
+		if ml.Moves[i].Move == move {
+			return ml.Moves[i].Score
+		}
+	}
+	return -1
+}
+
+func TestCountermoveOrderedBeforeHistory(t *testing.T) {
+	game := ParseFen(data.StartFEN)
+	p := game.Position()
+	prev := p.ParseMove([]byte("e2e4"))
+	p.MakeMove(prev)
+	p.MoveHistory.SetMove(prev, p.Play)
+
+	counter := p.ParseMove([]byte("c7c5"))
+	other := p.ParseMove([]byte("e7e5"))
+	p.MoveHistory.History[data.BP][data.ToSquare(counter)] = 10
+	p.MoveHistory.History[data.BP][data.ToSquare(other)] = 10
+	if moveScore(p, counter) != moveScore(p, other) {
+		t.Fatalf("Expected equal scores before the countermove is stored")
+	}
+
+	p.MoveHistory.StoreCountermove(data.WP, data.ToSquare(prev), counter)
+	if moveScore(p, counter) != DefaultCountermoveBonus {
+		t.Errorf("Expected countermove score %v but got %v", DefaultCountermoveBonus, moveScore(p, counter))
+	}
+	if moveScore(p, counter) <= moveScore(p, other) {
+		t.Errorf("Expected the countermove to be ordered before %v", moveScore(p, other))
+	}
+
+	p.MoveHistory.StoreKiller(other, p.Play)
+	if moveScore(p, other) <= moveScore(p, counter) {
+		t.Errorf("Expected a killer to be ordered before the countermove")
+	}
+}
+
+func TestContinuationOrdering(t *testing.T) {
+	game := ParseFen(data.StartFEN)
+	p := game.Position()
+	prev := p.ParseMove([]byte("g1f3"))
+	p.MakeMove(prev)
+	p.MoveHistory.SetMove(prev, p.Play)
+	p.MoveHistory.Continuation = &ContinuationHistory{}
+
+	reply := p.ParseMove([]byte("d7d5"))
+	other := p.ParseMove([]byte("e7e5"))
+	p.MoveHistory.UpdateContinuation(data.WN, data.ToSquare(prev), data.BP, data.ToSquare(reply), 16)
+	if moveScore(p, reply) != 16 || moveScore(p, other) != 0 {
+		t.Errorf("Expected scores 16 and 0 but got %v and %v", moveScore(p, reply), moveScore(p, other))
+	}
+
+	p.MoveHistory.ContinuationWeight = 50
+	if moveScore(p, reply) != 8 {
+		t.Errorf("Expected a weighted score of 8 but got %v", moveScore(p, reply))
+	}
+
+	p.MoveHistory.Continuation.Age(1)
+	if moveScore(p, reply) != 4 {
+		t.Errorf("Expected an aged score of 4 but got %v", moveScore(p, reply))
+	}
+
+	p.MoveHistory.ContinuationWeight = 100
+	for i := 0; i < 1000; i++ {
+		p.MoveHistory.UpdateContinuation(data.WN, data.ToSquare(prev), data.BP, data.ToSquare(reply), 400)
+	}
+	if score := moveScore(p, reply); score > DefaultHistoryMax {
+		t.Errorf("Expected the score to stay within %v but got %v", DefaultHistoryMax, score)
+	}
+}
+
+func TestStoreKillerNoDuplicates(t *testing.T) {
+	tests := []struct {
+		slots  int
+		stored []int
+		want   []int
+	}{
+		{2, []int{1, 2, 1}, []int{1, 2}},
+		{2, []int{1, 1}, []int{1, 0}},
+		{3, []int{1, 2, 3, 1}, []int{1, 3, 2}},
+		{3, []int{1, 2, 3, 2}, []int{2, 3, 1}},
+	}
+	for _, test := range tests {
+		m := MoveHistory{KillerSlots: test.slots}
+		for _, move := range test.stored {
+			m.StoreKiller(move, 4)
+		}
+		for slot, want := range test.want {
+			if m.Killers[slot][4] != want {
+				t.Errorf("Storing %v: expected %v in slot %v but got %v", test.stored, want, slot, m.Killers[slot][4])
+			}
+		}
+	}
+}
+
+func TestAgeHistory(t *testing.T) {
+	var m MoveHistory
+	m.History[data.WN][data.F3] = 400
+	m.History[data.WP][data.E4] = 300
+
+	m.AgeHistory()
+	if m.History[data.WN][data.F3] != 200 || m.History[data.WP][data.E4] != 150 {
+		t.Errorf("Expected the scores to be halved but got %v and %v", m.History[data.WN][data.F3], m.History[data.WP][data.E4])
+	}
+
+	m.SetHistoryDecay(4)
+	m.AgeHistory()
+	if m.History[data.WN][data.F3] != 50 || m.History[data.WP][data.E4] != 37 {
+		t.Errorf("Expected the scores to be quartered but got %v and %v", m.History[data.WN][data.F3], m.History[data.WP][data.E4])
+	}
+	if m.History[data.WN][data.F3] <= m.History[data.WP][data.E4] {
+		t.Errorf("Expected the ordering to be kept after aging")
+	}
+}
+
+func TestAddHistoryConvergesToMax(t *testing.T) {
+	var m MoveHistory
+	last := 0
+	for i := 0; i < 100000; i++ {
+		m.AddHistory(data.WN, data.F3, 400)
+		score := m.History[data.WN][data.F3]
+		if score > DefaultHistoryMax {
+			t.Fatalf("Expected the score to stay within %v but got %v", DefaultHistoryMax, score)
+		}
+		if score < last {
+			t.Fatalf("Expected the score to keep rising but it fell from %v to %v", last, score)
+		}
+		last = score
+	}
+	if last < DefaultHistoryMax*9/10 {
+		t.Errorf("Expected the score to converge near %v but got %v", DefaultHistoryMax, last)
+	}
+
+	m.HistoryMax = 1000
+	for i := 0; i < 1000; i++ {
+		m.AddHistory(data.WP, data.E4, -5000)
+	}
+	if m.History[data.WP][data.E4] != -1000 {
+		t.Errorf("Expected -1000 but got %v", m.History[data.WP][data.E4])
+	}
+}
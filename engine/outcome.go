@@ -0,0 +1,112 @@
+package engine
+
+// darkSquares is every dark square on the board
+const darkSquares = uint64(0xAA55AA55AA55AA55)
+
+// Outcome is how the game stands in a position
+type Outcome int
+
+const (
+	// OutcomeNone means the game carries on
+	OutcomeNone Outcome = iota
+	OutcomeCheckmate
+	OutcomeStalemate
+	OutcomeRepetition
+	OutcomeFiftyMove
+	OutcomeInsufficientMaterial
+)
+
+func (o Outcome) String() string {
+	switch o {
+	case OutcomeCheckmate:
+		return "checkmate"
+	case OutcomeStalemate:
+		return "stalemate"
+	case OutcomeRepetition:
+		return "draw by threefold repetition"
+	case OutcomeFiftyMove:
+		return "draw by the fifty move rule"
+	case OutcomeInsufficientMaterial:
+		return "draw by insufficient material"
+	}
+	return "none"
+}
+
+// MakeGameMove plays a move in the game rather than the search, the position
+// is counted towards repetitions. It returns false if the move is illegal
+func (p *Position) MakeGameMove(move int) bool {
+	isAllowed, _, _, _ := p.MakeMove(move)
+	if !isAllowed {
+		return false
+	}
+	p.Positions.Add(p.PositionKey)
+	p.Play = 0
+	p.PositionHistory.RemovePositionHistory()
+	return true
+}
+
+// Outcome checks if the game is over in the position, repetitions are
+// counted over the positions reached by moves played with MakeGameMove
+func (p *Position) Outcome() Outcome {
+	if !p.hasLegalMove() {
+		if p.IsKingAttacked(p.Side ^ 1) {
+			return OutcomeCheckmate
+		}
+		return OutcomeStalemate
+	}
+	if p.IsInsufficientMaterial() {
+		return OutcomeInsufficientMaterial
+	}
+	if p.FiftyMove >= 100 {
+		return OutcomeFiftyMove
+	}
+	if p.Positions.Count(p.PositionKey) >= 3 {
+		return OutcomeRepetition
+	}
+	return OutcomeNone
+}
+
+// hasLegalMove checks if the side to move has any legal move
+func (p *Position) hasLegalMove() bool {
+	ml := &MoveList{}
+	p.GenerateAllMoves(ml)
+	for i := 0; i < ml.Count; i++ {
+		if p.isLegal(ml.Moves[i].Move) {
+			return true
+		}
+	}
+	return false
+}
+
+// CountLegalMoves returns the number of legal moves side has, playing a null
+// move first if it is not side's turn
+func (p *Position) CountLegalMoves(side int) int {
+	if side != p.Side {
+		_, enPas, castlePerm := p.MakeNullMove()
+		defer p.TakeNullMoveBack(enPas, castlePerm)
+	}
+	count := 0
+	ml := &MoveList{}
+	p.GenerateAllMoves(ml)
+	for i := 0; i < ml.Count; i++ {
+		if p.isLegal(ml.Moves[i].Move) {
+			count++
+		}
+	}
+	return count
+}
+
+// IsInsufficientMaterial checks if neither side can mate, either a lone minor
+// piece is left or every bishop stands on the same colour squares
+func (p *Position) IsInsufficientMaterial() bool {
+	b := &p.Board
+	if b.WhitePawn|b.BlackPawn|b.WhiteRook|b.BlackRook|b.WhiteQueen|b.BlackQueen != 0 {
+		return false
+	}
+	knights := b.WhiteKnight | b.BlackKnight
+	bishops := b.WhiteBishop | b.BlackBishop
+	if b.CountBits(knights|bishops) <= 1 {
+		return true
+	}
+	return knights == 0 && (bishops&darkSquares == 0 || bishops&^darkSquares == 0)
+}
@@ -0,0 +1,91 @@
+package engine
+
+import (
+	"testing"
+
+	"github.com/AdamGriffiths31/ChessEngine/data"
+)
+
+func TestOutcome(t *testing.T) {
+	tests := []struct {
+		fen   string
+		moves []string
+		want  Outcome
+	}{
+		{data.StartFEN, nil, OutcomeNone},
+		{data.StartFEN, []string{"e4", "e5", "Bc4", "Nc6", "Qh5", "Nf6", "Qxf7#"}, OutcomeCheckmate},
+		{"7k/5Q2/6K1/8/8/8/8/8 b - - 0 1", nil, OutcomeStalemate},
+		{"4k3/8/8/8/8/8/8/2B1KB2 w - - 0 1", nil, OutcomeNone},
+		{"4k3/8/8/8/8/8/8/4KB2 w - - 0 1", nil, OutcomeInsufficientMaterial},
+		{"2b1k3/8/8/8/8/8/8/4KB2 w - - 0 1", nil, OutcomeInsufficientMaterial},
+		{data.StartFEN, []string{"Nf3", "Nf6", "Ng1", "Ng8", "Nf3"}, OutcomeNone},
+		{data.StartFEN, []string{"Nf3", "Nf6", "Ng1", "Ng8", "Nf3", "Nf6", "Ng1", "Ng8", "Nf3"}, OutcomeRepetition},
+		{data.StartFEN, []string{"Nf3", "Nf6", "Ng1", "Ng8", "Nf3", "Nf6", "Ng1"}, OutcomeNone},
+		{data.StartFEN, []string{"Nf3", "Nf6", "Ng1", "Ng8", "Nf3", "Nf6", "Ng1", "Ng8"}, OutcomeRepetition},
+	}
+	for _, test := range tests {
+		game := ParseFen(test.fen)
+		p := game.Position()
+		for _, san := range test.moves {
+			if !p.MakeGameMove(p.ParseSAN(san)) {
+				t.Fatalf("%v: illegal move %v", test.fen, san)
+			}
+		}
+		if got := p.Outcome(); got != test.want {
+			t.Errorf("%v %v: expected %v but got %v", test.fen, test.moves, test.want, got)
+		}
+	}
+}
+
+func TestOutcomeFiftyMove(t *testing.T) {
+	game := ParseFen("4k3/8/8/8/8/8/8/R3K3 w - - 0 1")
+	p := game.Position()
+	p.FiftyMove = 99
+	p.MakeGameMove(p.ParseSAN("Ra2"))
+	if got := p.Outcome(); got != OutcomeFiftyMove {
+		t.Errorf("Expected %v but got %v", OutcomeFiftyMove, got)
+	}
+}
+
+func TestCountLegalMoves(t *testing.T) {
+	tests := []struct {
+		fen   string
+		side  int
+		white int
+		black int
+	}{
+		{data.StartFEN, data.White, 20, 20},
+		{"7k/5Q2/6K1/8/8/8/8/8 b - - 0 1", data.Black, 26, 0},
+		{"7k/8/6K1/8/8/8/8/5Q2 w - - 0 1", data.White, 27, 1},
+	}
+	for _, test := range tests {
+		game := ParseFen(test.fen)
+		p := game.Position()
+		key := p.PositionKey
+		if got := p.CountLegalMoves(data.White); got != test.white {
+			t.Errorf("%v: expected %v white moves but got %v", test.fen, test.white, got)
+		}
+		if got := p.CountLegalMoves(data.Black); got != test.black {
+			t.Errorf("%v: expected %v black moves but got %v", test.fen, test.black, got)
+		}
+		if p.Side != test.side || p.PositionKey != key {
+			t.Errorf("%v: expected the position to be restored", test.fen)
+		}
+	}
+}
+
+func TestParseFenResetsRepetitions(t *testing.T) {
+	game := ParseFen(data.StartFEN)
+	p := game.Position()
+	for _, san := range []string{"Nf3", "Nf6", "Ng1", "Ng8"} {
+		p.MakeGameMove(p.ParseSAN(san))
+	}
+	if count := p.Positions.Count(p.PositionKey); count != 2 {
+		t.Fatalf("Expected the start position to have occurred twice but got %v", count)
+	}
+
+	p.ParseFen(data.StartFEN)
+	if count := p.Positions.Count(p.PositionKey); count != 1 || len(p.Positions) != 1 {
+		t.Errorf("Expected only the start position once but got %v", p.Positions)
+	}
+}
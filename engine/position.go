@@ -7,10 +7,12 @@ import (
 )
 
 // CheckBitboard validates that the board is in the correct form for the current
-// position
-// TODO make this check based on a flag
+// position and that the incrementally updated key matches one generated from
+// scratch, it only runs when VerifyHash is set as it is slow
 func (p *Position) CheckBitboard() {
-	return
+	if !p.VerifyHash {
+		return
+	}
 	newBB := Bitboard{}
 	for sq := 0; sq < 64; sq++ {
 		piece := p.Board.PieceAt(sq)
@@ -20,15 +22,14 @@ func (p *Position) CheckBitboard() {
 	}
 	for piece := data.WP; piece <= data.BK; piece++ {
 		original := p.Board.CountBits(p.Board.GetBitboardForPiece(piece))
-		copy := newBB.CountBits(p.Board.GetBitboardForPiece(piece))
+		copy := newBB.CountBits(newBB.GetBitboardForPiece(piece))
 		if original != copy {
-			panic("err")
+			panic(fmt.Errorf("CheckBitboard: %v pieces of type %v but the squares hold %v", original, piece, copy))
 		}
 	}
 
-	if p.PositionKey != p.GeneratePositionKey() {
-		fmt.Printf("%v - %v\n", p.PositionKey, p.GeneratePositionKey())
-		panic("err")
+	if key := p.GeneratePositionKey(); p.PositionKey != key {
+		panic(fmt.Errorf("CheckBitboard: position key %v but generated %v", p.PositionKey, key))
 	}
 }
 
@@ -109,6 +110,7 @@ func (p *Position) MakeMove(move int) (bool, int, int, int) {
 		p.ClearPiece(data.Square120ToSquare64[to])
 	}
 	p.Play++
+	p.GamePly++
 
 	piece := p.Board.PieceAt(data.Square120ToSquare64[from])
 	if piece == data.WP || piece == data.BP {
@@ -136,10 +138,11 @@ func (p *Position) MakeMove(move int) (bool, int, int, int) {
 
 	p.PositionHistory.AddPositionHistory(p.PositionKey)
 	if p.IsKingAttacked(p.Side) {
-		p.TakeMoveBack(move, p.EnPassant, castlePerm, fifty)
-		return false, p.EnPassant, castlePerm, fifty
+		p.TakeMoveBack(move, enPas, castlePerm, fifty)
+		return false, enPas, castlePerm, fifty
 	}
 	//p.History[p.PositionKey]++
+	p.CheckBitboard()
 	return true, enPas, castlePerm, fifty
 }
 
@@ -147,6 +150,7 @@ func (p *Position) MakeMove(move int) (bool, int, int, int) {
 func (p *Position) TakeMoveBack(move int, enPas int, castlePerm int, fifty int) {
 	p.CheckBitboard()
 	p.Play--
+	p.GamePly--
 	from := data.FromSquare(move)
 	to := data.ToSquare(move)
 
@@ -214,18 +218,49 @@ func (p *Position) MovePiece(from, to int) {
 	p.Board.RemovePieceAtSquare(data.Square120ToSquare64[from], piece)
 	p.hashPiece(piece, to)
 	p.Board.SetPieceAtSquare(data.Square120ToSquare64[to], piece)
+	if p.PieceScores != nil {
+		p.PieceScore += p.PieceScores[piece][data.Square120ToSquare64[to]] - p.PieceScores[piece][data.Square120ToSquare64[from]]
+	}
 }
 
 // ClearPiece removes a piece at the given square
 func (p *Position) ClearPiece(sq64 int) {
-	p.hashPiece(p.Board.PieceAt(sq64), data.Square64ToSquare120[sq64])
-	p.Board.RemovePieceAtSquare(sq64, p.Board.PieceAt(sq64))
+	piece := p.Board.PieceAt(sq64)
+	p.hashPiece(piece, data.Square64ToSquare120[sq64])
+	p.Board.RemovePieceAtSquare(sq64, piece)
+	if p.PieceScores != nil {
+		p.PieceScore -= p.PieceScores[piece][sq64]
+	}
 }
 
 // AddPiece adds a piece at the given square
 func (p *Position) AddPiece(sq, piece int) {
 	p.hashPiece(piece, data.Square64ToSquare120[sq])
 	p.Board.SetPieceAtSquare(sq, piece)
+	if p.PieceScores != nil {
+		p.PieceScore += p.PieceScores[piece][sq]
+	}
+}
+
+// SetPieceScores attaches a table of per piece, per square scores to the
+// position. PieceScore holds the sum of the table for every piece on the
+// board and is kept up to date as pieces are added, removed and moved
+func (p *Position) SetPieceScores(scores *[13][64]int32) {
+	p.PieceScores = scores
+	p.PieceScore = p.GeneratePieceScore()
+}
+
+// GeneratePieceScore sums the attached piece scores from scratch
+func (p *Position) GeneratePieceScore() int32 {
+	var score int32
+	if p.PieceScores == nil {
+		return score
+	}
+	for bb := p.Board.Pieces; bb != 0; bb &= bb - 1 {
+		sq := FirstSquare(bb)
+		score += p.PieceScores[p.Board.PieceAt(sq)][sq]
+	}
+	return score
 }
 
 func (p *Position) hashPiece(piece, square int) {
@@ -1,6 +1,7 @@
 package engine
 
 import (
+	"math/rand"
 	"testing"
 
 	"github.com/AdamGriffiths31/ChessEngine/data"
@@ -251,3 +252,150 @@ func TestPositionHistoryTakeBackNullMove(t *testing.T) {
 		t.Errorf("Expected %v but got %v", 0, game.Position().PositionHistory.Count)
 	}
 }
+
+func TestMakeMoveInvalidMoveRestoresEnPassant(t *testing.T) {
+	game := ParseFen("6k1/8/8/8/8/8/7P/1r5K w - - 0 1")
+	fen := game.position.ToFEN()
+	valid, _, _, _ := game.position.MakeMove(game.position.ParseMove([]byte("h2h4")))
+
+	if valid {
+		t.Errorf("Expected invalid but got %v", valid)
+	}
+	if game.position.ToFEN() != fen {
+		t.Errorf("Expected %v but got %v", fen, game.position.ToFEN())
+	}
+}
+
+func TestVerifyHashRandomGames(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	fens := []string{
+		data.StartFEN,
+		"r3k2r/p1ppqpb1/bn2pnp1/3PN3/1p2P3/2N2Q1p/PPPBBPPP/R3K2R w KQkq - 0 1",
+		"8/2p5/3p4/KP5r/1R3p1k/8/4P1P1/8 w - - 0 1",
+	}
+	for game := 0; game < 30; game++ {
+		g := ParseFen(fens[game%len(fens)])
+		p := g.Position()
+		p.VerifyHash = true
+		for ply := 0; ply < 200; ply++ {
+			moves := p.LegalMoves()
+			if len(moves) == 0 {
+				break
+			}
+			// try a null move and every move before playing one at random so
+			// the take backs are checked too
+			_, enPas, castlePerm := p.MakeNullMove()
+			p.TakeNullMoveBack(enPas, castlePerm)
+			for _, move := range moves {
+				_, enPas, castlePerm, fifty := p.MakeMove(move)
+				p.TakeMoveBack(move, enPas, castlePerm, fifty)
+			}
+			p.MakeGameMove(moves[rng.Intn(len(moves))])
+		}
+	}
+}
+
+func TestVerifyHashPanicsOnMismatch(t *testing.T) {
+	game := ParseFen(data.StartFEN)
+	p := game.Position()
+	p.VerifyHash = true
+	p.PositionKey ^= 1
+	defer func() {
+		if recover() == nil {
+			t.Errorf("Expected a mismatched key to panic")
+		}
+	}()
+	p.MakeMove(p.ParseMove([]byte("e2e4")))
+}
+
+func TestCastleKeyMatchesGeneratedKey(t *testing.T) {
+	tests := []struct {
+		fen  string
+		move int
+	}{
+		{"r3k2r/8/8/8/8/8/8/R3K2R w KQkq - 0 1", MakeMoveInt(data.E1, data.G1, data.Empty, data.Empty, data.MFLAGGCA)},
+		{"r3k2r/8/8/8/8/8/8/R3K2R w KQkq - 0 1", MakeMoveInt(data.E1, data.C1, data.Empty, data.Empty, data.MFLAGGCA)},
+		{"r3k2r/8/8/8/8/8/8/R3K2R b KQkq - 0 1", MakeMoveInt(data.E8, data.G8, data.Empty, data.Empty, data.MFLAGGCA)},
+		{"r3k2r/8/8/8/8/8/8/R3K2R b KQkq - 0 1", MakeMoveInt(data.E8, data.C8, data.Empty, data.Empty, data.MFLAGGCA)},
+	}
+	for _, test := range tests {
+		game := ParseFen(test.fen)
+		p := game.Position()
+		key := p.PositionKey
+		isAllowed, enPas, castlePerm, fifty := p.MakeMove(test.move)
+		if !isAllowed {
+			t.Fatalf("%v: expected %v to be legal", test.fen, io.PrintMove(test.move))
+		}
+		if p.PositionKey != p.GeneratePositionKey() {
+			t.Errorf("%v %v: expected key %v but got %v", test.fen, io.PrintMove(test.move), p.GeneratePositionKey(), p.PositionKey)
+		}
+		p.TakeMoveBack(test.move, enPas, castlePerm, fifty)
+		if p.PositionKey != key {
+			t.Errorf("%v %v: expected the key to be restored to %v but got %v", test.fen, io.PrintMove(test.move), key, p.PositionKey)
+		}
+	}
+}
+
+// homeCastleRights returns the castling rights the pieces on their home
+// squares still allow, a right is lost once its king or rook leaves home
+func homeCastleRights(p *Position) int {
+	on := func(sq, piece int) bool { return p.Board.PieceAt(data.Square120ToSquare64[sq]) == piece }
+	rights := 0
+	if on(data.E1, data.WK) && on(data.H1, data.WR) {
+		rights |= data.WhiteKingCastle
+	}
+	if on(data.E1, data.WK) && on(data.A1, data.WR) {
+		rights |= data.WhiteQueenCastle
+	}
+	if on(data.E8, data.BK) && on(data.H8, data.BR) {
+		rights |= data.BlackKingCastle
+	}
+	if on(data.E8, data.BK) && on(data.A8, data.BR) {
+		rights |= data.BlackQueenCastle
+	}
+	return rights
+}
+
+func TestReplayMatchesFreshPosition(t *testing.T) {
+	rng := rand.New(rand.NewSource(2))
+	fens := []string{
+		data.StartFEN,
+		"r3k2r/8/8/8/8/8/8/R3K2R w KQkq - 0 1",
+		"r3k2r/p1ppqpb1/bn2pnp1/3PN3/1p2P3/2N2Q1p/PPPBBPPP/R3K2R w KQkq - 0 1",
+		"r3k2r/Pppp1ppp/1b3nbN/nP6/BBP1P3/q4N2/Pp1P2PP/R2Q1RK1 w kq - 0 1",
+		"rnbqkbnr/ppp1p1pp/8/3pPp2/8/8/PPPP1PPP/RNBQKBNR w KQkq f6 0 1",
+	}
+	for game := 0; game < 40; game++ {
+		g := ParseFen(fens[game%len(fens)])
+		p := g.Position()
+		rights := p.CastlePermission
+		for ply := 0; ply < 150; ply++ {
+			moves := p.LegalMoves()
+			if len(moves) == 0 {
+				break
+			}
+			move := moves[rng.Intn(len(moves))]
+			from := data.FromSquare(move)
+			p.MakeGameMove(move)
+
+			rights &= homeCastleRights(p)
+			if p.CastlePermission != rights {
+				t.Fatalf("%v after %v: expected castling rights %v but got %v", p.ToFEN(), io.PrintMove(move), rights, p.CastlePermission)
+			}
+			enPassant := data.NoSquare
+			if move&data.MFLAGPS != 0 {
+				enPassant = (from + data.ToSquare(move)) / 2
+			}
+			if p.EnPassant != enPassant {
+				t.Fatalf("%v after %v: expected en passant %v but got %v", p.ToFEN(), io.PrintMove(move), enPassant, p.EnPassant)
+			}
+
+			fresh := ParseFen(p.ToFEN())
+			f := fresh.Position()
+			if f.CastlePermission != p.CastlePermission || f.EnPassant != p.EnPassant || f.PositionKey != p.PositionKey {
+				t.Fatalf("%v after %v: expected castling %v en passant %v key %v from the fen but got %v %v %v",
+					p.ToFEN(), io.PrintMove(move), f.CastlePermission, f.EnPassant, f.PositionKey, p.CastlePermission, p.EnPassant, p.PositionKey)
+			}
+		}
+	}
+}
@@ -0,0 +1,314 @@
+package engine
+
+import (
+	"fmt"
+	"math/rand"
+	"sort"
+	"testing"
+
+	"github.com/AdamGriffiths31/ChessEngine/data"
+)
+
+// referenceFens seed the fuzz target, they are full of pins, checks, en
+// passant captures and promotions
+var referenceFens = []string{
+	data.StartFEN,
+	"r3k2r/p1ppqpb1/bn2pnp1/3PN3/1p2P3/2N2Q1p/PPPBBPPP/R3K2R w KQkq - 0 1",
+	"8/2p5/3p4/KP5r/1R3p1k/8/4P1P1/8 w - - 0 1",
+	"r3k2r/Pppp1ppp/1b3nbN/nP6/BBP1P3/q4N2/Pp1P2PP/R2Q1RK1 w kq - 0 1",
+	"rnbq1k1r/pp1Pbppp/2p5/8/2B5/8/PPP1NnPP/RNBQK2R w KQ - 0 1",
+	"8/8/8/2k5/3Pp3/8/8/4KQ2 b - d3 0 1",
+	"8/8/3k4/8/1b2Pp2/8/8/6K1 b - e3 0 1",
+	"rnbqkbnr/ppp1p1pp/8/3pPp2/8/8/PPPP1PPP/RNBQKBNR w KQkq f6 0 1",
+	"4k3/8/8/2pP4/8/8/8/4K3 w - c6 0 1",
+}
+
+// offBoard marks the squares around the board in a reference mailbox
+const offBoard = -1
+
+// referenceBoard is a plain 120 square mailbox, kept apart from the
+// bitboards so the reference does not share the code it checks
+type referenceBoard [120]int
+
+// newReferenceBoard copies the pieces of the position to a mailbox
+func newReferenceBoard(p *Position) referenceBoard {
+	var b referenceBoard
+	for sq := range b {
+		b[sq] = offBoard
+		if sq64 := data.Square120ToSquare64[sq]; sq64 < 64 {
+			b[sq] = p.Board.PieceAt(sq64)
+		}
+	}
+	return b
+}
+
+// attacked checks if side attacks sq by stepping out from it
+func (b *referenceBoard) attacked(sq, side int) bool {
+	pawn, knight, bishop, rook, queen, king := data.WP, data.WN, data.WB, data.WR, data.WQ, data.WK
+	pawnFrom := [2]int{sq - 9, sq - 11}
+	if side == data.Black {
+		pawn, knight, bishop, rook, queen, king = data.BP, data.BN, data.BB, data.BR, data.BQ, data.BK
+		pawnFrom = [2]int{sq + 9, sq + 11}
+	}
+	for _, from := range pawnFrom {
+		if b[from] == pawn {
+			return true
+		}
+	}
+	for _, dir := range data.KnightDirection {
+		if b[sq+dir] == knight {
+			return true
+		}
+	}
+	for _, dir := range data.KingDirection {
+		if b[sq+dir] == king {
+			return true
+		}
+	}
+	slides := func(dirs [4]int, slider int) bool {
+		for _, dir := range dirs {
+			to := sq + dir
+			for b[to] == data.Empty {
+				to += dir
+			}
+			if b[to] == slider || b[to] == queen {
+				return true
+			}
+		}
+		return false
+	}
+	return slides(data.RookDirection, rook) || slides(data.BishopDirection, bishop)
+}
+
+// kingSquare returns where side's king is
+func (b *referenceBoard) kingSquare(side int) int {
+	king := data.WK
+	if side == data.Black {
+		king = data.BK
+	}
+	for sq, piece := range b {
+		if piece == king {
+			return sq
+		}
+	}
+	return offBoard
+}
+
+// play returns the board after the move, which must be pseudo legal
+func (b referenceBoard) play(move int) referenceBoard {
+	from, to := data.FromSquare(move), data.ToSquare(move)
+	piece := b[from]
+	b[from] = data.Empty
+	b[to] = piece
+	if promoted := data.Promoted(move); promoted != data.Empty {
+		b[to] = promoted
+	}
+	if move&data.MFLAGEP != 0 {
+		if piece == data.WP {
+			b[to-10] = data.Empty
+		} else {
+			b[to+10] = data.Empty
+		}
+	}
+	if move&data.MFLAGGCA != 0 {
+		rookFrom, rookTo := to+1, to-1
+		if to == data.C1 || to == data.C8 {
+			rookFrom, rookTo = to-2, to+1
+		}
+		b[rookTo] = b[rookFrom]
+		b[rookFrom] = data.Empty
+	}
+	return b
+}
+
+// referenceLegalMoves generates every legal move in the position by trying
+// each step and ray of every piece, then playing it on a copy of the mailbox
+// to see if the king is left in check
+func referenceLegalMoves(p *Position) []int {
+	b := newReferenceBoard(p)
+	side := p.Side
+	var pseudo []int
+	add := func(from, to, flag int) {
+		pseudo = append(pseudo, MakeMoveInt(from, to, b[to], data.Empty, flag))
+	}
+	addPawn := func(from, to, flag int) {
+		captured := b[to]
+		if move := MakeMoveInt(from, to, captured, data.Empty, flag); data.RanksBoard[to] != data.Rank1 && data.RanksBoard[to] != data.Rank8 {
+			pseudo = append(pseudo, move)
+			return
+		}
+		promotions := []int{data.WQ, data.WR, data.WB, data.WN}
+		if side == data.Black {
+			promotions = []int{data.BQ, data.BR, data.BB, data.BN}
+		}
+		for _, promoted := range promotions {
+			pseudo = append(pseudo, MakeMoveInt(from, to, captured, promoted, flag))
+		}
+	}
+	isEnemy := func(piece int) bool {
+		return piece != data.Empty && piece != offBoard && data.PieceCol[piece] != side
+	}
+
+	for from, piece := range b {
+		if piece == data.Empty || piece == offBoard || data.PieceCol[piece] != side {
+			continue
+		}
+		switch piece {
+		case data.WP, data.BP:
+			forward, start := 10, data.Rank2
+			if side == data.Black {
+				forward, start = -10, data.Rank7
+			}
+			if b[from+forward] == data.Empty {
+				addPawn(from, from+forward, 0)
+				if data.RanksBoard[from] == start && b[from+2*forward] == data.Empty {
+					add(from, from+2*forward, data.MFLAGPS)
+				}
+			}
+			for _, to := range []int{from + forward - 1, from + forward + 1} {
+				if isEnemy(b[to]) {
+					addPawn(from, to, 0)
+				}
+				if to == p.EnPassant && b[to] != offBoard {
+					add(from, to, data.MFLAGEP)
+				}
+			}
+		case data.WN, data.BN, data.WK, data.BK:
+			dirs := data.KnightDirection
+			if piece == data.WK || piece == data.BK {
+				dirs = data.KingDirection
+			}
+			for _, dir := range dirs {
+				if to := from + dir; b[to] == data.Empty || isEnemy(b[to]) {
+					add(from, to, 0)
+				}
+			}
+		default:
+			var dirs []int
+			if piece != data.WB && piece != data.BB {
+				dirs = append(dirs, data.RookDirection[:]...)
+			}
+			if piece != data.WR && piece != data.BR {
+				dirs = append(dirs, data.BishopDirection[:]...)
+			}
+			for _, dir := range dirs {
+				to := from + dir
+				for ; b[to] == data.Empty; to += dir {
+					add(from, to, 0)
+				}
+				if isEnemy(b[to]) {
+					add(from, to, 0)
+				}
+			}
+		}
+	}
+
+	castles := []struct {
+		perm, king, to, rook int
+		empty, safe          []int
+	}{
+		{data.WhiteKingCastle, data.E1, data.G1, data.H1, []int{data.F1, data.G1}, []int{data.E1, data.F1}},
+		{data.WhiteQueenCastle, data.E1, data.C1, data.A1, []int{data.D1, data.C1, data.B1}, []int{data.E1, data.D1}},
+		{data.BlackKingCastle, data.E8, data.G8, data.H8, []int{data.F8, data.G8}, []int{data.E8, data.F8}},
+		{data.BlackQueenCastle, data.E8, data.C8, data.A8, []int{data.D8, data.C8, data.B8}, []int{data.E8, data.D8}},
+	}
+	king := data.WK
+	if side == data.Black {
+		king = data.BK
+	}
+	for _, castle := range castles {
+		if p.CastlePermission&castle.perm == 0 || b[castle.king] != king {
+			continue
+		}
+		ok := true
+		for _, sq := range castle.empty {
+			ok = ok && b[sq] == data.Empty
+		}
+		for _, sq := range castle.safe {
+			ok = ok && !b.attacked(sq, side^1)
+		}
+		if ok {
+			add(castle.king, castle.to, data.MFLAGGCA)
+		}
+	}
+
+	var legal []int
+	for _, move := range pseudo {
+		after := b.play(move)
+		if !after.attacked(after.kingSquare(side), side^1) {
+			legal = append(legal, move)
+		}
+	}
+	return legal
+}
+
+// generatedLegalMoves returns the moves from the generator that MakeMove
+// accepts
+func generatedLegalMoves(p *Position) []int {
+	ml := &MoveList{}
+	p.GenerateAllMoves(ml)
+	var legal []int
+	for i := 0; i < ml.Count; i++ {
+		if p.isLegal(ml.Moves[i].Move) {
+			legal = append(legal, ml.Moves[i].Move)
+		}
+	}
+	return legal
+}
+
+// moveDifference returns the moves in a that are not in b
+func moveDifference(a, b []int) []string {
+	in := map[int]bool{}
+	for _, move := range b {
+		in[move] = true
+	}
+	var diff []string
+	for _, move := range a {
+		if !in[move] {
+			diff = append(diff, moveString(move))
+		}
+	}
+	sort.Strings(diff)
+	return diff
+}
+
+// moveString formats a move by its squares and any promotion
+func moveString(move int) string {
+	square := func(sq int) string {
+		return fmt.Sprintf("%c%c", 'a'+data.FilesBoard[sq], '1'+data.RanksBoard[sq])
+	}
+	s := square(data.FromSquare(move)) + square(data.ToSquare(move))
+	if promoted := data.Promoted(move); promoted != data.Empty {
+		s += string("pnbrqk"[pieceKind(promoted)-data.WP])
+	}
+	return s
+}
+
+func FuzzLegalMovesMatchReference(f *testing.F) {
+	r := rand.New(rand.NewSource(1))
+	for i := range referenceFens {
+		for n := 0; n < 4; n++ {
+			path := make([]byte, 100)
+			r.Read(path)
+			f.Add(uint8(i), path)
+		}
+	}
+
+	f.Fuzz(func(t *testing.T, fen uint8, path []byte) {
+		game := ParseFen(referenceFens[int(fen)%len(referenceFens)])
+		p := game.Position()
+		for _, choice := range path {
+			want, got := referenceLegalMoves(p), generatedLegalMoves(p)
+			missing, extra := moveDifference(want, got), moveDifference(got, want)
+			if len(missing) > 0 || len(extra) > 0 {
+				t.Fatalf("%v: generator is missing %v and has extra %v", p.ToFEN(), missing, extra)
+			}
+			if len(want) == 0 {
+				return
+			}
+			p.MakeMove(want[int(choice)%len(want)])
+			p.Play = 0
+			p.PositionHistory.RemovePositionHistory()
+		}
+	})
+}
@@ -0,0 +1,23 @@
+package engine
+
+// RepetitionTable counts how many times each position key has occurred in
+// the moves played in the game, not those tried by the search
+type RepetitionTable map[uint64]int
+
+// Add records another occurrence of the key
+func (r RepetitionTable) Add(key uint64) {
+	r[key]++
+}
+
+// Remove takes back an occurrence of the key
+func (r RepetitionTable) Remove(key uint64) {
+	r[key]--
+	if r[key] <= 0 {
+		delete(r, key)
+	}
+}
+
+// Count returns how many times the key has occurred
+func (r RepetitionTable) Count(key uint64) int {
+	return r[key]
+}
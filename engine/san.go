@@ -0,0 +1,216 @@
+package engine
+
+import (
+	"strings"
+
+	"github.com/AdamGriffiths31/ChessEngine/data"
+)
+
+// ParseSAN parses a move in standard algebraic notation, returning
+// data.NoMove if it does not match exactly one legal move
+func (p *Position) ParseSAN(san string) int {
+	match := data.NoMove
+	for _, move := range p.sanCandidates(san) {
+		if !p.isLegal(move) {
+			continue
+		}
+		if match != data.NoMove {
+			return data.NoMove
+		}
+		match = move
+	}
+	return match
+}
+
+// sanCandidates returns the pseudo legal moves matching the SAN, they may
+// leave the king in check
+func (p *Position) sanCandidates(san string) []int {
+	san = strings.TrimRight(san, "+#!?")
+	if san == "O-O" || san == "0-0" || san == "O-O-O" || san == "0-0-0" {
+		return p.castleCandidates(len(san) == 5)
+	}
+	if san == "" {
+		return nil
+	}
+
+	piece := data.WP
+	if i := strings.IndexByte("NBRQK", san[0]); i >= 0 {
+		piece = data.WN + i
+		san = san[1:]
+	}
+
+	promoted := data.Empty
+	if i := strings.IndexAny(san, "NBRQ"); i >= 0 {
+		promoted = data.WN + strings.IndexByte("NBRQ", san[i])
+		san = strings.TrimSuffix(san[:i], "=")
+	}
+
+	if len(san) < 2 {
+		return nil
+	}
+	to := san[len(san)-2:]
+	if to[0] < 'a' || to[0] > 'h' || to[1] < '1' || to[1] > '8' {
+		return nil
+	}
+	toSq := data.FileRankToSquare(int(to[0]-'a'), int(to[1]-'1'))
+	from := strings.TrimSuffix(san[:len(san)-2], "x")
+
+	var candidates []int
+	ml := &MoveList{}
+	p.GenerateAllMoves(ml)
+	for i := 0; i < ml.Count; i++ {
+		move := ml.Moves[i].Move
+		fromSq := data.FromSquare(move)
+		if data.ToSquare(move) != toSq || pieceKind(p.Board.PieceAt(data.Square120ToSquare64[fromSq])) != piece {
+			continue
+		}
+		if pieceKind(data.Promoted(move)) != promoted || !matchesDisambiguation(fromSq, from) {
+			continue
+		}
+		candidates = append(candidates, move)
+	}
+	return candidates
+}
+
+// castleCandidates returns the castle for the side to move if it can be
+// generated
+func (p *Position) castleCandidates(queenSide bool) []int {
+	var candidates []int
+	ml := &MoveList{}
+	p.GenerateAllMoves(ml)
+	for i := 0; i < ml.Count; i++ {
+		move := ml.Moves[i].Move
+		if move&data.MFLAGGCA == 0 {
+			continue
+		}
+		to := data.ToSquare(move)
+		if (to == data.C1 || to == data.C8) == queenSide {
+			candidates = append(candidates, move)
+		}
+	}
+	return candidates
+}
+
+// isLegal checks the move does not leave the side to move in check
+func (p *Position) isLegal(move int) bool {
+	isAllowed, enPas, castlePerm, fifty := p.MakeMove(move)
+	if !isAllowed {
+		return false
+	}
+	p.TakeMoveBack(move, enPas, castlePerm, fifty)
+	return true
+}
+
+// matchesDisambiguation checks the from square against the file and rank
+// given in the SAN before the destination
+func matchesDisambiguation(sq int, from string) bool {
+	for _, ch := range from {
+		if ch >= 'a' && ch <= 'h' && data.FilesBoard[sq] != int(ch-'a') {
+			return false
+		}
+		if ch >= '1' && ch <= '8' && data.RanksBoard[sq] != int(ch-'1') {
+			return false
+		}
+	}
+	return true
+}
+
+// pieceKind returns the white piece of the same type
+func pieceKind(piece int) int {
+	if piece >= data.BP {
+		return piece - (data.BP - data.WP)
+	}
+	return piece
+}
+
+// SAN formats a legal move in standard algebraic notation, adding the file,
+// rank or both of the moving piece when another piece of the same type can
+// reach the destination. It returns an empty string for data.NoMove or a
+// move with nothing on its from square
+func (p *Position) SAN(move int) string {
+	if move == data.NoMove {
+		return ""
+	}
+	from := data.FromSquare(move)
+	to := data.ToSquare(move)
+	var sb strings.Builder
+
+	if move&data.MFLAGGCA != 0 {
+		if to == data.C1 || to == data.C8 {
+			sb.WriteString("O-O-O")
+		} else {
+			sb.WriteString("O-O")
+		}
+	} else {
+		piece := pieceKind(p.Board.PieceAt(data.Square120ToSquare64[from]))
+		if piece == data.Empty {
+			return ""
+		}
+		capture := move&data.MFLAGCAP != 0
+		if piece == data.WP {
+			if capture {
+				sb.WriteByte(byte('a' + data.FilesBoard[from]))
+			}
+		} else {
+			sb.WriteByte("NBRQK"[piece-data.WN])
+			sb.WriteString(p.disambiguation(move, piece))
+		}
+		if capture {
+			sb.WriteByte('x')
+		}
+		sb.WriteByte(byte('a' + data.FilesBoard[to]))
+		sb.WriteByte(byte('1' + data.RanksBoard[to]))
+		if promoted := data.Promoted(move); promoted != data.Empty {
+			sb.WriteByte('=')
+			sb.WriteByte("NBRQ"[pieceKind(promoted)-data.WN])
+		}
+	}
+
+	isAllowed, enPas, castlePerm, fifty := p.MakeMove(move)
+	if isAllowed {
+		if p.IsKingAttacked(p.Side ^ 1) {
+			if p.hasLegalMove() {
+				sb.WriteByte('+')
+			} else {
+				sb.WriteByte('#')
+			}
+		}
+		p.TakeMoveBack(move, enPas, castlePerm, fifty)
+	}
+	return sb.String()
+}
+
+// disambiguation returns the file, rank or square of the move's from square
+// needed to tell it apart from other legal moves of the piece type to the
+// same square
+func (p *Position) disambiguation(move, piece int) string {
+	from := data.FromSquare(move)
+	to := data.ToSquare(move)
+	ambiguous, sameFile, sameRank := false, false, false
+
+	ml := &MoveList{}
+	p.GenerateAllMoves(ml)
+	for i := 0; i < ml.Count; i++ {
+		other := ml.Moves[i].Move
+		otherFrom := data.FromSquare(other)
+		if otherFrom == from || data.ToSquare(other) != to ||
+			pieceKind(p.Board.PieceAt(data.Square120ToSquare64[otherFrom])) != piece || !p.isLegal(other) {
+			continue
+		}
+		ambiguous = true
+		sameFile = sameFile || data.FilesBoard[otherFrom] == data.FilesBoard[from]
+		sameRank = sameRank || data.RanksBoard[otherFrom] == data.RanksBoard[from]
+	}
+
+	file := string(rune('a' + data.FilesBoard[from]))
+	rank := string(rune('1' + data.RanksBoard[from]))
+	switch {
+	case !ambiguous:
+		return ""
+	case !sameFile:
+		return file
+	case !sameRank:
+		return rank
+	}
+	return file + rank
+}
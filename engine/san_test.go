@@ -0,0 +1,86 @@
+package engine
+
+import (
+	"testing"
+
+	"github.com/AdamGriffiths31/ChessEngine/data"
+	"github.com/AdamGriffiths31/ChessEngine/io"
+)
+
+func TestParseSAN(t *testing.T) {
+	tests := []struct {
+		fen  string
+		san  string
+		want string
+	}{
+		{data.StartFEN, "e4", "e2e4"},
+		{data.StartFEN, "Nf3", "g1f3"},
+		{"r3k2r/8/8/8/8/8/8/R3K2R w KQkq - 0 1", "O-O", "e1g1"},
+		{"r3k2r/8/8/8/8/8/8/R3K2R b KQkq - 0 1", "O-O-O", "e8c8"},
+		{"4k3/8/8/8/8/8/8/R3K2R w - - 0 1", "Rhf1", "h1f1"},
+		{"4k3/8/8/8/8/5N2/8/1N2K3 w - - 0 1", "Nd2", "NoMove"},
+		{"4k3/8/8/8/8/8/R7/R3K3 w - - 0 1", "R2a4", "a2a4"},
+		{"4k3/1P6/8/8/8/8/8/4K3 w - - 0 1", "b8=N", "b7b8n"},
+		{"4k3/8/8/3pP3/8/8/8/4K3 w - d6 0 1", "exd6", "e5d6"},
+		{"4k3/8/8/8/1b6/2N3N1/8/4K3 w - - 0 1", "Ne2", "g3e2"},
+		{"4k3/8/8/8/8/8/8/4K3 w - - 0 1", "Nf3", "NoMove"},
+	}
+	for _, test := range tests {
+		game := ParseFen(test.fen)
+		move := game.Position().ParseSAN(test.san)
+		got := "NoMove"
+		if move != data.NoMove {
+			got = io.PrintMove(move)
+		}
+		if got != test.want {
+			t.Errorf("%v %v: expected %v but got %v", test.fen, test.san, test.want, got)
+		}
+	}
+}
+
+func TestSAN(t *testing.T) {
+	tests := []struct {
+		fen  string
+		move string
+		want string
+	}{
+		{data.StartFEN, "e2e4", "e4"},
+		{data.StartFEN, "g1f3", "Nf3"},
+		{"r3k2r/8/8/8/8/8/8/R3K2R w KQkq - 0 1", "e1g1", "O-O"},
+		{"r3k2r/8/8/8/8/8/8/R3K2R b KQkq - 0 1", "e8c8", "O-O-O"},
+		{"4k3/8/8/8/8/8/7K/R6R w - - 0 1", "a1d1", "Rad1"},
+		{"4k3/R7/8/8/8/8/7K/R7 w - - 0 1", "a1a4", "R1a4"},
+		{"4k3/8/8/8/8/8/R7/R3K3 w - - 0 1", "a2a4", "Ra4"},
+		{"4k3/8/8/8/8/1Q6/8/1Q1QK3 w - - 0 1", "b1d3", "Qb1d3"},
+		{"4k3/1P6/8/8/8/8/8/4K3 w - - 0 1", "b7b8n", "b8=N"},
+		{"4k3/8/8/3pP3/8/8/8/4K3 w - d6 0 1", "e5d6", "exd6"},
+		{"4k3/8/8/8/1b6/2N3N1/8/4K3 w - - 0 1", "g3e2", "Ne2"},
+		{"r1bqkb1r/pppp1ppp/2n2n2/4p2Q/2B1P3/8/PPPP1PPP/RNB1K1NR w KQkq - 4 4", "h5f7", "Qxf7#"},
+		{"4k3/8/8/8/8/8/8/R3K3 w - - 0 1", "a1a8", "Ra8+"},
+		{data.StartFEN, "NoMove", ""},
+	}
+	for _, test := range tests {
+		game := ParseFen(test.fen)
+		p := game.Position()
+		if got := p.SAN(p.ParseMove([]byte(test.move))); got != test.want {
+			t.Errorf("%v %v: expected %v but got %v", test.fen, test.move, test.want, got)
+		}
+	}
+}
+
+func TestSANRoundTrip(t *testing.T) {
+	game := ParseFen("r3k2r/p1ppqpb1/bn2pnp1/3PN3/1p2P3/2N2Q1p/PPPBBPPP/R3K2R w KQkq - 0 1")
+	p := game.Position()
+	ml := &MoveList{}
+	p.GenerateAllMoves(ml)
+	for i := 0; i < ml.Count; i++ {
+		move := ml.Moves[i].Move
+		if !p.isLegal(move) {
+			continue
+		}
+		san := p.SAN(move)
+		if got := p.ParseSAN(san); got != move {
+			t.Errorf("%v: expected %v but got %v", san, io.PrintMove(move), io.PrintMove(got))
+		}
+	}
+}
@@ -0,0 +1,149 @@
+package engine
+
+import (
+	"math/bits"
+
+	"github.com/AdamGriffiths31/ChessEngine/data"
+)
+
+// SEE returns the material the side to move wins or loses if the pieces
+// attacking the destination of move keep recapturing, least valuable first
+func (p *Position) SEE(move int) int {
+	return p.SEEWithValues(move, data.PieceVal)
+}
+
+// SEEWithValues is SEE with the pieces valued by values, indexed by piece
+func (p *Position) SEEWithValues(move int, values [13]int) int {
+	from := data.Square120ToSquare64[data.FromSquare(move)]
+	to := data.Square120ToSquare64[data.ToSquare(move)]
+
+	attacker := p.Board.PieceAt(from)
+	gain := values[data.Captured(move)]
+	if move&data.MFLAGEP != 0 {
+		gain = values[data.WP]
+	}
+	if promoted := data.Promoted(move); promoted != data.Empty {
+		gain += values[promoted] - values[data.WP]
+		attacker = promoted
+	}
+
+	occupied := p.Board.Pieces &^ (uint64(1) << from)
+	if move&data.MFLAGEP != 0 {
+		if p.Side == data.White {
+			occupied &^= uint64(1) << (to - 8)
+		} else {
+			occupied &^= uint64(1) << (to + 8)
+		}
+	}
+	return p.exchange(to, p.Side^1, attacker, gain, occupied, values)
+}
+
+// SEEOnSquare returns the material side wins by starting the exchange on sq
+// with its least valuable attacker, or zero if it has none. The square does
+// not need to hold a piece, any piece on it is taken to be the other side's
+func (p *Position) SEEOnSquare(sq, side int) int {
+	to := data.Square120ToSquare64[sq]
+	from, attacker := p.leastValuableAttacker(to, side, p.Board.Pieces)
+	if attacker == data.Empty {
+		return 0
+	}
+	if attacker == data.WK || attacker == data.BK {
+		if other, _ := p.leastValuableAttacker(to, side^1, p.Board.Pieces&^(uint64(1)<<from)); other != -1 {
+			return 0
+		}
+	}
+	gain := 0
+	if target := p.Board.PieceAt(to); target != data.Empty {
+		gain = data.PieceVal[target]
+	}
+	return p.exchange(to, side^1, attacker, gain, p.Board.Pieces&^(uint64(1)<<from), data.PieceVal)
+}
+
+// exchange plays out the recaptures on to once attacker has taken, winning
+// gain, with side to recapture next. It returns the gain of the first capture
+// when each side can stop recapturing as soon as it would lose material
+func (p *Position) exchange(to, side, attacker, gain int, occupied uint64, values [13]int) int {
+	var gains [32]int
+	gains[0] = gain
+	d := 0
+	for d < len(gains)-1 {
+		sq, piece := p.leastValuableAttacker(to, side, occupied)
+		if piece == data.Empty {
+			break
+		}
+		d++
+		gains[d] = values[attacker] - gains[d-1]
+		if piece == data.WK || piece == data.BK {
+			// the king can only recapture if the square is no longer defended
+			if other, _ := p.leastValuableAttacker(to, side^1, occupied&^(uint64(1)<<sq)); other != -1 {
+				d--
+				break
+			}
+		}
+		occupied &^= uint64(1) << sq
+		attacker = piece
+		side ^= 1
+	}
+
+	for ; d > 0; d-- {
+		if -gains[d] < gains[d-1] {
+			gains[d-1] = -gains[d]
+		}
+	}
+	return gains[0]
+}
+
+// leastValuableAttacker returns the square and piece of the cheapest piece of
+// side attacking sq with only the occupied pieces on the board, the square is
+// -1 and the piece data.Empty if there is none. A piece pinned to its king
+// only attacks squares along the pin
+func (p *Position) leastValuableAttacker(sq, side int, occupied uint64) (int, int) {
+	b := &p.Board
+	pieces := [6]uint64{b.WhitePawn, b.WhiteKnight, b.WhiteBishop, b.WhiteRook, b.WhiteQueen, b.WhiteKing}
+	first := data.WP
+	pawnAttacks := p.getBlackPawnAttackedSquares(uint64(1) << sq)
+	if side == data.Black {
+		pieces = [6]uint64{b.BlackPawn, b.BlackKnight, b.BlackBishop, b.BlackRook, b.BlackQueen, b.BlackKing}
+		first = data.BP
+		pawnAttacks = p.getWhitePawnAttackedSquares(uint64(1) << sq)
+	}
+
+	diagonal := data.GetBishopAttacks(occupied, sq)
+	straight := data.GetRookAttacks(occupied, sq)
+	attacks := [6]uint64{
+		pawnAttacks,
+		PreCalculatedKnightMoves[sq],
+		diagonal,
+		straight,
+		diagonal | straight,
+		PreCalculatedKingMoves[sq],
+	}
+	king := pieces[5]
+	for i, bb := range pieces {
+		for found := bb & attacks[i] & occupied; found != 0; found &= found - 1 {
+			from := bits.TrailingZeros64(found)
+			if i == 5 || !p.exposesKing(from, sq, side, king, occupied) {
+				return from, first + i
+			}
+		}
+	}
+	return -1, data.Empty
+}
+
+// exposesKing checks if moving side's piece on from to the enemy piece on to
+// leaves its king attacked by an enemy slider in occupied
+func (p *Position) exposesKing(from, to, side int, king, occupied uint64) bool {
+	if king == 0 {
+		return false
+	}
+	b := &p.Board
+	bishops, rooks := b.BlackBishop|b.BlackQueen, b.BlackRook|b.BlackQueen
+	if side == data.Black {
+		bishops, rooks = b.WhiteBishop|b.WhiteQueen, b.WhiteRook|b.WhiteQueen
+	}
+	enemies := occupied &^ (uint64(1) << to)
+	after := occupied&^(uint64(1)<<from) | uint64(1)<<to
+	kingSq := bits.TrailingZeros64(king)
+	return data.GetBishopAttacks(after, kingSq)&bishops&enemies != 0 ||
+		data.GetRookAttacks(after, kingSq)&rooks&enemies != 0
+}
@@ -0,0 +1,80 @@
+package engine
+
+import (
+	"testing"
+
+	"github.com/AdamGriffiths31/ChessEngine/data"
+)
+
+func TestSEE(t *testing.T) {
+	tests := []struct {
+		fen  string
+		move string
+		want int
+	}{
+		{"1k1r4/1pp4p/p7/4p3/8/P5P1/1PP4P/2K1R3 w - - 0 1", "e1e5", 100},
+		{"1k1r3q/1ppn3p/p4b2/4p3/8/P2N2P1/1PP1R1BP/2K1Q3 w - - 0 1", "d3e5", 100 - 325},
+		{"4k3/8/8/3p4/4P3/8/8/4K3 w - - 0 1", "e4d5", 100},
+		{"4k3/8/2p5/3p4/8/8/8/3QK3 w - - 0 1", "d1d5", 100 - 1000},
+		{"4k3/8/8/8/8/8/3r4/3QK3 w - - 0 1", "d1d2", 550},
+		{"4k3/8/8/8/8/8/3r4/4K3 w - - 0 1", "e1d2", 550},
+		{"4k3/8/8/8/8/8/3r4/3RK3 b - - 0 1", "d2d1", 550 - 550},
+		{"4k3/8/8/3pP3/8/8/8/4K3 w - d6 0 1", "e5d6", 100},
+		{"4k3/8/4p3/8/8/8/8/3NK3 w - - 0 1", "d1c3", 0},
+		{"4k3/8/3p4/8/8/8/8/2N1K3 w - - 0 1", "c1b3", 0},
+		{"4k3/8/3p4/8/8/8/3N4/4K3 w - - 0 1", "d2c4", 0},
+		{"4k3/8/3p4/8/8/1N6/8/4K3 w - - 0 1", "b3c5", -325},
+		{"3rk3/3r4/8/3p4/8/8/3R4/3R1K2 w - - 0 1", "d2d5", 100 - 550},
+		{"4k3/3r4/8/3p4/8/8/3R4/3R1K2 w - - 0 1", "d2d5", 100},
+		{"4k3/4n3/8/3p4/8/2N5/8/4RK2 w - - 0 1", "c3d5", 100},
+		{"7k/8/5b2/4p3/8/8/1B2Q3/6K1 w - - 0 1", "e2e5", 100 - 675},
+	}
+	for _, test := range tests {
+		game := ParseFen(test.fen)
+		p := game.Position()
+		move := p.ParseMove([]byte(test.move))
+		if move == data.NoMove {
+			t.Fatalf("%v: %v is not a legal move", test.fen, test.move)
+		}
+		if got := p.SEE(move); got != test.want {
+			t.Errorf("%v %v: expected %v but got %v", test.fen, test.move, test.want, got)
+		}
+	}
+}
+
+func TestSEEWithValues(t *testing.T) {
+	game := ParseFen("4k3/8/2p5/3b4/8/4N3/8/4K3 w - - 0 1")
+	p := game.Position()
+	move := p.ParseMove([]byte("e3d5"))
+	if got := p.SEE(move); got != 0 {
+		t.Errorf("Expected knight for bishop to be level but got %v", got)
+	}
+	values := data.PieceVal
+	values[data.BB] = 400
+	if got := p.SEEWithValues(move, values); got != 400-325 {
+		t.Errorf("Expected a dearer bishop to win %v but got %v", 400-325, got)
+	}
+}
+
+func TestSEEOnSquare(t *testing.T) {
+	tests := []struct {
+		fen  string
+		sq   int
+		side int
+		want int
+	}{
+		{"4k3/8/2p5/3p4/8/4N3/8/4K3 w - - 0 1", data.D5, data.White, 100 - 325},
+		{"4k3/8/8/3p4/8/4N3/8/4K3 w - - 0 1", data.D5, data.White, 100},
+		{"4k3/8/8/3p4/8/8/8/4K3 w - - 0 1", data.D5, data.White, 0},
+		{"4k3/8/2p5/8/8/4N3/8/4K3 w - - 0 1", data.D5, data.White, -325},
+		{"4k3/8/2p5/8/8/4N3/8/4K3 w - - 0 1", data.D5, data.Black, -100},
+		{"4k3/8/8/3p4/4K3/8/8/8 w - - 0 1", data.D5, data.White, 100},
+		{"4k3/8/2p5/3p4/4K3/8/8/8 w - - 0 1", data.D5, data.White, 0},
+	}
+	for _, test := range tests {
+		game := ParseFen(test.fen)
+		if got := game.Position().SEEOnSquare(test.sq, test.side); got != test.want {
+			t.Errorf("%v: expected %v but got %v", test.fen, test.want, got)
+		}
+	}
+}
@@ -1,7 +1,13 @@
 package engine
 
 import (
+	"bufio"
+	"encoding/binary"
+	"errors"
 	"fmt"
+	"io"
+	"os"
+	"sync/atomic"
 	"unsafe"
 
 	"github.com/AdamGriffiths31/ChessEngine/data"
@@ -9,19 +15,76 @@ import (
 
 var TranspositionTable = NewCache()
 
+// DefaultCacheSize is the size of a new cache in MB
+const DefaultCacheSize = 64
+
+// MaxBuckets is the most entries a cluster can hold, entries are 16 bytes so
+// four of them fill a 64 byte cache line
+const MaxBuckets = 4
+
+// ageShift and ageMask place the search an entry was stored in above the
+// move in its data, ages are compared modulo ageMask+1 searches
+const (
+	ageShift = 50
+	ageMask  = 0x3FFF
+)
+
+const cacheFileMagic = 0x54544543 // "CETT"
+const cacheFileVersion = 3
+const cacheFileEntrySize = 16
+
+var errCacheFile = errors.New("not a transposition table file")
+
+// cacheFileHeader is written before the entries when the cache is saved
+type cacheFileHeader struct {
+	Magic      uint32
+	Version    uint32
+	Entries    uint64
+	Buckets    uint64
+	CurrentAge int64
+}
+
+// CacheEntry is a position's search result packed into SMPData with the
+// age of the search, SMPKey is the position key xor SMPData so an entry torn
+// by another thread does not match
 type CacheEntry struct {
-	Age     int
 	SMPData uint64
 	SMPKey  uint64
 }
 
+// ReplacementPolicy decides when Store overwrites an occupied entry
+type ReplacementPolicy int
+
+const (
+	// ReplaceHybrid replaces entries from older searches, shallower entries
+	// and the same position unless it was searched much deeper
+	ReplaceHybrid ReplacementPolicy = iota
+	// ReplaceDepthPreferred keeps the deeper of the two entries
+	ReplaceDepthPreferred
+	// ReplaceAlways always stores the new entry
+	ReplaceAlways
+	numReplacementPolicies
+)
+
 type Cache struct {
 	CacheTable    []CacheEntry
 	NumberEntries int
-	Hit           int
-	Cut           int
-	CurrentAge    int
-	Stored        int
+	// Probed counts the calls to Get and Hit those that found an entry
+	// searched deep enough to use its score
+	Probed     int
+	Hit        int
+	Cut        int
+	CurrentAge int
+	Stored     int
+	Policy     ReplacementPolicy
+	// Buckets is the number of entries in each cluster, a position can be
+	// stored in any entry of its cluster. Zero is treated as one
+	Buckets int
+	// Overwrites counts the occupied entries replaced under each policy
+	Overwrites [numReplacementPolicies]int
+	// Protected counts the bounds not stored so an exact entry from the
+	// current search was kept
+	Protected int
 }
 
 func (c *Cache) BestMove(key uint64, play int) int {
@@ -30,58 +93,143 @@ func (c *Cache) BestMove(key uint64, play int) int {
 
 // Probe for the given Position key return the move stored in the TT
 func (c *Cache) Probe(key uint64) int {
-	index := key % uint64(c.NumberEntries)
-	testKey := key ^ c.CacheTable[index].SMPData
-	if testKey == c.CacheTable[index].SMPKey {
+	if index, ok := c.find(key); ok {
 		return extractMove(c.CacheTable[index].SMPData)
 	}
 	return data.NoMove
 }
 
-// Store Attempts to store the vale in the TT if a value is not already present or
-// the depth of the move is greater than the original
-func (c *Cache) Store(key uint64, play int, move, score, flag, depth int) {
-	index := key % uint64(c.NumberEntries)
-	replace := false
+// bucketCount returns the number of entries in each cluster
+func (c *Cache) bucketCount() uint64 {
+	if c.Buckets < 1 {
+		return 1
+	}
+	return uint64(c.Buckets)
+}
 
-	oldValue := c.CacheTable[index]
-	oldKey := oldValue.SMPKey
-	oldData := oldValue.SMPData
-	oldPosKey := oldKey ^ oldData
+// cluster returns the index of the first entry of the cluster for key
+func (c *Cache) cluster(key uint64) uint64 {
+	buckets := c.bucketCount()
+	return key % (uint64(c.NumberEntries) / buckets) * buckets
+}
 
-	if oldData == 0 {
-		replace = true
-	} else if oldPosKey == key {
-		replace = (flag == data.PVExact) || (uint64(depth) >= extractDepth(c.CacheTable[index].SMPData)-3)
-	} else {
-		if c.CacheTable[index].Age < c.CurrentAge {
-			replace = true
-		} else if extractDepth(c.CacheTable[index].SMPData) <= uint64(depth) {
-			replace = true
+// find returns the index of the entry holding key if there is one
+func (c *Cache) find(key uint64) (uint64, bool) {
+	start := c.cluster(key)
+	for index := start; index < start+c.bucketCount(); index++ {
+		if entry := &c.CacheTable[index]; key^entry.SMPData == entry.SMPKey {
+			return index, true
 		}
 	}
+	return 0, false
+}
 
-	if replace {
+// slot returns the index key is stored at, the entry already holding key,
+// an empty entry or else the oldest and then shallowest entry in the cluster
+func (c *Cache) slot(key uint64) uint64 {
+	if index, ok := c.find(key); ok {
+		return index
+	}
+	start := c.cluster(key)
+	victim := start
+	for index := start; index < start+c.bucketCount(); index++ {
+		entry, old := c.CacheTable[index], c.CacheTable[victim]
+		if entry.SMPData == 0 {
+			return index
+		}
+		if c.age(entry) > c.age(old) || (c.age(entry) == c.age(old) && extractDepth(entry.SMPData) < extractDepth(old.SMPData)) {
+			victim = index
+		}
+	}
+	return victim
+}
+
+// Prefetch loads the entry for key so it is likely to be cached by the time it
+// is probed, Go has no prefetch instruction so the entry is read atomically to
+// stop the load being removed
+func (c *Cache) Prefetch(key uint64) {
+	atomic.LoadUint64(&c.CacheTable[c.cluster(key)].SMPKey)
+}
+
+// Store Attempts to store the vale in the TT if a value is not already present or
+// the depth of the move is greater than the original
+func (c *Cache) Store(key uint64, play int, move, score, flag, depth int) {
+	index := c.slot(key)
+
+	if c.shouldReplace(index, key, flag, depth) {
+		if c.CacheTable[index].SMPData != 0 {
+			c.Overwrites[c.Policy]++
+		}
 		c.Stored++
 		if score > data.Mate {
 			score += play
 		} else if score < -data.Mate {
 			score -= play
 		}
-		smpData := foldData(uint64(score), uint64(depth), uint64(flag), move)
+		smpData := foldData(uint64(score), uint64(depth), uint64(flag), move, uint64(c.CurrentAge))
 		smpKey := key ^ smpData
-		c.CacheTable[index].Age = c.CurrentAge
 		c.CacheTable[index].SMPData = smpData
 		c.CacheTable[index].SMPKey = smpKey
 	}
 }
 
+// shouldReplace checks if the entry at index should be replaced by the policy
+func (c *Cache) shouldReplace(index, key uint64, flag, depth int) bool {
+	oldValue := c.CacheTable[index]
+	oldData := oldValue.SMPData
+	oldPosKey := oldValue.SMPKey ^ oldData
+	oldDepth := extractDepth(oldData)
+
+	if oldData == 0 {
+		return true
+	}
+
+	if c.Policy == ReplaceAlways {
+		return true
+	}
+
+	// a bound no deeper than an exact entry from this search would lose
+	// part of the principal variation
+	if c.age(oldValue) == 0 && extractFlag(oldData) == data.PVExact &&
+		flag != data.PVExact && uint64(depth) <= oldDepth {
+		c.Protected++
+		return false
+	}
+
+	switch c.Policy {
+	case ReplaceDepthPreferred:
+		return uint64(depth) >= oldDepth
+	}
+
+	if oldPosKey == key {
+		return (flag == data.PVExact) || (uint64(depth) >= oldDepth-3)
+	}
+	return c.age(oldValue) > 0 || oldDepth <= uint64(depth)
+}
+
+// ResetStats zeroes the probe, hit, cut, store and overwrite counts, the entries
+// are kept
+func (c *Cache) ResetStats() {
+	c.Probed = 0
+	c.Hit = 0
+	c.Cut = 0
+	c.Stored = 0
+	c.Overwrites = [numReplacementPolicies]int{}
+	c.Protected = 0
+}
+
+// ClearEntries empties every entry, the stats and age are kept
+func (c *Cache) ClearEntries() {
+	for i := range c.CacheTable {
+		c.CacheTable[i] = CacheEntry{}
+	}
+}
+
 // Get searches the TT for the given Position key for a move
 func (c *Cache) Get(key uint64, play int, move *int, score *int, alpha, beta, depth int) bool {
-	index := key % uint64(c.NumberEntries)
-	entry := c.CacheTable[index]
-	testKey := key ^ entry.SMPData
-	if testKey == c.CacheTable[index].SMPKey {
+	c.Probed++
+	if index, ok := c.find(key); ok {
+		entry := c.CacheTable[index]
 		*move = extractMove(entry.SMPData)
 		if int(extractDepth(entry.SMPData)) >= depth {
 			c.Hit++
@@ -112,8 +260,13 @@ func (c *Cache) Get(key uint64, play int, move *int, score *int, alpha, beta, de
 	return false
 }
 
+// age returns how many searches ago the entry was stored
+func (c *Cache) age(entry CacheEntry) int {
+	return (c.CurrentAge - int(entry.SMPData>>ageShift)) & ageMask
+}
+
 func extractMove(value uint64) int {
-	return int(value >> 25)
+	return int((value >> 25) & 0x1FFFFFF)
 }
 
 func extractScore(value uint64) uint64 {
@@ -129,14 +282,127 @@ func extractFlag(value uint64) uint64 {
 }
 
 // foldData hashes the data into a unique key
-func foldData(score, depth, flag uint64, move int) uint64 {
-	return (score + data.Infinite) | (depth << 16) | (flag << 23) | (uint64(move) << 25)
+func foldData(score, depth, flag uint64, move int, age uint64) uint64 {
+	return (score + data.Infinite) | (depth << 16) | (flag << 23) | (uint64(move) << 25) | ((age & ageMask) << ageShift)
+}
+
+// SaveToFile writes the cache entries and age to path
+func (c *Cache) SaveToFile(path string) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	w := bufio.NewWriter(f)
+	header := cacheFileHeader{cacheFileMagic, cacheFileVersion, uint64(c.NumberEntries), c.bucketCount(), int64(c.CurrentAge)}
+	if err := binary.Write(w, binary.LittleEndian, header); err != nil {
+		return err
+	}
+	var buf [cacheFileEntrySize]byte
+	for i := 0; i < c.NumberEntries; i++ {
+		entry := c.CacheTable[i]
+		binary.LittleEndian.PutUint64(buf[0:], entry.SMPData)
+		binary.LittleEndian.PutUint64(buf[8:], entry.SMPKey)
+		if _, err := w.Write(buf[:]); err != nil {
+			return err
+		}
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	return f.Close()
+}
+
+// LoadFromFile replaces the cache entries with those saved in path, the file
+// must have been saved from a cache of the same size and buckets
+func (c *Cache) LoadFromFile(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	r := bufio.NewReader(f)
+	var header cacheFileHeader
+	if err := binary.Read(r, binary.LittleEndian, &header); err != nil {
+		return err
+	}
+	if header.Magic != cacheFileMagic {
+		return errCacheFile
+	}
+	if header.Version != cacheFileVersion {
+		return fmt.Errorf("unsupported transposition table version %v", header.Version)
+	}
+	if header.Entries != uint64(c.NumberEntries) {
+		return fmt.Errorf("transposition table has %v entries but the file has %v", c.NumberEntries, header.Entries)
+	}
+	if header.Buckets != c.bucketCount() {
+		return fmt.Errorf("transposition table has %v buckets but the file has %v", c.bucketCount(), header.Buckets)
+	}
+
+	table := make([]CacheEntry, c.NumberEntries)
+	var buf [cacheFileEntrySize]byte
+	for i := range table {
+		if _, err := io.ReadFull(r, buf[:]); err != nil {
+			return err
+		}
+		table[i] = CacheEntry{
+			SMPData: binary.LittleEndian.Uint64(buf[0:]),
+			SMPKey:  binary.LittleEndian.Uint64(buf[8:]),
+		}
+	}
+	c.CacheTable = table
+	c.CurrentAge = int(header.CurrentAge)
+	return nil
+}
+
+// Resize allocates a table of sizeMB and re-inserts the current entries,
+// where two entries share a slot the newer and then deeper entry is kept
+func (c *Cache) Resize(sizeMB int) {
+	old := c.CacheTable
+	c.NumberEntries = cacheLength(sizeMB) / int(c.bucketCount()) * int(c.bucketCount())
+	c.CacheTable = make([]CacheEntry, c.NumberEntries)
+	for _, entry := range old {
+		if entry.SMPData == 0 {
+			continue
+		}
+		index := c.slot(entry.SMPKey ^ entry.SMPData)
+		victim := c.CacheTable[index]
+		if victim.SMPData == 0 || c.age(victim) > c.age(entry) ||
+			(c.age(victim) == c.age(entry) && extractDepth(victim.SMPData) < extractDepth(entry.SMPData)) {
+			c.CacheTable[index] = entry
+		}
+	}
+}
+
+// cacheLength returns the number of entries that fit in sizeMB
+func cacheLength(sizeMB int) int {
+	size := ((0x100000 * sizeMB) / int(unsafe.Sizeof(CacheEntry{})))
+	return size - 2
 }
 
 // NewCache allocates the space for a new cache
 func NewCache() *Cache {
-	size := ((0x100000 * 64) / int(unsafe.Sizeof(CacheEntry{})))
-	length := size - 2
+	return NewCacheWithSize(DefaultCacheSize)
+}
+
+// NewCacheWithSize allocates the space for a new cache of sizeMB with one
+// entry for each position
+func NewCacheWithSize(sizeMB int) *Cache {
+	return NewCacheWithBuckets(sizeMB, 1)
+}
+
+// NewCacheWithBuckets allocates the space for a new cache of sizeMB split
+// into clusters of buckets entries, 1, 2 or MaxBuckets. An entry is 16 bytes
+// and a cluster's entries are next to each other starting at a multiple of
+// the cluster size, so a cluster of four is one 64 byte cache line and
+// smaller clusters never straddle two
+func NewCacheWithBuckets(sizeMB, buckets int) *Cache {
+	if buckets < 1 || buckets > MaxBuckets || buckets&(buckets-1) != 0 {
+		panic(fmt.Errorf("NewCacheWithBuckets: %v buckets is not 1, 2 or %v", buckets, MaxBuckets))
+	}
+	length := cacheLength(sizeMB) / buckets * buckets
 
-	return &Cache{make([]CacheEntry, length), length, 0, 0, 0, 0}
+	return &Cache{CacheTable: make([]CacheEntry, length), NumberEntries: length, Buckets: buckets}
 }
@@ -1,7 +1,9 @@
 package engine
 
 import (
+	"path/filepath"
 	"testing"
+	"unsafe"
 
 	"github.com/AdamGriffiths31/ChessEngine/data"
 )
@@ -46,3 +48,275 @@ func TestProbeTTWrongKey(t *testing.T) {
 		t.Errorf("Expected %v but got %v", data.NoMove, tt.Probe(game2.position.PositionKey))
 	}
 }
+
+func TestSaveAndLoadTT(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "tt.bin")
+	tt := NewCache()
+	tt.CurrentAge = 4
+	game := ParseFen("4k3/8/8/8/8/8/5PPP/4K2R w K - 0 1")
+	game2 := ParseFen("r3k3/8/8/8/8/8/8/4K3 b q - 0 1")
+	move := game.Position().ParseMove([]byte("e1g1"))
+	move2 := game2.Position().ParseMove([]byte("e8c8"))
+	tt.Store(game.position.PositionKey, game.position.Play, move, 35, data.PVExact, 6)
+	tt.Store(game2.position.PositionKey, game2.position.Play, move2, -20, data.PVExact, 3)
+	if err := tt.SaveToFile(path); err != nil {
+		t.Fatal(err)
+	}
+
+	loaded := NewCache()
+	if err := loaded.LoadFromFile(path); err != nil {
+		t.Fatal(err)
+	}
+	if loaded.CurrentAge != 4 {
+		t.Errorf("Expected age %v but got %v", 4, loaded.CurrentAge)
+	}
+	var found, score int
+	if !loaded.Get(game.position.PositionKey, game.position.Play, &found, &score, -data.ABInfinite, data.ABInfinite, 6) || found != move || score != 35 {
+		t.Errorf("Expected %v (%v) but got %v (%v)", move, 35, found, score)
+	}
+	if loaded.Probe(game2.position.PositionKey) != move2 {
+		t.Errorf("Expected %v but got %v", move2, loaded.Probe(game2.position.PositionKey))
+	}
+}
+
+func TestLoadTTSizeMismatch(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "tt.bin")
+	tt := NewCache()
+	if err := tt.SaveToFile(path); err != nil {
+		t.Fatal(err)
+	}
+
+	small := &Cache{CacheTable: make([]CacheEntry, 1024), NumberEntries: 1024}
+	if err := small.LoadFromFile(path); err == nil {
+		t.Errorf("Expected an error loading a table of a different size")
+	}
+}
+
+func TestSaveAndLoadTTBuckets(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "tt.bin")
+	game := ParseFen("4k3/8/8/8/8/8/5PPP/4K2R w K - 0 1")
+	move := game.Position().ParseMove([]byte("e1g1"))
+	tt := &Cache{CacheTable: make([]CacheEntry, 1024), NumberEntries: 1024, Buckets: 2}
+	tt.Store(game.position.PositionKey, game.position.Play, move, 35, data.PVExact, 6)
+	if err := tt.SaveToFile(path); err != nil {
+		t.Fatal(err)
+	}
+
+	loaded := &Cache{CacheTable: make([]CacheEntry, 1024), NumberEntries: 1024, Buckets: 2}
+	if err := loaded.LoadFromFile(path); err != nil {
+		t.Fatal(err)
+	}
+	if loaded.Probe(game.position.PositionKey) != move {
+		t.Errorf("Expected %v but got %v", move, loaded.Probe(game.position.PositionKey))
+	}
+	for _, buckets := range []int{1, 4} {
+		other := &Cache{CacheTable: make([]CacheEntry, 1024), NumberEntries: 1024, Buckets: buckets}
+		if err := other.LoadFromFile(path); err == nil {
+			t.Errorf("Expected an error loading 2 buckets into %v", buckets)
+		}
+	}
+}
+
+func TestResizeTTKeepsEntries(t *testing.T) {
+	tt := NewCacheWithSize(4)
+	game := ParseFen("4k3/8/8/8/8/8/5PPP/4K2R w K - 0 1")
+	move := game.Position().ParseMove([]byte("e1g1"))
+	key := game.position.PositionKey
+	tt.Store(key, game.position.Play, move, 120, data.PVExact, 20)
+	for i := uint64(1); i < 5000; i++ {
+		tt.Store(key*i+i, 0, data.NoMove, 0, data.PVAlpha, 1)
+	}
+
+	tt.Resize(8)
+	if tt.NumberEntries != cacheLength(8) {
+		t.Errorf("Expected %v entries but got %v", cacheLength(8), tt.NumberEntries)
+	}
+	if tt.Probe(key) != move {
+		t.Errorf("Expected %v after growing but got %v", move, tt.Probe(key))
+	}
+
+	tt.Resize(1)
+	var found, score int
+	if !tt.Get(key, game.position.Play, &found, &score, -data.ABInfinite, data.ABInfinite, 20) || found != move || score != 120 {
+		t.Errorf("Expected %v (%v) after shrinking but got %v (%v)", move, 120, found, score)
+	}
+}
+
+func TestReplacementPolicy(t *testing.T) {
+	tests := []struct {
+		policy  ReplacementPolicy
+		evicted bool
+	}{
+		{ReplaceDepthPreferred, false},
+		{ReplaceAlways, true},
+	}
+	for _, test := range tests {
+		tt := NewCacheWithSize(1)
+		tt.Policy = test.policy
+		deep := uint64(12345)
+		shallow := deep + uint64(tt.NumberEntries)
+		tt.Store(deep, 0, 7, 10, data.PVExact, 10)
+		tt.Store(shallow, 0, 9, 10, data.PVExact, 2)
+
+		if evicted := tt.Probe(deep) == data.NoMove; evicted != test.evicted {
+			t.Errorf("Policy %v: expected evicted %v but got %v", test.policy, test.evicted, evicted)
+		}
+		overwrites := 0
+		if test.evicted {
+			overwrites = 1
+		}
+		if tt.Overwrites[test.policy] != overwrites {
+			t.Errorf("Policy %v: expected %v overwrites but got %v", test.policy, overwrites, tt.Overwrites[test.policy])
+		}
+	}
+}
+
+func TestExactEntryProtected(t *testing.T) {
+	tests := []struct {
+		age   int
+		flag  int
+		depth int
+		kept  bool
+	}{
+		{0, data.PVBeta, 8, true},
+		{0, data.PVAlpha, 10, true},
+		{0, data.PVBeta, 11, false},
+		{0, data.PVExact, 8, false},
+		{1, data.PVBeta, 8, false},
+	}
+	for _, test := range tests {
+		tt := NewCacheWithSize(1)
+		key := uint64(12345)
+		tt.Store(key, 0, 7, 10, data.PVExact, 10)
+		tt.CurrentAge += test.age
+		tt.Store(key, 0, 9, 20, test.flag, test.depth)
+
+		if kept := tt.Probe(key) == 7; kept != test.kept {
+			t.Errorf("%+v: expected the exact entry kept %v but got %v", test, test.kept, kept)
+		}
+		protected := 0
+		if test.kept {
+			protected = 1
+		}
+		if tt.Protected != protected {
+			t.Errorf("%+v: expected %v protected but got %v", test, protected, tt.Protected)
+		}
+	}
+}
+
+func TestClusterHoldsEveryBucket(t *testing.T) {
+	tt := NewCacheWithBuckets(1, 4)
+	clusters := uint64(tt.NumberEntries / 4)
+	for i := uint64(0); i < 4; i++ {
+		tt.Store(100+i*clusters, 0, int(10+i), 0, data.PVExact, 5)
+	}
+	for i := uint64(0); i < 4; i++ {
+		if move := tt.Probe(100 + i*clusters); move != int(10+i) {
+			t.Errorf("Expected bucket %v to hold %v but got %v", i, 10+i, move)
+		}
+	}
+	if tt.Overwrites[tt.Policy] != 0 {
+		t.Errorf("Expected no overwrites but got %v", tt.Overwrites[tt.Policy])
+	}
+}
+
+func TestClusterReplacesShallowestOldEntry(t *testing.T) {
+	tt := NewCacheWithBuckets(1, 4)
+	clusters := uint64(tt.NumberEntries / 4)
+	key := func(i int) uint64 { return 100 + uint64(i)*clusters }
+
+	tt.Store(key(0), 0, 10, 0, data.PVExact, 9)
+	tt.Store(key(1), 0, 11, 0, data.PVExact, 3)
+	tt.Store(key(2), 0, 12, 0, data.PVExact, 6)
+	tt.CurrentAge++
+	tt.Store(key(3), 0, 13, 0, data.PVExact, 1)
+	tt.Store(key(4), 0, 14, 0, data.PVExact, 2)
+
+	for i, want := range []int{10, data.NoMove, 12, 13, 14} {
+		if move := tt.Probe(key(i)); move != want {
+			t.Errorf("Expected key %v to hold %v but got %v", i, want, move)
+		}
+	}
+}
+
+func TestClusterEvictsOldestGeneration(t *testing.T) {
+	tt := NewCacheWithBuckets(1, 4)
+	clusters := uint64(tt.NumberEntries / 4)
+	key := func(i int) uint64 { return 100 + uint64(i)*clusters }
+
+	// one entry from each of four searches, the oldest is the deepest
+	for i := 0; i < 4; i++ {
+		tt.Store(key(i), 0, 10+i, 0, data.PVExact, 12-i)
+		tt.CurrentAge++
+	}
+	tt.Store(key(4), 0, 14, 0, data.PVBeta, 1)
+	tt.Store(key(5), 0, 15, 0, data.PVBeta, 1)
+
+	for i, want := range []int{data.NoMove, data.NoMove, 12, 13, 14, 15} {
+		if move := tt.Probe(key(i)); move != want {
+			t.Errorf("Expected key %v to hold %v but got %v", i, want, move)
+		}
+	}
+}
+
+func TestClusterFitsCacheLine(t *testing.T) {
+	if size := unsafe.Sizeof(CacheEntry{}) * MaxBuckets; size != 64 {
+		t.Errorf("Expected a full cluster to be 64 bytes but got %v", size)
+	}
+	defer func() {
+		if recover() == nil {
+			t.Errorf("Expected a cluster of 3 buckets to be rejected")
+		}
+	}()
+	NewCacheWithBuckets(1, 3)
+}
+
+func TestClusterEvictsOldestAcrossAgeWrap(t *testing.T) {
+	tt := NewCacheWithBuckets(1, 4)
+	clusters := uint64(tt.NumberEntries / 4)
+	key := func(i int) uint64 { return 100 + uint64(i)*clusters }
+
+	// the deepest entry is stored just before the age bits wrap around
+	tt.CurrentAge = ageMask
+	tt.Store(key(0), 0, 10, 0, data.PVExact, 12)
+	tt.CurrentAge++
+	for i := 1; i < 5; i++ {
+		tt.Store(key(i), 0, 10+i, 0, data.PVExact, 1)
+	}
+
+	for i, want := range []int{data.NoMove, 11, 12, 13, 14} {
+		if move := tt.Probe(key(i)); move != want {
+			t.Errorf("Expected key %v to hold %v but got %v", i, want, move)
+		}
+	}
+}
+
+func TestResetStatsKeepsEntries(t *testing.T) {
+	tt := NewCacheWithSize(1)
+	tt.Store(12345, 0, 7, 0, data.PVExact, 4)
+	tt.Store(12345+uint64(tt.NumberEntries), 0, 8, 0, data.PVExact, 5)
+	tt.Probed, tt.Hit, tt.Cut, tt.Protected = 4, 3, 2, 1
+
+	tt.ResetStats()
+	if tt.Probed != 0 || tt.Hit != 0 || tt.Cut != 0 || tt.Stored != 0 || tt.Protected != 0 || tt.Overwrites != [numReplacementPolicies]int{} {
+		t.Errorf("Expected the stats to be zero but got hit %v cut %v stored %v protected %v overwrites %v", tt.Hit, tt.Cut, tt.Stored, tt.Protected, tt.Overwrites)
+	}
+	if move := tt.Probe(12345 + uint64(tt.NumberEntries)); move != 8 {
+		t.Errorf("Expected the entry to be kept but got %v", move)
+	}
+}
+
+func TestClearEntriesKeepsStats(t *testing.T) {
+	tt := NewCacheWithSize(1)
+	tt.CurrentAge = 3
+	tt.Store(12345, 0, 7, 0, data.PVExact, 4)
+	tt.Hit = 5
+
+	tt.ClearEntries()
+	if move := tt.Probe(12345); move != data.NoMove {
+		t.Errorf("Expected the entry to be cleared but got %v", move)
+	}
+	if tt.Hit != 5 || tt.Stored != 1 || tt.CurrentAge != 3 {
+		t.Errorf("Expected the stats and age to be kept but got hit %v stored %v age %v", tt.Hit, tt.Stored, tt.CurrentAge)
+	}
+}
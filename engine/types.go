@@ -1,5 +1,7 @@
 package engine
 
+import "github.com/AdamGriffiths31/ChessEngine/data"
+
 type Position struct {
 	Board            Bitboard
 	Play             int
@@ -12,7 +14,16 @@ type Position struct {
 	MoveHistory      MoveHistory
 	FiftyMove        int
 	PositionHistory  PositionHistory
-	Positions        map[uint64]int
+	Positions        RepetitionTable
+	PieceScores      *[13][64]int32
+	PieceScore       int32
+	// GamePly is the number of plies since the start of the game, including
+	// those before the fen, it is not reset between moves like Play
+	GamePly int
+	// VerifyHash makes CheckBitboard compare the board and position key
+	// against ones built from scratch after every move, panicking on a
+	// mismatch
+	VerifyHash bool
 }
 
 type Bitboard struct {
@@ -35,11 +46,51 @@ type Bitboard struct {
 	WhiteKing   uint64
 }
 
+// MaxKillers is the most killer moves that can be stored per ply
+const MaxKillers = 3
+
+// DefaultKillers is the number of killer moves stored per ply when
+// KillerSlots is not set
+const DefaultKillers = 2
+
+// DefaultContinuationWeight is the percentage of the continuation score added
+// to a quiet move's history score when ContinuationWeight is not set
+const DefaultContinuationWeight = 100
+
+// DefaultCountermoveBonus is the ordering score given to a countermove when
+// CountermoveBonus is not set, between the killers and history
+const DefaultCountermoveBonus = 600000
+
+// DefaultHistoryDecay is the factor history scores are divided by between
+// searches when HistoryDecay is not set
+const DefaultHistoryDecay = 2
+
+// DefaultHistoryMax is the largest magnitude of a history score when
+// HistoryMax is not set
+const DefaultHistoryMax = 16384
+
 type MoveHistory struct {
-	Killers [2][64]int
-	History [13][120]int
+	Killers      [MaxKillers][data.MaxDepth]int
+	KillerSlots  int
+	History      [13][120]int
+	HistoryDecay int
+	HistoryMax   int
+	// Moves holds the move made to reach each ply of the search
+	Moves [data.MaxDepth]int
+	// CounterMoves holds the quiet reply that last caused a cutoff indexed by
+	// the piece and to square of the previous move
+	CounterMoves     [13][120]int
+	CountermoveBonus int
+	// Continuation scores quiet moves by the move made before them, it is
+	// owned by the search and may be nil
+	Continuation       *ContinuationHistory
+	ContinuationWeight int
 }
 
+// ContinuationHistory is indexed by the piece and to square of the previous
+// move and then the piece and to square of the reply, squares are 64 based
+type ContinuationHistory [13][64][13][64]int32
+
 type PositionHistory struct {
 	History []uint64
 	Count   int
@@ -0,0 +1,187 @@
+package epd
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/AdamGriffiths31/ChessEngine/engine"
+)
+
+// EPDPosition is a position read from an EPD line
+type EPDPosition struct {
+	FEN      string
+	BestMove []string
+	ID       string
+	// MoveScores holds the points for each move given in a c0 opcode of the
+	// form "Qd3=10, Qe4=5"
+	MoveScores map[string]int
+	// Opcodes holds the value of every opcode with quotes removed, operands
+	// of multi value opcodes are separated by a space
+	Opcodes map[string]string
+}
+
+// EPDResult is a searched position to be written as an EPD line
+type EPDResult struct {
+	Position *engine.Position
+	Move     int
+	Score    int
+	Depth    int
+}
+
+// WriteEPD writes a line for each result with the engine move in SAN, score
+// and depth in the bm, ce and acd opcodes. The bm opcode is left out when
+// there is no move, as in a mated or stalemated position
+func WriteEPD(w io.Writer, positions []EPDResult) error {
+	bw := bufio.NewWriter(w)
+	for _, result := range positions {
+		fields := strings.Fields(result.Position.ToFEN())
+		bm := ""
+		if san := result.Position.SAN(result.Move); san != "" {
+			bm = "bm " + san + "; "
+		}
+		_, err := fmt.Fprintf(bw, "%s %sce %d; acd %d;\n", strings.Join(fields[:4], " "),
+			bm, result.Score, result.Depth)
+		if err != nil {
+			return err
+		}
+	}
+	return bw.Flush()
+}
+
+// ReadEPD parses each non empty line of r
+func ReadEPD(r io.Reader) ([]EPDPosition, error) {
+	var positions []EPDPosition
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		position, err := ParseEPD(line)
+		if err != nil {
+			return nil, err
+		}
+		positions = append(positions, position)
+	}
+	return positions, scanner.Err()
+}
+
+// ParseEPD parses a single EPD line, the half and full move clocks are
+// optional and default to 0 1
+func ParseEPD(line string) (EPDPosition, error) {
+	fields := strings.Fields(line)
+	if len(fields) < 4 {
+		return EPDPosition{}, fmt.Errorf("epd %q: expected at least 4 fields", line)
+	}
+
+	clocks := "0 1"
+	skip := 4
+	if len(fields) >= 6 && isNumber(fields[4]) && isNumber(fields[5]) {
+		clocks = fields[4] + " " + fields[5]
+		skip = 6
+	}
+	position := EPDPosition{FEN: strings.Join(fields[:4], " ") + " " + clocks, Opcodes: map[string]string{}}
+
+	ops, err := splitOpcodes(skipFields(line, skip))
+	if err != nil {
+		return EPDPosition{}, fmt.Errorf("epd %q: %v", line, err)
+	}
+	for _, op := range ops {
+		name, value, _ := strings.Cut(op, " ")
+		value = unquote(strings.TrimSpace(value))
+		position.Opcodes[name] = value
+		switch name {
+		case "bm":
+			position.BestMove = strings.Fields(value)
+		case "id":
+			position.ID = value
+		case "c0":
+			position.MoveScores = parseMoveScores(value)
+		}
+	}
+	return position, nil
+}
+
+// splitOpcodes splits the operations on semicolons outside of quotes
+func splitOpcodes(s string) ([]string, error) {
+	var ops []string
+	start := 0
+	quoted := false
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '"':
+			quoted = !quoted
+		case ';':
+			if !quoted {
+				ops = appendOpcode(ops, s[start:i])
+				start = i + 1
+			}
+		}
+	}
+	if quoted {
+		return nil, fmt.Errorf("unterminated quote")
+	}
+	return appendOpcode(ops, s[start:]), nil
+}
+
+// appendOpcode appends op with repeated spaces removed if it is not empty
+func appendOpcode(ops []string, op string) []string {
+	op = strings.TrimSpace(op)
+	if op == "" {
+		return ops
+	}
+	name, value, _ := strings.Cut(op, " ")
+	value = strings.TrimSpace(value)
+	if !strings.HasPrefix(value, "\"") {
+		value = strings.Join(strings.Fields(value), " ")
+	}
+	return append(ops, strings.TrimSpace(name+" "+value))
+}
+
+// unquote removes the quotes around a string operand
+func unquote(s string) string {
+	if len(s) >= 2 && s[0] == '"' && s[len(s)-1] == '"' {
+		return s[1 : len(s)-1]
+	}
+	return s
+}
+
+// parseMoveScores parses "move=score" pairs separated by commas, nil is
+// returned if the value is not in that form
+func parseMoveScores(s string) map[string]int {
+	scores := map[string]int{}
+	for _, pair := range strings.Split(s, ",") {
+		move, score, ok := strings.Cut(strings.TrimSpace(pair), "=")
+		if !ok {
+			return nil
+		}
+		value, err := strconv.Atoi(score)
+		if err != nil {
+			return nil
+		}
+		scores[move] = value
+	}
+	return scores
+}
+
+// skipFields returns s after the first n space separated fields
+func skipFields(s string, n int) string {
+	for i := 0; i < n; i++ {
+		s = strings.TrimLeft(s, " \t")
+		if end := strings.IndexAny(s, " \t"); end >= 0 {
+			s = s[end:]
+		} else {
+			return ""
+		}
+	}
+	return s
+}
+
+// isNumber checks if s is a non negative integer
+func isNumber(s string) bool {
+	_, err := strconv.ParseUint(s, 10, 32)
+	return err == nil
+}
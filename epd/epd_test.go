@@ -0,0 +1,145 @@
+package epd
+
+import (
+	"bytes"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/AdamGriffiths31/ChessEngine/data"
+	"github.com/AdamGriffiths31/ChessEngine/engine"
+)
+
+func TestWriteEPDRoundTrip(t *testing.T) {
+	tests := []struct {
+		fen  string
+		move string
+		san  string
+	}{
+		{"r3k2r/8/8/8/8/8/8/R3K2R w KQkq - 0 1", "e1g1", "O-O"},
+		{"rnbqkbnr/ppp1pppp/8/8/3pP3/8/PPPP1PPP/RNBQKBNR b KQkq e3 0 3", "d4e3", "dxe3"},
+		{"8/P7/8/8/8/8/8/k6K w - - 0 1", "a7a8q", "a8=Q+"},
+	}
+	var results []EPDResult
+	for i, test := range tests {
+		game := engine.ParseFen(test.fen)
+		p := game.Position()
+		results = append(results, EPDResult{Position: p, Move: p.ParseMove([]byte(test.move)), Score: 10 * i, Depth: 8})
+	}
+
+	var buf bytes.Buffer
+	if err := WriteEPD(&buf, results); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(buf.String(), "r3k2r/8/8/8/8/8/8/R3K2R w KQkq - bm O-O; ce 0; acd 8;\n") {
+		t.Errorf("Unexpected epd %q", buf.String())
+	}
+
+	positions, err := ReadEPD(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(positions) != len(tests) {
+		t.Fatalf("Expected %v positions but got %v", len(tests), len(positions))
+	}
+	for i, test := range tests {
+		want := strings.Join(strings.Fields(test.fen)[:4], " ")
+		got := strings.Join(strings.Fields(positions[i].FEN)[:4], " ")
+		if got != want {
+			t.Errorf("Expected %v but got %v", want, got)
+		}
+		if len(positions[i].BestMove) != 1 || positions[i].BestMove[0] != test.san {
+			t.Errorf("Expected bm %v but got %v", test.san, positions[i].BestMove)
+		}
+		if ce, acd := positions[i].Opcodes["ce"], positions[i].Opcodes["acd"]; ce != strconv.Itoa(10*i) || acd != "8" {
+			t.Errorf("Expected ce %v and acd 8 but got %v and %v", 10*i, ce, acd)
+		}
+	}
+}
+
+func TestWriteEPDWithoutMove(t *testing.T) {
+	game := engine.ParseFen("7k/6Q1/6K1/8/8/8/8/8 b - - 0 1")
+	var buf bytes.Buffer
+	if err := WriteEPD(&buf, []EPDResult{{Position: game.Position(), Move: data.NoMove, Score: -30000, Depth: 1}}); err != nil {
+		t.Fatal(err)
+	}
+	if want := "7k/6Q1/6K1/8/8/8/8/8 b - - ce -30000; acd 1;\n"; buf.String() != want {
+		t.Errorf("Expected %q but got %q", want, buf.String())
+	}
+}
+
+func TestParseEPD(t *testing.T) {
+	tests := []struct {
+		line string
+		fen  string
+		bm   []string
+		id   string
+	}{
+		{"1R6/1brk2p1/4p2p/p1P1Pp2/P7/6P1/1P4P1/2R3K1 w - - 0 1 bm b8b7", "1R6/1brk2p1/4p2p/p1P1Pp2/P7/6P1/1P4P1/2R3K1 w - - 0 1", []string{"b8b7"}, ""},
+		{"8/p2p4/r7/1k6/8/pK5Q/P7/b7 w - - ; bm Qd3; id ERET 015 - Endspiel", "8/p2p4/r7/1k6/8/pK5Q/P7/b7 w - - 0 1", []string{"Qd3"}, "ERET 015 - Endspiel"},
+	}
+	for _, test := range tests {
+		position, err := ParseEPD(test.line)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if position.FEN != test.fen {
+			t.Errorf("Expected %v but got %v", test.fen, position.FEN)
+		}
+		if strings.Join(position.BestMove, " ") != strings.Join(test.bm, " ") {
+			t.Errorf("Expected bm %v but got %v", test.bm, position.BestMove)
+		}
+		if position.ID != test.id {
+			t.Errorf("Expected id %v but got %v", test.id, position.ID)
+		}
+	}
+}
+
+func TestParseEPDOpcodes(t *testing.T) {
+	line := `r1bqk2r/pp2bppp/2p5/3pP3/P2Q1P2/2N1B3/1PP3PP/R4RK1 b kq - bm f6; acd 12; acn 154003; pv f6 e5 d4; c0 "comment;  with spaces"; id "test 1";`
+	position, err := ParseEPD(line)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{
+		"bm":  "f6",
+		"acd": "12",
+		"acn": "154003",
+		"pv":  "f6 e5 d4",
+		"c0":  "comment;  with spaces",
+		"id":  "test 1",
+	}
+	for name, value := range want {
+		if position.Opcodes[name] != value {
+			t.Errorf("Expected %v to be %q but got %q", name, value, position.Opcodes[name])
+		}
+	}
+	if len(position.BestMove) != 1 || position.BestMove[0] != "f6" {
+		t.Errorf("Expected bm f6 but got %v", position.BestMove)
+	}
+	if position.ID != "test 1" {
+		t.Errorf("Expected id test 1 but got %v", position.ID)
+	}
+}
+
+func TestParseEPDMoveScores(t *testing.T) {
+	position, err := ParseEPD(`1kr5/3n4/q3p2p/p2n2p1/PppB1P2/5BP1/1P2Q2P/3R2K1 w - - bm f5; c0 "f5=10, Be5+=2, Bf2=3, Bg4=2";`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]int{"f5": 10, "Be5+": 2, "Bf2": 3, "Bg4": 2}
+	if len(position.MoveScores) != len(want) {
+		t.Fatalf("Expected %v but got %v", want, position.MoveScores)
+	}
+	for move, score := range want {
+		if position.MoveScores[move] != score {
+			t.Errorf("Expected %v to score %v but got %v", move, score, position.MoveScores[move])
+		}
+	}
+}
+
+func TestParseEPDUnterminatedQuote(t *testing.T) {
+	if _, err := ParseEPD(`8/8/8/8/8/8/8/k6K w - - c0 "open;`); err == nil {
+		t.Errorf("Expected an error for an unterminated quote")
+	}
+}
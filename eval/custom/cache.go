@@ -0,0 +1,62 @@
+package eval
+
+// evalCacheSize is the number of entries in the evaluation cache, it must be
+// a power of two
+const evalCacheSize = 1 << 16
+
+// evalCacheEntry is the score of the position with the key, from the side to
+// move's point of view
+type evalCacheEntry struct {
+	key   uint64
+	score int32
+	valid bool
+}
+
+// evalCache is a direct mapped table of evaluations, a new position always
+// replaces the one stored in its slot
+type evalCache struct {
+	entries [evalCacheSize]evalCacheEntry
+	hits    int
+	probes  int
+}
+
+// get returns the cached score for the key if there is one
+func (c *evalCache) get(key uint64) (int, bool) {
+	c.probes++
+	entry := &c.entries[key&(evalCacheSize-1)]
+	if !entry.valid || entry.key != key {
+		return 0, false
+	}
+	c.hits++
+	return int(entry.score), true
+}
+
+// put stores the score for the key
+func (c *evalCache) put(key uint64, score int) {
+	c.entries[key&(evalCacheSize-1)] = evalCacheEntry{key: key, score: int32(score), valid: true}
+}
+
+// clear empties the cache and resets the hit count
+func (c *evalCache) clear() {
+	*c = evalCache{}
+}
+
+// ClearCache empties the evaluation cache, it is cleared before each search
+func (e *EvaluationService) ClearCache() {
+	e.cache.clear()
+}
+
+// CacheStats returns how many evaluations were found in the cache out of how
+// many were looked up since it was last cleared
+func (e *EvaluationService) CacheStats() (hits, probes int) {
+	return e.cache.hits, e.cache.probes
+}
+
+// SetWeights replaces the weights, rebuilding the tables made from them and
+// clearing any evaluations cached with the old weights. Positions attached
+// before the change must be attached again
+func (e *EvaluationService) SetWeights(w Weights) {
+	e.Weights = w
+	e.initPieceSquare()
+	e.ClearCache()
+}
@@ -0,0 +1,47 @@
+package eval
+
+import (
+	"testing"
+
+	"github.com/AdamGriffiths31/ChessEngine/engine"
+)
+
+func TestEvaluateUsesCache(t *testing.T) {
+	game := engine.ParseFen("r3k2r/p1ppqpb1/bn2pnp1/3PN3/1p2P3/2N2Q1p/PPPBBPPP/R3K2R w KQkq - 0 1")
+	p := game.Position()
+	e := NewEvaluationService()
+
+	first := e.Evaluate(p)
+	if hits, probes := e.CacheStats(); hits != 0 || probes != 1 {
+		t.Fatalf("Expected a miss but got %v hits of %v", hits, probes)
+	}
+	if second := e.Evaluate(p); second != first {
+		t.Errorf("Expected the cached score %v but got %v", first, second)
+	}
+	if hits, probes := e.CacheStats(); hits != 1 || probes != 2 {
+		t.Errorf("Expected a hit but got %v hits of %v", hits, probes)
+	}
+	if first != e.evaluate(p) {
+		t.Errorf("Expected the cached score to match the evaluation %v", e.evaluate(p))
+	}
+
+	e.ClearCache()
+	if hits, probes := e.CacheStats(); hits != 0 || probes != 0 {
+		t.Errorf("Expected clearing to reset the stats but got %v hits of %v", hits, probes)
+	}
+}
+
+func TestSetWeightsClearsCache(t *testing.T) {
+	game := engine.ParseFen("4k3/pp6/8/8/8/8/PPP5/4K3 w - - 0 1")
+	p := game.Position()
+	e := NewEvaluationService()
+	before := e.Evaluate(p)
+
+	w := e.Weights
+	w.PawnValue = S(200, 200)
+	e.SetWeights(w)
+	after := e.Evaluate(p)
+	if after == before || after != e.evaluate(p) {
+		t.Errorf("Expected the new weights to give %v rather than %v but got %v", e.evaluate(p), before, after)
+	}
+}
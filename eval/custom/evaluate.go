@@ -14,68 +14,163 @@ type EvaluationService struct {
 
 	mobilityAreas [2]uint64
 	pawnAttacks   [2]uint64
+
+	pieceSquare [13][64]int32
+
+	cache *evalCache
 }
 
 func NewEvaluationService() *EvaluationService {
-	var es = &EvaluationService{}
+	var es = &EvaluationService{cache: &evalCache{}}
 	es.Weights.init()
+	es.initPieceSquare()
 	return es
 }
 
+// initPieceSquare combines the material and PSQT weights into a single table
+// from white's point of view, it must be rebuilt if those weights change
+func (e *EvaluationService) initPieceSquare() {
+	values := [7]Score{0, e.PawnValue, e.KnightValue, e.BishopValue, e.RookValue, e.QueenValue, 0}
+	for pt := data.WP; pt <= data.WK; pt++ {
+		for sq := 0; sq < 64; sq++ {
+			e.pieceSquare[pt][sq] = int32(values[pt] + e.PSQT[data.White][pt][sq])
+			e.pieceSquare[pt+data.BP-data.WP][sq] = -int32(values[pt] + e.PSQT[data.Black][pt][sq])
+		}
+	}
+}
+
+// Attach keeps the material and PSQT part of the evaluation up to date on
+// the position as moves are made
+func (e *EvaluationService) Attach(p *engine.Position) {
+	p.SetPieceScores(&e.pieceSquare)
+}
+
 const (
 	QueenSideBB = data.FileAMask | data.FileBMask | data.FileCMask | data.FileDMask
 	KingSideBB  = data.FileEMask | data.FileFMask | data.FileGMask | data.FileHMask
 )
 
+// fiftyMoveScaleStart is the half move clock after which the evaluation is
+// scaled towards a draw
+const fiftyMoveScaleStart = 20
+
+// Evaluate returns the score of the position for the side to move, looking
+// it up in the cache first. The key does not include the half move clock so
+// the score is cached before it is scaled for the fifty move rule
 func (e *EvaluationService) Evaluate(p *engine.Position) int {
+	score, ok := e.cache.get(p.PositionKey)
+	if !ok {
+		score = e.evaluate(p)
+		e.cache.put(p.PositionKey, score)
+	}
+	return scaleFiftyMove(score, p.FiftyMove)
+}
 
+// scaleFiftyMove shrinks score towards zero as the half move clock goes from
+// fiftyMoveScaleStart to 100, when the game would be drawn, so a side that
+// is ahead is pushed to make progress. Static evaluations are never mate
+// scores so there are none to keep
+func scaleFiftyMove(score, fiftyMove int) int {
+	if fiftyMove <= fiftyMoveScaleStart {
+		return score
+	}
+	if fiftyMove >= 100 {
+		return 0
+	}
+	return score * (100 - fiftyMove) / (100 - fiftyMoveScaleStart)
+}
+
+// evaluate scores the position for the side to move without the cache
+func (e *EvaluationService) evaluate(p *engine.Position) int {
 	if p.Board.WhitePawn == 0 && p.Board.BlackPawn == 0 && e.IsMaterialDraw(p) {
 		return 0
 	}
 
 	bothPawns := p.Board.WhitePawn | p.Board.BlackPawn
 
-	e.SetupEvaluate(p)
+	eval := e.whiteScore(p, bothPawns)
+	result := e.blend(eval, e.phase(), computeFactor(e, p, eval, bothPawns))
 
-	eval := e.calculateEvalPawns(p)
-	eval += e.calculateEvalKnights(p)
-	eval += e.calculateEvalBishop(p)
-	eval += e.calculateEvalRook(p, bothPawns)
-	eval += e.calculateEvalQueens(p, bothPawns)
-	eval += e.calculateEvalKings(p)
+	if p.Side == data.White {
+		return result
+	} else {
+		return -result
+	}
+}
+
+// whiteScore sets up the evaluation and returns the middle and end game
+// scores from white's point of view before they are tapered
+func (e *EvaluationService) whiteScore(p *engine.Position, bothPawns uint64) Score {
+	e.SetupEvaluate(p)
+	eval := e.evaluatePieceSquare(p)
+	return eval + e.evaluateSide(p, data.White, bothPawns) - e.evaluateSide(p, data.Black, bothPawns)
+}
 
-	eval += e.evaluateThreats(p, data.White, bothPawns) - e.evaluateThreats(p, data.Black, bothPawns)
-	eval += e.evaluateMobility(p)
+// ScaleFactor returns how much of the end game score is kept for the side
+// it favours, out of 128. Drawish endings such as a rook pawn with the wrong
+// bishop are scaled down and 128 keeps the whole score
+func (e *EvaluationService) ScaleFactor(p *engine.Position) int {
+	if p.Board.WhitePawn == 0 && p.Board.BlackPawn == 0 && e.IsMaterialDraw(p) {
+		return 0
+	}
+	bothPawns := p.Board.WhitePawn | p.Board.BlackPawn
+	return computeFactor(e, p, e.whiteScore(p, bothPawns), bothPawns)
+}
 
-	eval += e.PawnValue * Score(e.pieceCount[data.White][data.WP]-e.pieceCount[data.Black][data.WP])
-	eval += e.KnightValue * Score(e.pieceCount[data.White][data.WN]-e.pieceCount[data.Black][data.WN])
-	eval += e.BishopValue * Score(e.pieceCount[data.White][data.WB]-e.pieceCount[data.Black][data.WB])
-	eval += e.RookValue * Score(e.pieceCount[data.White][data.WR]-e.pieceCount[data.Black][data.WR])
-	eval += e.QueenValue * Score(e.pieceCount[data.White][data.WQ]-e.pieceCount[data.Black][data.WQ])
+// evaluatePieceSquare returns the material and PSQT score from white's point
+// of view, using the incrementally updated score when this evaluator is
+// attached to the position
+func (e *EvaluationService) evaluatePieceSquare(p *engine.Position) Score {
+	if p.PieceScores == &e.pieceSquare {
+		return Score(p.PieceScore)
+	}
+	return e.evaluateMaterial(data.White) + e.evaluatePSQT(p, data.White) -
+		e.evaluateMaterial(data.Black) - e.evaluatePSQT(p, data.Black)
+}
 
-	factor := computeFactor(e, p, eval, bothPawns)
+// evaluateSide sums the evaluation terms for the given colour, other than
+// material and PSQT
+func (e *EvaluationService) evaluateSide(p *engine.Position, colour int, bothPawns uint64) Score {
+	eval := e.evaluatePawnStructure(p, colour)
+	eval += e.evaluateOpenFiles(p, colour, bothPawns)
+	eval += e.evaluateSeventhRank(p, colour)
+	eval += e.evaluateImbalance(p, colour)
+	eval += e.evaluateMobility(p, colour)
+	eval += e.evaluateThreats(p, colour, bothPawns)
+	eval += e.evaluateBackRank(p, colour)
+	eval += e.evaluateMopUp(p, colour)
+	eval += e.evaluateStalemateRisk(p, colour)
+	return eval
+}
 
+// phase returns the game phase scaled from 0 (endgame) to 256 (opening)
+func (e *EvaluationService) phase() int {
 	var phase = 4*(e.pieceCount[data.White][data.WQ]+e.pieceCount[data.Black][data.WQ]) +
 		2*(e.pieceCount[data.White][data.WR]+e.pieceCount[data.Black][data.WR]) +
 		1*(e.pieceCount[data.White][data.WN]+e.pieceCount[data.Black][data.WN]+
 			e.pieceCount[data.White][data.WB]+e.pieceCount[data.Black][data.WB])
 
-	phase = (phase*256 + 12) / 24
+	return (phase*256 + 12) / 24
+}
 
-	result := (eval.Middle()*phase +
+// blend tapers the middle and end game scores by the phase, scaling the end
+// game by the given factor
+func (e *EvaluationService) blend(eval Score, phase, factor int) int {
+	return (eval.Middle()*phase +
 		eval.End()*(256-phase)*factor/scaleFactorNormal) / 256
+}
 
-	if p.Side == data.White {
-		return result
-	} else {
-		return -result
-	}
+// Centipawns converts a score to centipawns using the value of a pawn in the
+// game phase of p, a pawn is worth more than 100 in the end game
+func (e *EvaluationService) Centipawns(p *engine.Position, score int) int {
+	e.SetupEvaluate(p)
+	return score * 100 / e.blend(e.PawnValue, e.phase(), scaleFactorNormal)
 }
 
 func (e *EvaluationService) SetupEvaluate(p *engine.Position) {
 	for pt := data.WP; pt <= data.WK; pt++ {
-		e.pieceCount[data.White][pt] = 0
-		e.pieceCount[data.Black][pt] = 0
+		e.pieceCount[data.White][pt] = p.Board.CountBits(p.Board.GetPieces(data.White, pt))
+		e.pieceCount[data.Black][pt] = p.Board.CountBits(p.Board.GetPieces(data.Black, pt))
 	}
 
 	bothPawns := p.Board.WhitePawn | p.Board.BlackPawn
@@ -91,180 +186,229 @@ func isEndGame() int {
 	return (1 * data.PieceVal[data.WR]) + (2 * data.PieceVal[data.WN]) + (2 * data.PieceVal[data.WP]) + data.PieceVal[data.WK]
 }
 
-func (e *EvaluationService) calculateEvalPawns(p *engine.Position) Score {
+// evaluateMaterial scores the material for the given colour, SetupEvaluate
+// must be called first
+func (e *EvaluationService) evaluateMaterial(colour int) Score {
+	eval := e.PawnValue * Score(e.pieceCount[colour][data.WP])
+	eval += e.KnightValue * Score(e.pieceCount[colour][data.WN])
+	eval += e.BishopValue * Score(e.pieceCount[colour][data.WB])
+	eval += e.RookValue * Score(e.pieceCount[colour][data.WR])
+	eval += e.QueenValue * Score(e.pieceCount[colour][data.WQ])
+	return eval
+}
+
+// evaluatePSQT sums the piece square table scores for the given colour
+func (e *EvaluationService) evaluatePSQT(p *engine.Position, colour int) Score {
+	var eval Score
+	for pt := data.WP; pt <= data.WK; pt++ {
+		for bb := p.Board.GetPieces(colour, pt); bb != 0; bb &= bb - 1 {
+			eval += e.PSQT[colour][pt][engine.FirstSquare(bb)]
+		}
+	}
+	return eval
+}
+
+// evaluatePawnStructure scores isolated and passed pawns for the given colour,
+// passed pawns gain more the further the enemy king is from their queening
+// square and the further they have advanced
+func (e *EvaluationService) evaluatePawnStructure(p *engine.Position, colour int) Score {
 	var eval Score
-	for wp := p.Board.WhitePawn; wp != 0; wp &= wp - 1 {
-		e.pieceCount[data.White][data.WP]++
-		sq := engine.FirstSquare(wp)
-		eval += e.PSQT[data.White][data.WP][sq]
-		if data.IsolatedMask[sq]&p.Board.WhitePawn == 0 {
+	friendly, enemy, passedMask := p.Board.WhitePawn, p.Board.BlackPawn, &data.WhitePassedMask
+	promotionRank := 56
+	if colour == data.Black {
+		friendly, enemy, passedMask = p.Board.BlackPawn, p.Board.WhitePawn, &data.BlackPassedMask
+		promotionRank = 0
+	}
+	enemyKing := p.Board.GetPieces(colour^1, data.WK)
+
+	for bb := friendly; bb != 0; bb &= bb - 1 {
+		sq := engine.FirstSquare(bb)
+		if data.IsolatedMask[sq]&friendly == 0 {
 			eval += e.PawnIsolated
 		}
 
-		if data.WhitePassedMask[sq]&p.Board.BlackPawn == 0 {
-			eval += e.PassedPawn[data.RanksBoard[data.Square64ToSquare120[sq]]]
+		if passedMask[sq]&enemy == 0 {
+			rank := relativeRank(colour, sq)
+			eval += e.PassedPawn[rank]
+			if enemyKing != 0 {
+				distance := engine.KingDistance(engine.FirstSquare(enemyKing), promotionRank+sq%8)
+				eval += e.PassedKingDistance * Score(distance*rank)
+			}
 		}
 	}
 
-	for bp := p.Board.BlackPawn; bp != 0; bp &= bp - 1 {
-		e.pieceCount[data.Black][data.WP]++
-		sq := engine.FirstSquare(bp)
-		eval -= e.PSQT[data.Black][data.WP][sq]
+	return eval
+}
+
+// evaluateOpenFiles scores rooks and queens on open and semi-open files for
+// the given colour
+func (e *EvaluationService) evaluateOpenFiles(p *engine.Position, colour int, bothPawns uint64) Score {
+	var eval Score
+	friendlyPawns := p.Board.GetPieces(colour, data.WP)
 
-		if data.IsolatedMask[sq]&p.Board.BlackPawn == 0 {
-			eval -= e.PawnIsolated
+	for bb := p.Board.GetPieces(colour, data.WR); bb != 0; bb &= bb - 1 {
+		file := data.FileBBMask[data.FilesBoard[data.Square64ToSquare120[engine.FirstSquare(bb)]]]
+		if bothPawns&file == 0 {
+			eval += e.RookOpenFile
+		} else if friendlyPawns&file == 0 {
+			eval += e.RookSemiOpenFile
 		}
+	}
 
-		if data.BlackPassedMask[sq]&p.Board.WhitePawn == 0 {
-			eval -= e.PassedPawn[data.RanksBoard[data.Square64ToSquare120[flip(sq)]]]
+	for bb := p.Board.GetPieces(colour, data.WQ); bb != 0; bb &= bb - 1 {
+		file := data.FileBBMask[data.FilesBoard[data.Square64ToSquare120[engine.FirstSquare(bb)]]]
+		if bothPawns&file == 0 {
+			eval += e.QueenOpenFile
+		} else if friendlyPawns&file == 0 {
+			eval += e.QueenSemiOpenFile
 		}
 	}
 
 	return eval
 }
 
-func (e *EvaluationService) calculateEvalKnights(p *engine.Position) Score {
+// evaluateSeventhRank scores rooks on the colour's seventh rank, with an
+// extra bonus when the enemy king is trapped on its back rank behind them
+func (e *EvaluationService) evaluateSeventhRank(p *engine.Position, colour int) Score {
 	var eval Score
-	bb := p.Board.WhiteKnight
-	for bb != 0 {
-		e.pieceCount[data.White][data.WN]++
-		sq := engine.FirstSquare(bb)
-		eval += e.PSQT[data.White][data.WN][sq]
-		bb ^= (1 << sq)
-	}
-	bb = p.Board.BlackKnight
-	for bb != 0 {
-		e.pieceCount[data.Black][data.WN]++
-		sq := engine.FirstSquare(bb)
-		eval -= e.PSQT[data.Black][data.WN][sq]
-		bb ^= (1 << sq)
+	enemyKing := p.Board.GetPieces(colour^1, data.WK)
+	kingOnEighth := enemyKing != 0 && relativeRank(colour, engine.FirstSquare(enemyKing)) == 7
+
+	for bb := p.Board.GetPieces(colour, data.WR); bb != 0; bb &= bb - 1 {
+		if relativeRank(colour, engine.FirstSquare(bb)) != 6 {
+			continue
+		}
+		eval += e.RookSeventhRank
+		if kingOnEighth {
+			eval += e.RookSeventhKing
+		}
 	}
 
 	return eval
 }
 
-func (e *EvaluationService) calculateEvalBishop(p *engine.Position) Score {
+// evaluateImbalance scores piece pairs for the given colour: a bonus for the
+// bishop pair (only when the bishops cover both square colours), a small
+// penalty for the knight pair and for redundant rooks
+func (e *EvaluationService) evaluateImbalance(p *engine.Position, colour int) Score {
 	var eval Score
-	bb := p.Board.WhiteBishop
-	countWB := 0
-	countBB := 0
-	for bb != 0 {
-		e.pieceCount[data.White][data.WB]++
-		countWB++
-		sq := engine.FirstSquare(bb)
-		eval += e.PSQT[data.White][data.WB][sq]
-		bb ^= (1 << sq)
-	}
 
-	bb = p.Board.BlackBishop
-	for bb != 0 {
-		e.pieceCount[data.Black][data.WB]++
-		countBB++
-		sq := engine.FirstSquare(bb)
-		eval -= e.PSQT[data.Black][data.WB][sq]
-		bb ^= (1 << sq)
-	}
-	if countWB >= 2 {
+	if hasBishopPair(p.Board.GetPieces(colour, data.WB)) {
 		eval += e.BishopPair
 	}
-	if countBB >= 2 {
-		eval -= e.BishopPair
+	if e.pieceCount[colour][data.WN] >= 2 {
+		eval += e.KnightPair
 	}
+	if e.pieceCount[colour][data.WR] >= 2 {
+		eval += e.RookPair
+	}
+
 	return eval
 }
 
-func (e *EvaluationService) calculateEvalRook(p *engine.Position, bothPawns uint64) Score {
-	var eval Score
+// evaluateBackRank penalises a king on its back rank that has no escape square
+// in front of it when an enemy rook or queen stands on a file clear of the
+// colour's pawns and no friendly rook or queen guards the back rank
+func (e *EvaluationService) evaluateBackRank(p *engine.Position, colour int) Score {
+	king := p.Board.GetPieces(colour, data.WK)
+	if king == 0 {
+		return 0
+	}
 
-	bb := p.Board.WhiteRook
-	for bb != 0 {
-		e.pieceCount[data.White][data.WR]++
-		sq := engine.FirstSquare(bb)
-		eval += e.PSQT[data.White][data.WR][sq]
-		bb ^= (1 << sq)
+	backRank, luftRank := data.Rank1Mask, data.Rank2Mask
+	if colour == data.Black {
+		backRank, luftRank = data.Rank8Mask, data.Rank7Mask
+	}
 
-		if bothPawns&data.FileBBMask[data.FilesBoard[data.Square64ToSquare120[sq]]] == 0 {
-			eval += e.RookOpenFile
-		} else if p.Board.WhitePawn&data.FileBBMask[data.FilesBoard[data.Square64ToSquare120[sq]]] == 0 {
-			eval += e.RookSemiOpenFile
-		}
+	if king&backRank == 0 {
+		return 0
 	}
 
-	bb = p.Board.BlackRook
-	for bb != 0 {
-		e.pieceCount[data.Black][data.WR]++
-		sq := engine.FirstSquare(bb)
-		eval -= e.PSQT[data.Black][data.WR][sq]
-		bb ^= (1 << sq)
+	friendly := p.Board.GetPiecesBitboard(colour)
+	if engine.PreCalculatedKingMoves[engine.FirstSquare(king)]&luftRank&^friendly != 0 {
+		return 0
+	}
 
-		if bothPawns&data.FileBBMask[data.FilesBoard[data.Square64ToSquare120[sq]]] == 0 {
-			eval -= e.RookOpenFile
-		} else if p.Board.BlackPawn&data.FileBBMask[data.FilesBoard[data.Square64ToSquare120[sq]]] == 0 {
-			eval -= e.RookSemiOpenFile
+	if (p.Board.GetPieces(colour, data.WR)|p.Board.GetPieces(colour, data.WQ))&backRank != 0 {
+		return 0
+	}
+
+	friendlyPawns := p.Board.GetPieces(colour, data.WP)
+	enemyMajors := p.Board.GetPieces(colour^1, data.WR) | p.Board.GetPieces(colour^1, data.WQ)
+	for ; enemyMajors != 0; enemyMajors &= enemyMajors - 1 {
+		file := data.FileBBMask[data.FilesBoard[data.Square64ToSquare120[engine.FirstSquare(enemyMajors)]]]
+		if friendlyPawns&file == 0 {
+			return e.BackRankWeakness
 		}
 	}
 
-	return eval
+	return 0
 }
 
-func (e *EvaluationService) calculateEvalQueens(p *engine.Position, bothPawns uint64) Score {
-	var eval Score
-
-	bb := p.Board.WhiteQueen
-	for bb != 0 {
-		e.pieceCount[data.White][data.WQ]++
-		sq := engine.FirstSquare(bb)
-		eval += e.PSQT[data.White][data.WQ][sq]
-		bb ^= (1 << sq)
-
-		if bothPawns&data.FileBBMask[data.FilesBoard[data.Square64ToSquare120[sq]]] == 0 {
-			eval += e.QueenOpenFile
-		} else if p.Board.WhitePawn&data.FileBBMask[data.FilesBoard[data.Square64ToSquare120[sq]]] == 0 {
-			eval += e.QueenSemiOpenFile
-		}
+// evaluateMopUp helps the colour convert a basic mate when the enemy has a
+// bare king against a queen or rook, rewarding driving the enemy king to the
+// edge and bringing the colour's king closer to it
+func (e *EvaluationService) evaluateMopUp(p *engine.Position, colour int) Score {
+	enemyKing := p.Board.GetPieces(colour^1, data.WK)
+	if p.Board.GetPiecesBitboard(colour^1) != enemyKing || enemyKing == 0 {
+		return 0
 	}
-	bb = p.Board.BlackQueen
-	for bb != 0 {
-		e.pieceCount[data.Black][data.WQ]++
-		sq := engine.FirstSquare(bb)
-		eval -= e.PSQT[data.Black][data.WQ][sq]
-		bb ^= (1 << sq)
-
-		if bothPawns&data.FileBBMask[data.FilesBoard[data.Square64ToSquare120[sq]]] == 0 {
-			eval -= e.QueenOpenFile
-		} else if p.Board.BlackPawn&data.FileBBMask[data.FilesBoard[data.Square64ToSquare120[sq]]] == 0 {
-			eval -= e.QueenSemiOpenFile
-		}
+	if p.Board.GetPieces(colour, data.WQ)|p.Board.GetPieces(colour, data.WR) == 0 {
+		return 0
+	}
+	king := p.Board.GetPieces(colour, data.WK)
+	if king == 0 {
+		return 0
 	}
 
+	enemySq := engine.FirstSquare(enemyKing)
+	kingSq := engine.FirstSquare(king)
+	eval := e.MopUpEdge * Score(centreDistance(enemySq))
+	eval += e.MopUpKingDistance * Score(7-engine.KingDistance(kingSq, enemySq))
 	return eval
 }
 
-func (e *EvaluationService) calculateEvalKings(p *engine.Position) Score {
-	var eval Score
-
-	bb := p.Board.WhiteKing
-	for bb != 0 {
-		sq := engine.FirstSquare(bb)
-		eval += e.PSQT[data.White][data.WK][sq]
-		bb ^= (1 << sq)
+// evaluateStalemateRisk penalises the colour for leaving an enemy with only
+// a king and pawns short of legal moves while it is the enemy's turn, so a
+// won ending is not thrown away by stalemating the king
+func (e *EvaluationService) evaluateStalemateRisk(p *engine.Position, colour int) Score {
+	enemy := colour ^ 1
+	if p.Side != enemy || p.IsKingAttacked(colour) {
+		return 0
 	}
-
-	bb = p.Board.BlackKing
-	for bb != 0 {
-		sq := engine.FirstSquare(bb)
-		eval -= e.PSQT[data.Black][data.WK][sq]
-		bb ^= (1 << sq)
+	enemyPieces := p.Board.GetPiecesBitboard(enemy)
+	if enemyPieces != p.Board.GetPieces(enemy, data.WK)|p.Board.GetPieces(enemy, data.WP) {
+		return 0
 	}
-
-	return eval
+	if p.Board.GetPiecesBitboard(colour) == p.Board.GetPieces(colour, data.WK)|p.Board.GetPieces(colour, data.WP) {
+		return 0
+	}
+	// a king with two safe squares is seen from the attack maps without
+	// making every move
+	king := p.Board.GetPieces(enemy, data.WK)
+	occupied := p.Board.Pieces &^ king
+	escapes := 0
+	for squares := engine.PreCalculatedKingMoves[engine.FirstSquare(king)] &^ enemyPieces; squares != 0 && escapes < 2; squares &= squares - 1 {
+		if p.AttackersTo(engine.FirstSquare(squares), colour, occupied) == 0 {
+			escapes++
+		}
+	}
+	if escapes >= 2 {
+		return 0
+	}
+	moves := p.CountLegalMoves(enemy)
+	if moves >= 2 {
+		return 0
+	}
+	return e.StalemateRisk * Score(2-moves)
 }
 
-func (e *EvaluationService) evaluateMobility(p *engine.Position) Score {
-	eval := e.EvaluateMobilityKnights(p, data.White) - e.EvaluateMobilityKnights(p, data.Black)
-	eval += e.EvaluateMobilityBishops(p, data.White) - e.EvaluateMobilityBishops(p, data.Black)
-	eval += e.EvaluateMobilityRooks(p, data.White) - e.EvaluateMobilityRooks(p, data.Black)
-	eval += e.EvaluateMobilityQueens(p, data.White) - e.EvaluateMobilityQueens(p, data.Black)
+// evaluateMobility scores the mobility of the given colour's pieces
+func (e *EvaluationService) evaluateMobility(p *engine.Position, colour int) Score {
+	eval := e.EvaluateMobilityKnights(p, colour)
+	eval += e.EvaluateMobilityBishops(p, colour)
+	eval += e.EvaluateMobilityRooks(p, colour)
+	eval += e.EvaluateMobilityQueens(p, colour)
 
 	return eval
 }
@@ -398,6 +542,65 @@ func (e *EvaluationService) IsMaterialDraw(p *engine.Position) bool {
 	return false
 }
 
+// oppositeBishops checks each side has one bishop and they are on opposite
+// coloured squares
+func oppositeBishops(p *engine.Position) bool {
+	return OnlyOne(p.Board.WhiteBishop) && OnlyOne(p.Board.BlackBishop) &&
+		OnlyOne((p.Board.WhiteBishop|p.Board.BlackBishop)&darkSquares)
+}
+
+// IsOppositeBishopDraw checks for an ending with only a bishop each, on
+// opposite coloured squares, and no more than two pawns between the sides.
+// The side ahead can rarely win as the defending bishop holds a blockade on
+// the squares the other bishop cannot reach
+func IsOppositeBishopDraw(p *engine.Position) bool {
+	b := &p.Board
+	kings := b.WhiteKing | b.BlackKing
+	pieces := (b.WhitePieces | b.BlackPieces) &^ (b.WhitePawn | b.BlackPawn | kings)
+	if pieces != b.WhiteBishop|b.BlackBishop || !oppositeBishops(p) {
+		return false
+	}
+	return util.Abs(b.CountBits(b.WhitePawn)-b.CountBits(b.BlackPawn)) <= 2
+}
+
+// isWrongBishopRookPawn checks if side has only a bishop and pawns on one
+// rook file, where the bishop cannot cover the queening square, and the
+// other king is next to that square. The defending king can never be driven
+// out of the corner so it is a draw
+func isWrongBishopRookPawn(p *engine.Position, side int) bool {
+	b := &p.Board
+	own, other, pawns, bishops := b.WhitePieces, b.BlackPieces, b.WhitePawn, b.WhiteBishop
+	otherKing, corner := b.BlackKing, 56
+	if side == data.Black {
+		own, other, pawns, bishops = b.BlackPieces, b.WhitePieces, b.BlackPawn, b.BlackBishop
+		otherKing, corner = b.WhiteKing, 0
+	}
+	if own&^(pawns|b.WhiteKing|b.BlackKing) != bishops || !OnlyOne(bishops) {
+		return false
+	}
+	if other&^(b.WhitePawn|b.BlackPawn|b.WhiteKing|b.BlackKing) != 0 {
+		return false
+	}
+	switch {
+	case pawns != 0 && pawns&^data.FileAMask == 0:
+	case pawns != 0 && pawns&^data.FileHMask == 0:
+		corner += 7
+	default:
+		return false
+	}
+	cornerDark := uint64(1)<<corner&darkSquares != 0
+	bishopDark := bishops&darkSquares != 0
+	return cornerDark != bishopDark && engine.KingDistance(engine.FirstSquare(otherKing), corner) <= 1
+}
+
+// isRookEnding checks each side has a rook and nothing else but pawns
+func isRookEnding(p *engine.Position) bool {
+	b := &p.Board
+	kings := b.WhiteKing | b.BlackKing
+	pieces := (b.WhitePieces | b.BlackPieces) &^ (b.WhitePawn | b.BlackPawn | kings)
+	return pieces == b.WhiteRook|b.BlackRook && OnlyOne(b.WhiteRook) && OnlyOne(b.BlackRook)
+}
+
 func computeFactor(e *EvaluationService, p *engine.Position, eval Score, bothPawns uint64) int {
 	var strongSide int
 	var strong uint64
@@ -406,7 +609,7 @@ func computeFactor(e *EvaluationService, p *engine.Position, eval Score, bothPaw
 		strong = p.Board.WhitePieces
 	} else {
 		strongSide = data.Black
-		strong = p.Board.Pieces
+		strong = p.Board.BlackPieces
 	}
 
 	var strongPawnCount = e.pieceCount[strongSide][data.WP]
@@ -418,24 +621,26 @@ func computeFactor(e *EvaluationService, p *engine.Position, eval Score, bothPaw
 		pawnScale -= 20
 	}
 
-	bishops := p.Board.WhiteBishop | p.Board.BlackBishop
-	kings := p.Board.WhiteKing | p.Board.BlackKing
-
-	if e.pieceCount[data.White][data.WB] == 1 &&
-		e.pieceCount[data.Black][data.WB] == 1 &&
-		OnlyOne(bishops&darkSquares) {
+	if isWrongBishopRookPawn(p, strongSide) {
+		return 0
+	}
 
-		var whiteNonPawnCount = p.Board.CountBits(p.Board.WhitePieces &^ (bothPawns | kings))
-		var blackNonPawnCount = p.Board.CountBits(p.Board.BlackPieces &^ (bothPawns | kings))
-		if whiteNonPawnCount == blackNonPawnCount &&
-			whiteNonPawnCount <= 2 &&
-			blackNonPawnCount <= 2 {
+	if isRookEnding(p) && util.Abs(p.Board.CountBits(p.Board.WhitePawn)-p.Board.CountBits(p.Board.BlackPawn)) <= 1 {
+		pawnScale = util.Min(pawnScale, e.RookEndingScale)
+	}
 
-			if whiteNonPawnCount == 1 {
-				pawnScale = util.Min(pawnScale, 64)
-			} else {
-				pawnScale = util.Min(pawnScale, 96)
-			}
+	if oppositeBishops(p) {
+		kings := p.Board.WhiteKing | p.Board.BlackKing
+		whiteNonPawnCount := p.Board.CountBits(p.Board.WhitePieces &^ (bothPawns | kings))
+		blackNonPawnCount := p.Board.CountBits(p.Board.BlackPieces &^ (bothPawns | kings))
+		switch {
+		case IsOppositeBishopDraw(p):
+			pawnScale = util.Min(pawnScale, e.OppositeBishopScale)
+		case whiteNonPawnCount == 1 && blackNonPawnCount == 1:
+			// a bigger lead in pawns is scaled half as much
+			pawnScale = util.Min(pawnScale, 2*e.OppositeBishopScale)
+		case whiteNonPawnCount == 2 && blackNonPawnCount == 2:
+			pawnScale = util.Min(pawnScale, e.OppositeBishopPieceScale)
 		}
 	}
 
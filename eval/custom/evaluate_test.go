@@ -1,6 +1,7 @@
 package eval
 
 import (
+	"math/rand"
 	"testing"
 
 	"github.com/AdamGriffiths31/ChessEngine/data"
@@ -26,3 +27,413 @@ func TestBishopMobility(t *testing.T) {
 		t.Errorf("Expected %v but got %v", e.BishopMobility[1], eval)
 	}
 }
+
+func TestBishopPair(t *testing.T) {
+	pair := engine.ParseFen("4k3/8/8/8/8/8/8/2B1KB2 w - - 0 1")
+	sameColour := engine.ParseFen("4k3/8/8/8/8/8/8/2B1K1B1 w - - 0 1")
+	e := NewEvaluationService()
+
+	e.SetupEvaluate(pair.Position())
+	withPair := e.evaluateImbalance(pair.Position(), data.White)
+	e.SetupEvaluate(sameColour.Position())
+	withoutPair := e.evaluateImbalance(sameColour.Position(), data.White)
+
+	if withPair-withoutPair != e.BishopPair {
+		t.Errorf("Expected %v but got %v", e.BishopPair, withPair-withoutPair)
+	}
+}
+
+func TestBishopPairBlack(t *testing.T) {
+	game := engine.ParseFen("2b1kb2/8/8/8/8/8/8/4K3 w - - 0 1")
+	e := NewEvaluationService()
+	e.SetupEvaluate(game.Position())
+
+	eval := e.evaluateImbalance(game.Position(), data.Black)
+	if eval != e.BishopPair {
+		t.Errorf("Expected %v but got %v", e.BishopPair, eval)
+	}
+}
+
+func TestKnightAndRookPair(t *testing.T) {
+	game := engine.ParseFen("4k3/8/8/8/8/8/8/RN2K1NR w - - 0 1")
+	e := NewEvaluationService()
+	e.SetupEvaluate(game.Position())
+
+	eval := e.evaluateImbalance(game.Position(), data.White)
+	if eval != e.KnightPair+e.RookPair {
+		t.Errorf("Expected %v but got %v", e.KnightPair+e.RookPair, eval)
+	}
+}
+
+func TestPairsChangeEvaluate(t *testing.T) {
+	pairGame := engine.ParseFen("4k3/pppp4/8/8/8/8/PPPP4/2B1KB2 w - - 0 1")
+	sameColourGame := engine.ParseFen("4k3/pppp4/8/8/8/8/PPPP4/2B1K1B1 w - - 0 1")
+	othersGame := engine.ParseFen("4k3/pppp4/8/8/8/8/PPPP4/RN2K1NR w - - 0 1")
+	pair, sameColour, others := pairGame.Position(), sameColourGame.Position(), othersGame.Position()
+	e := NewEvaluationService()
+	if e.Evaluate(pair) <= e.Evaluate(sameColour) {
+		t.Errorf("Expected the bishop pair %v to score more than bishops on the same colour %v", e.Evaluate(pair), e.Evaluate(sameColour))
+	}
+
+	// the change in the evaluation when the pair weights are zeroed, -1 for
+	// lower, 0 for the same and 1 for higher
+	tests := []struct {
+		p      *engine.Position
+		change func(w *Weights)
+		want   int
+	}{
+		{pair, func(w *Weights) { w.BishopPair = 0 }, -1},
+		{sameColour, func(w *Weights) { w.BishopPair = 0 }, 0},
+		{others, func(w *Weights) { w.KnightPair, w.RookPair = 0, 0 }, 1},
+	}
+	for _, test := range tests {
+		e := NewEvaluationService()
+		before := e.Evaluate(test.p)
+		w := e.Weights
+		test.change(&w)
+		e.SetWeights(w)
+		after := e.Evaluate(test.p)
+		got := 0
+		if after > before {
+			got = 1
+		} else if after < before {
+			got = -1
+		}
+		if got != test.want {
+			t.Errorf("%v: expected a change of %v but went from %v to %v", test.p.ToFEN(), test.want, before, after)
+		}
+	}
+}
+
+func TestRookOpenFile(t *testing.T) {
+	tests := []struct {
+		fen  string
+		want func(e *EvaluationService) Score
+	}{
+		{"4k3/ppp2ppp/8/8/8/8/PPP2PPP/3RK3 w - - 0 1", func(e *EvaluationService) Score { return e.RookOpenFile }},
+		{"4k3/pppp1ppp/8/8/8/8/PPP1PPPP/3RK3 w - - 0 1", func(e *EvaluationService) Score { return e.RookSemiOpenFile }},
+		{"4k3/pppppppp/8/8/8/8/PPPPPPPP/3RK3 w - - 0 1", func(e *EvaluationService) Score { return 0 }},
+	}
+	e := NewEvaluationService()
+	for _, test := range tests {
+		game := engine.ParseFen(test.fen)
+		p := game.Position()
+		eval := e.evaluateOpenFiles(p, data.White, p.Board.WhitePawn|p.Board.BlackPawn)
+		if want := test.want(e); eval != want {
+			t.Errorf("%v: expected %v but got %v", test.fen, want, eval)
+		}
+	}
+
+	open := engine.ParseFen("4k3/ppp2ppp/8/8/8/8/PPP2PPP/3RK3 w - - 0 1")
+	closed := engine.ParseFen("4k3/ppp2ppp/8/8/8/8/PPP2PPP/2R1K3 w - - 0 1")
+	bothPawns := open.Position().Board.WhitePawn | open.Position().Board.BlackPawn
+	if e.evaluateOpenFiles(open.Position(), data.White, bothPawns) <= e.evaluateOpenFiles(closed.Position(), data.White, bothPawns) {
+		t.Errorf("Expected the rook on the open file to score more than the rook behind its pawn")
+	}
+}
+
+func TestRookSeventhRank(t *testing.T) {
+	tests := []struct {
+		fen    string
+		colour int
+		want   func(e *EvaluationService) Score
+	}{
+		{"4k3/R7/8/8/8/8/8/4K3 w - - 0 1", data.White, func(e *EvaluationService) Score { return e.RookSeventhRank + e.RookSeventhKing }},
+		{"8/R7/4k3/8/8/8/8/4K3 w - - 0 1", data.White, func(e *EvaluationService) Score { return e.RookSeventhRank }},
+		{"4k3/8/R7/8/8/8/8/4K3 w - - 0 1", data.White, func(e *EvaluationService) Score { return 0 }},
+		{"4k3/8/8/8/8/8/r7/4K3 w - - 0 1", data.Black, func(e *EvaluationService) Score { return e.RookSeventhRank + e.RookSeventhKing }},
+	}
+	e := NewEvaluationService()
+	for _, test := range tests {
+		game := engine.ParseFen(test.fen)
+		eval := e.evaluateSeventhRank(game.Position(), test.colour)
+		if want := test.want(e); eval != want {
+			t.Errorf("%v: expected %v but got %v", test.fen, want, eval)
+		}
+	}
+}
+
+func TestAdvancingPassedPawnIncreasesEval(t *testing.T) {
+	e := NewEvaluationService()
+	for _, fens := range [][]string{
+		{"K7/8/8/8/8/8/3P4/7k w - - 0 1", "K7/8/8/8/8/3P4/8/7k w - - 0 1", "K7/8/8/8/3P4/8/8/7k w - - 0 1",
+			"K7/8/8/3P4/8/8/8/7k w - - 0 1", "K7/8/3P4/8/8/8/8/7k w - - 0 1", "K7/3P4/8/8/8/8/8/7k w - - 0 1"},
+		{"7K/3p4/8/8/8/8/8/k7 b - - 0 1", "7K/8/3p4/8/8/8/8/k7 b - - 0 1", "7K/8/8/3p4/8/8/8/k7 b - - 0 1",
+			"7K/8/8/8/3p4/8/8/k7 b - - 0 1", "7K/8/8/8/8/3p4/8/k7 b - - 0 1", "7K/8/8/8/8/8/3p4/k7 b - - 0 1"},
+	} {
+		previous := -data.ABInfinite
+		for _, fen := range fens {
+			game := engine.ParseFen(fen)
+			eval := e.Evaluate(game.Position())
+			if eval <= previous {
+				t.Errorf("%v: expected more than %v but got %v", fen, previous, eval)
+			}
+			previous = eval
+		}
+	}
+}
+
+func TestPassedPawnKingDistance(t *testing.T) {
+	near := engine.ParseFen("3k4/8/8/3P4/8/8/8/K7 w - - 0 1")
+	far := engine.ParseFen("8/8/8/3P4/8/8/8/K6k w - - 0 1")
+	e := NewEvaluationService()
+	nearEval := e.evaluatePawnStructure(near.Position(), data.White)
+	farEval := e.evaluatePawnStructure(far.Position(), data.White)
+	if want := e.PassedKingDistance * Score(7*4); farEval-nearEval != want {
+		t.Errorf("Expected %v but got %v", want, farEval-nearEval)
+	}
+}
+
+func TestEvaluateTraceMatchesEvaluate(t *testing.T) {
+	fens := []string{
+		data.StartFEN,
+		"r1bqkb1r/pppp1ppp/2n2n2/4p2Q/2B1P3/8/PPPP1PPP/RNB1K1NR w KQkq - 4 4",
+		"2r3k1/pppR1pp1/4p3/4P1P1/5P2/1P4K1/P1P5/8 b - - 0 1",
+		"8/8/4k3/8/2B5/8/3K4/8 w - - 0 1",
+		"8/8/8/3k4/8/2K5/8/1Q6 w - - 0 1",
+	}
+	e := NewEvaluationService()
+	for _, fen := range fens {
+		game := engine.ParseFen(fen)
+		trace := e.EvaluateTrace(game.Position())
+		eval := e.Evaluate(game.Position())
+		if trace.Total != eval {
+			t.Errorf("%v: expected %v but got %v", fen, eval, trace.Total)
+		}
+
+		sum := e.blend(trace.Sum(), trace.Phase, trace.Factor)
+		if game.Position().Side == data.Black {
+			sum = -sum
+		}
+		if !trace.MaterialDraw && sum != eval {
+			t.Errorf("%v: expected components to sum to %v but got %v", fen, eval, sum)
+		}
+	}
+}
+
+func TestFiftyMoveClockScalesEvaluation(t *testing.T) {
+	game := engine.ParseFen("4k3/8/8/8/8/8/4P3/R3K3 w - - 0 1")
+	p := game.Position()
+	e := NewEvaluationService()
+	fresh := e.Evaluate(p)
+	p.FiftyMove = 90
+	late := e.Evaluate(p)
+	if late <= 0 || late >= fresh {
+		t.Errorf("Expected a clock of 90 to score between 0 and %v but got %v", fresh, late)
+	}
+	if trace := e.EvaluateTrace(p); trace.Total != late {
+		t.Errorf("Expected the trace to total %v but got %v", late, trace.Total)
+	}
+	p.FiftyMove = fiftyMoveScaleStart
+	if eval := e.Evaluate(p); eval != fresh {
+		t.Errorf("Expected no scaling before the clock passes %v but got %v", fiftyMoveScaleStart, eval)
+	}
+}
+
+func TestOppositeBishopEndingIsDrawish(t *testing.T) {
+	e := NewEvaluationService()
+	ocbGame := engine.ParseFen("6k1/5ppp/4b3/8/8/P7/3B1PPP/6K1 w - - 0 1")
+	sameGame := engine.ParseFen("6k1/4bppp/8/8/8/P7/3B1PPP/6K1 w - - 0 1")
+	ocb, same := e.Evaluate(ocbGame.Position()), e.Evaluate(sameGame.Position())
+	if ocb <= 0 || ocb > same/2 {
+		t.Errorf("Expected a pawn up with opposite bishops to score under half of %v but got %v", same, ocb)
+	}
+}
+
+func TestIsOppositeBishopDraw(t *testing.T) {
+	tests := []struct {
+		fen  string
+		want bool
+	}{
+		{"6k1/5ppp/4b3/8/8/P7/3B1PPP/6K1 w - - 0 1", true},
+		// same coloured bishops
+		{"6k1/4bppp/8/8/8/P7/3B1PPP/6K1 w - - 0 1", false},
+		// three pawns up
+		{"6k1/5ppp/4b3/8/8/PPP5/3B1PPP/6K1 w - - 0 1", false},
+		// a rook each as well
+		{"r5k1/5ppp/4b3/8/8/P7/3B1PPP/R5K1 w - - 0 1", false},
+	}
+	for _, tt := range tests {
+		game := engine.ParseFen(tt.fen)
+		if got := IsOppositeBishopDraw(game.Position()); got != tt.want {
+			t.Errorf("%v: expected %v but got %v", tt.fen, tt.want, got)
+		}
+	}
+}
+
+func TestScaleFactor(t *testing.T) {
+	tests := []struct {
+		name     string
+		fen      string
+		min, max int
+	}{
+		{"wrong bishop", "7k/8/8/7P/8/8/8/3BK3 w - - 0 1", 0, 0},
+		{"wrong bishop for black", "2b1k3/8/8/8/p7/8/8/1K6 b - - 0 1", 0, 0},
+		{"right bishop", "7k/8/8/7P/8/8/8/2B1K3 w - - 0 1", 1, 127},
+		{"king away from the corner", "8/8/8/3k3P/8/8/8/3BK3 w - - 0 1", 1, 127},
+		{"rook ending a pawn up", "4k3/r4ppp/8/8/8/8/R3PPPP/4K3 w - - 0 1", 1, 96},
+		{"winning ending", "4k3/8/8/8/8/8/PPPPPPPP/3QK3 w - - 0 1", 128, 128},
+	}
+	e := NewEvaluationService()
+	for _, tt := range tests {
+		game := engine.ParseFen(tt.fen)
+		if got := e.ScaleFactor(game.Position()); got < tt.min || got > tt.max {
+			t.Errorf("%v: expected a factor from %v to %v but got %v", tt.name, tt.min, tt.max, got)
+		}
+	}
+}
+
+func TestMopUpPushesBareKingToCorner(t *testing.T) {
+	fens := []string{
+		"8/8/8/4k3/8/8/2K5/1Q6 w - - 0 1",
+		"4k3/8/8/8/8/8/2K5/1Q6 w - - 0 1",
+		"5k2/8/8/8/8/8/2K5/1Q6 w - - 0 1",
+		"6k1/8/8/8/8/8/2K5/1Q6 w - - 0 1",
+		"7k/8/8/8/8/8/2K5/1Q6 w - - 0 1",
+	}
+	e := NewEvaluationService()
+	previous := -data.ABInfinite
+	for _, fen := range fens {
+		game := engine.ParseFen(fen)
+		eval := e.Evaluate(game.Position())
+		if eval <= previous {
+			t.Errorf("%v: expected more than %v but got %v", fen, previous, eval)
+		}
+		previous = eval
+	}
+}
+
+func TestBackRankWeakness(t *testing.T) {
+	weak := engine.ParseFen("4r1k1/5ppp/8/8/8/8/5PPP/6K1 w - - 0 1")
+	luft := engine.ParseFen("4r1k1/5ppp/8/8/8/7P/5PP1/6K1 w - - 0 1")
+	e := NewEvaluationService()
+
+	weakEval := e.evaluateBackRank(weak.Position(), data.White)
+	if weakEval != e.BackRankWeakness {
+		t.Errorf("Expected %v but got %v", e.BackRankWeakness, weakEval)
+	}
+
+	luftEval := e.evaluateBackRank(luft.Position(), data.White)
+	if luftEval != 0 {
+		t.Errorf("Expected 0 but got %v", luftEval)
+	}
+
+	if e.Evaluate(weak.Position()) >= e.Evaluate(luft.Position()) {
+		t.Errorf("Expected the weak back rank to score worse than the king with luft")
+	}
+}
+
+func TestBackRankGuarded(t *testing.T) {
+	game := engine.ParseFen("4r1k1/5ppp/8/8/8/8/5PPP/3R2K1 w - - 0 1")
+	e := NewEvaluationService()
+
+	eval := e.evaluateBackRank(game.Position(), data.White)
+	if eval != 0 {
+		t.Errorf("Expected 0 but got %v", eval)
+	}
+}
+
+func TestStalemateRisk(t *testing.T) {
+	e := NewEvaluationService()
+	tests := []struct {
+		fen  string
+		want Score
+	}{
+		{"4k3/8/8/8/8/8/8/3QK3 b - - 0 1", 0},
+		{"k7/8/8/1Q6/8/8/8/7K b - - 0 1", e.StalemateRisk},
+		{"7k/8/6QK/8/8/8/8/8 b - - 0 1", 2 * e.StalemateRisk},
+		{"7k/8/6QK/8/8/p7/8/8 b - - 0 1", e.StalemateRisk},
+		{"7k/8/6QK/8/8/8/8/8 w - - 0 1", 0},
+	}
+	for _, test := range tests {
+		game := engine.ParseFen(test.fen)
+		if got := e.evaluateStalemateRisk(game.Position(), data.White); got != test.want {
+			t.Errorf("%v: expected %v but got %v", test.fen, test.want, got)
+		}
+	}
+}
+
+func TestIncrementalPieceSquareMatchesScratch(t *testing.T) {
+	fens := []string{
+		data.StartFEN,
+		"r3k2r/pPp3pp/8/3Pp3/8/8/P1pP2PP/R3K2R w KQkq e6 0 1",
+	}
+	e := NewEvaluationService()
+	r := rand.New(rand.NewSource(1))
+
+	for n := 0; n < 40; n++ {
+		game := engine.ParseFen(fens[n%len(fens)])
+		p := game.Position()
+		e.Attach(p)
+
+		type undo struct{ move, enPas, castle, fifty int }
+		var played []undo
+		for ply := 0; ply < 60; ply++ {
+			ml := &engine.MoveList{}
+			p.GenerateAllMoves(ml)
+			r.Shuffle(ml.Count, func(i, j int) { ml.Moves[i], ml.Moves[j] = ml.Moves[j], ml.Moves[i] })
+
+			made := false
+			for i := 0; i < ml.Count && !made; i++ {
+				ok, enPas, castle, fifty := p.MakeMove(ml.Moves[i].Move)
+				if ok {
+					played = append(played, undo{ml.Moves[i].Move, enPas, castle, fifty})
+					made = true
+				}
+			}
+			if !made {
+				break
+			}
+			checkPieceScore(t, e, p)
+		}
+
+		for i := len(played) - 1; i >= 0; i-- {
+			p.TakeMoveBack(played[i].move, played[i].enPas, played[i].castle, played[i].fifty)
+			checkPieceScore(t, e, p)
+		}
+	}
+}
+
+func checkPieceScore(t *testing.T, e *EvaluationService, p *engine.Position) {
+	t.Helper()
+	e.SetupEvaluate(p)
+	scratch := e.evaluateMaterial(data.White) + e.evaluatePSQT(p, data.White) -
+		e.evaluateMaterial(data.Black) - e.evaluatePSQT(p, data.Black)
+	if Score(p.PieceScore) != scratch {
+		t.Fatalf("Expected %v but got %v", scratch, Score(p.PieceScore))
+	}
+}
+
+func TestEvaluationIsSymmetric(t *testing.T) {
+	fens := []string{
+		data.StartFEN,
+		"r3k2r/p1ppqpb1/bn2pnp1/3PN3/1p2P3/2N2Q1p/PPPBBPPP/R3K2R w KQkq - 0 1",
+		"8/2p5/3p4/KP5r/1R3p1k/8/4P1P1/8 w - - 0 1",
+		"r2q1rk1/pP1p2pp/Q4n2/bbp1p3/Np6/1B3NBn/pPPP1PPP/R3K2R b KQ - 0 1",
+	}
+	e := NewEvaluationService()
+	r := rand.New(rand.NewSource(1))
+
+	for n := 0; n < 40; n++ {
+		game := engine.ParseFen(fens[n%len(fens)])
+		p := game.Position()
+		for ply := 0; ply < 80; ply++ {
+			if err := e.CheckSymmetry(p.ToFEN()); err != nil {
+				t.Fatal(err)
+			}
+
+			ml := &engine.MoveList{}
+			p.GenerateAllMoves(ml)
+			r.Shuffle(ml.Count, func(i, j int) { ml.Moves[i], ml.Moves[j] = ml.Moves[j], ml.Moves[i] })
+			made := false
+			for i := 0; i < ml.Count && !made; i++ {
+				made, _, _, _ = p.MakeMove(ml.Moves[i].Move)
+			}
+			if !made {
+				break
+			}
+			p.Play = 0
+			p.PositionHistory.RemovePositionHistory()
+		}
+	}
+}
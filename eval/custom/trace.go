@@ -0,0 +1,140 @@
+package eval
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/AdamGriffiths31/ChessEngine/data"
+	"github.com/AdamGriffiths31/ChessEngine/engine"
+)
+
+// EvalTrace is a breakdown of an evaluation. Each term is held per colour
+// from that colour's point of view, Total matches the result of Evaluate
+type EvalTrace struct {
+	Material  [2]Score
+	PSQT      [2]Score
+	Pawns     [2]Score
+	Files     [2]Score
+	Seventh   [2]Score
+	Imbalance [2]Score
+	Mobility  [2]Score
+	Threats   [2]Score
+	BackRank  [2]Score
+	MopUp     [2]Score
+	Stalemate [2]Score
+
+	MaterialDraw bool
+	Phase        int
+	Factor       int
+	Total        int
+}
+
+// EvaluateTrace evaluates the position recording each term as it goes, it is
+// slower than Evaluate so should only be used for debugging
+func (e *EvaluationService) EvaluateTrace(p *engine.Position) EvalTrace {
+	var trace EvalTrace
+
+	if p.Board.WhitePawn == 0 && p.Board.BlackPawn == 0 && e.IsMaterialDraw(p) {
+		trace.MaterialDraw = true
+		return trace
+	}
+
+	bothPawns := p.Board.WhitePawn | p.Board.BlackPawn
+
+	e.SetupEvaluate(p)
+
+	for colour := data.White; colour <= data.Black; colour++ {
+		trace.Material[colour] = e.evaluateMaterial(colour)
+		trace.PSQT[colour] = e.evaluatePSQT(p, colour)
+		trace.Pawns[colour] = e.evaluatePawnStructure(p, colour)
+		trace.Files[colour] = e.evaluateOpenFiles(p, colour, bothPawns)
+		trace.Seventh[colour] = e.evaluateSeventhRank(p, colour)
+		trace.Imbalance[colour] = e.evaluateImbalance(p, colour)
+		trace.Mobility[colour] = e.evaluateMobility(p, colour)
+		trace.Threats[colour] = e.evaluateThreats(p, colour, bothPawns)
+		trace.BackRank[colour] = e.evaluateBackRank(p, colour)
+		trace.MopUp[colour] = e.evaluateMopUp(p, colour)
+		trace.Stalemate[colour] = e.evaluateStalemateRisk(p, colour)
+	}
+
+	eval := trace.Sum()
+	trace.Phase = e.phase()
+	trace.Factor = computeFactor(e, p, eval, bothPawns)
+	trace.Total = e.blend(eval, trace.Phase, trace.Factor)
+
+	if p.Side == data.Black {
+		trace.Total = -trace.Total
+	}
+	trace.Total = scaleFiftyMove(trace.Total, p.FiftyMove)
+
+	return trace
+}
+
+// Sum adds up every term from white's point of view
+func (t *EvalTrace) Sum() Score {
+	var eval Score
+	for _, term := range t.terms() {
+		eval += term.value[data.White] - term.value[data.Black]
+	}
+	return eval
+}
+
+type traceTerm struct {
+	name  string
+	value [2]Score
+}
+
+func (t *EvalTrace) terms() []traceTerm {
+	return []traceTerm{
+		{"Material", t.Material},
+		{"PSQT", t.PSQT},
+		{"Pawns", t.Pawns},
+		{"Files", t.Files},
+		{"Seventh", t.Seventh},
+		{"Imbalance", t.Imbalance},
+		{"Mobility", t.Mobility},
+		{"Threats", t.Threats},
+		{"BackRank", t.BackRank},
+		{"MopUp", t.MopUp},
+		{"Stalemate", t.Stalemate},
+	}
+}
+
+func (t EvalTrace) String() string {
+	var sb strings.Builder
+	if t.MaterialDraw {
+		sb.WriteString("Material draw\n")
+	}
+	fmt.Fprintf(&sb, "%-10s | %-18s | %-18s | %-18s\n", "Term", "White", "Black", "Total")
+	for _, term := range t.terms() {
+		fmt.Fprintf(&sb, "%-10s | %-18v | %-18v | %-18v\n", term.name, term.value[data.White], term.value[data.Black], term.value[data.White]-term.value[data.Black])
+	}
+	fmt.Fprintf(&sb, "Phase %d Factor %d\n", t.Phase, t.Factor)
+	fmt.Fprintf(&sb, "Total %d (side to move)\n", t.Total)
+	return sb.String()
+}
+
+// CheckSymmetry evaluates the position in the fen and its mirror, returning
+// an error naming the terms that differ if the side to move is not given the
+// same score in both
+func (e *EvaluationService) CheckSymmetry(fen string) error {
+	game := engine.ParseFen(fen)
+	p := game.Position()
+	e.Attach(p)
+	mirror := p.Mirror()
+
+	eval, mirrorEval := e.Evaluate(p), e.Evaluate(mirror)
+	if eval == mirrorEval {
+		return nil
+	}
+
+	trace, mirrorTrace := e.EvaluateTrace(p), e.EvaluateTrace(mirror)
+	var terms []string
+	mirrorTerms := mirrorTrace.terms()
+	for i, term := range trace.terms() {
+		if term.value[data.White] != mirrorTerms[i].value[data.Black] || term.value[data.Black] != mirrorTerms[i].value[data.White] {
+			terms = append(terms, term.name)
+		}
+	}
+	return fmt.Errorf("%v: evaluated %v but %v mirrored, differing terms %v", fen, eval, mirrorEval, terms)
+}
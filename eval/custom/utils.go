@@ -1,6 +1,9 @@
 package eval
 
-import "github.com/AdamGriffiths31/ChessEngine/data"
+import (
+	"github.com/AdamGriffiths31/ChessEngine/data"
+	"github.com/AdamGriffiths31/ChessEngine/util"
+)
 
 const (
 	darkSquares       = uint64(0xAA55AA55AA55AA55)
@@ -15,6 +18,27 @@ func Several(bb uint64) bool {
 	return bb&(bb-1) != 0
 }
 
+// hasBishopPair checks the bishops cover both light and dark squares
+func hasBishopPair(bishops uint64) bool {
+	return bishops&darkSquares != 0 && bishops&^darkSquares != 0
+}
+
 func flip(sq int) int {
 	return data.Mirror64[sq]
 }
+
+// relativeRank returns the rank of the square from the given colour's side
+// of the board
+func relativeRank(colour, sq int) int {
+	if colour == data.Black {
+		sq = flip(sq)
+	}
+	return sq / 8
+}
+
+// centreDistance returns how many king steps along files and ranks the square
+// is from the four centre squares
+func centreDistance(sq int) int {
+	file, rank := sq%8, sq/8
+	return util.Max(3-file, file-4) + util.Max(3-rank, rank-4)
+}
@@ -3,15 +3,24 @@ package eval
 import "github.com/AdamGriffiths31/ChessEngine/data"
 
 type Weights struct {
-	ThreatByPawn      Score
-	ThreatByPawnPush  Score
-	PassedPawn        [8]Score
-	PawnIsolated      Score
-	BishopPair        Score
-	RookOpenFile      Score
-	RookSemiOpenFile  Score
-	QueenOpenFile     Score
-	QueenSemiOpenFile Score
+	ThreatByPawn       Score
+	ThreatByPawnPush   Score
+	PassedPawn         [8]Score
+	PassedKingDistance Score
+	PawnIsolated       Score
+	BishopPair         Score
+	KnightPair         Score
+	RookPair           Score
+	RookOpenFile       Score
+	RookSemiOpenFile   Score
+	RookSeventhRank    Score
+	RookSeventhKing    Score
+	QueenOpenFile      Score
+	QueenSemiOpenFile  Score
+	BackRankWeakness   Score
+	MopUpEdge          Score
+	MopUpKingDistance  Score
+	StalemateRisk      Score
 
 	KnightMobility [9]Score
 	BishopMobility [14]Score
@@ -24,6 +33,15 @@ type Weights struct {
 	RookValue   Score
 	QueenValue  Score
 
+	// OppositeBishopScale is the factor out of 128 the end game score is
+	// scaled by when IsOppositeBishopDraw, OppositeBishopPieceScale is used
+	// when each side also has one other piece
+	OppositeBishopScale      int
+	OppositeBishopPieceScale int
+	// RookEndingScale is the factor for a rook each with no more than a
+	// pawn between the sides
+	RookEndingScale int
+
 	PSQT [2][7][64]Score `json:"-"`
 }
 
@@ -31,11 +49,23 @@ func (w *Weights) init() {
 	w.ThreatByPawn = S(-52, -73)
 	w.ThreatByPawnPush = S(-18, -7)
 	w.PawnIsolated = S(-8, -19)
+	w.PassedKingDistance = S(0, 2)
 	w.BishopPair = S(25, 124)
+	w.KnightPair = S(-8, -10)
+	w.RookPair = S(-12, -22)
 	w.RookOpenFile = S(10, 10)
 	w.RookSemiOpenFile = S(5, 5)
+	w.RookSeventhRank = S(10, 20)
+	w.RookSeventhKing = S(10, 15)
 	w.QueenOpenFile = S(5, 5)
 	w.QueenSemiOpenFile = S(3, 3)
+	w.BackRankWeakness = S(-45, -20)
+	w.MopUpEdge = S(0, 40)
+	w.MopUpKingDistance = S(0, 20)
+	w.StalemateRisk = S(0, -150)
+	w.OppositeBishopScale = 32
+	w.OppositeBishopPieceScale = 96
+	w.RookEndingScale = 96
 
 	w.PawnValue = S(104, 205)
 	w.KnightValue = S(408, 625)
@@ -2,18 +2,50 @@ package main
 
 import (
 	"bufio"
+	"context"
+	"encoding/json"
 	"flag"
 	"fmt"
+	stdio "io"
 	"log"
 	"os"
+	"os/signal"
 	"runtime/pprof"
 	"strings"
+	"time"
 
+	"github.com/AdamGriffiths31/ChessEngine/data"
+	"github.com/AdamGriffiths31/ChessEngine/engine"
+	"github.com/AdamGriffiths31/ChessEngine/epd"
+	"github.com/AdamGriffiths31/ChessEngine/eval"
+	custom "github.com/AdamGriffiths31/ChessEngine/eval/custom"
+	"github.com/AdamGriffiths31/ChessEngine/io"
+	"github.com/AdamGriffiths31/ChessEngine/match"
 	"github.com/AdamGriffiths31/ChessEngine/search"
+	"github.com/AdamGriffiths31/ChessEngine/sts"
 	"github.com/AdamGriffiths31/ChessEngine/uci"
+	"github.com/AdamGriffiths31/ChessEngine/util"
 )
 
 var cpuprofile = flag.String("cpuprofile", "", "write cpu profile to file")
+var evalTrace = flag.String("evaltrace", "", "print the evaluation breakdown for the given fen and exit")
+var buildBook = flag.String("buildbook", "", "build a polyglot book from the given pgn file and exit")
+var bookOut = flag.String("bookout", "book.bin", "file the built book is written to")
+var bookPly = flag.Int("bookply", 20, "number of plies of each game added to the built book")
+var bookMin = flag.Int("bookmin", 1, "number of games a move must be played in to be added to the built book")
+var bookSeed = flag.Int64("bookseed", 0, "seed for choosing between book moves so games can be repeated, zero seeds from the clock")
+var matchParams = flag.String("match", "", "play the default search params against the given json params and exit")
+var matchTime = flag.Int("matchtime", 100, "milliseconds per move in a match")
+var profile = flag.String("profile", "", "search the given fen and print the nodes and time taken, then exit")
+var profileDepth = flag.Int("profiledepth", 10, "depth the profiled fen is searched to, zero searches until stopped")
+var profileTime = flag.Int("profiletime", 0, "milliseconds the profiled fen is searched for, zero has no limit")
+var details = flag.Bool("details", false, "print the nodes and time of each depth of the profiled search")
+var stsFile = flag.String("sts", "", "score the engine on the given sts epd file and exit")
+var stsTime = flag.Int("ststime", 1000, "milliseconds each sts position is searched for")
+var stsWorkers = flag.Int("stsworkers", 1, "sts positions searched at once")
+var stsOut = flag.String("stsout", "", "file the sts results are written to, as csv if it ends in .csv and json otherwise")
+var benchDepth = flag.Int("bench", 0, "search the bench positions to the given depth and exit")
+var benchOut = flag.String("benchout", "", "file the bench results are written to, as csv if it ends in .csv and json otherwise")
 
 func main() {
 	flag.Parse()
@@ -26,6 +58,60 @@ func main() {
 		defer pprof.StopCPUProfile()
 	}
 
+	if *bookSeed != 0 {
+		search.SetBookSeed(*bookSeed)
+	}
+
+	if *evalTrace != "" {
+		game := engine.ParseFen(*evalTrace)
+		fmt.Print(custom.NewEvaluationService().EvaluateTrace(game.Position()))
+		return
+	}
+
+	if *buildBook != "" {
+		if err := writeBook(*buildBook, *bookOut, *bookPly, *bookMin); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
+	if *profile != "" {
+		runProfile(*profile, *profileDepth, time.Duration(*profileTime)*time.Millisecond, *details)
+		return
+	}
+
+	if *stsFile != "" {
+		if err := runSTS(*stsFile, time.Duration(*stsTime)*time.Millisecond, *stsWorkers, *stsOut); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
+	if *benchDepth > 0 {
+		r := uci.Bench(*benchDepth, search.Params{})
+		fmt.Printf("%d nodes in %v, %d nodes/second\n", r.Nodes, r.Time, r.NPS)
+		if *benchOut != "" {
+			if err := writeResult(*benchOut, r); err != nil {
+				log.Fatal(err)
+			}
+		}
+		return
+	}
+
+	if *matchParams != "" {
+		var params search.Params
+		if err := json.Unmarshal([]byte(*matchParams), &params); err != nil {
+			log.Fatal(err)
+		}
+		opts := match.Options{
+			Openings: match.DefaultOpenings,
+			MoveTime: time.Duration(*matchTime) * time.Millisecond,
+			Logger:   log.New(os.Stdout, "", 0),
+		}
+		fmt.Println(match.Run(search.Params{}, params, opts))
+		return
+	}
+
 	reader := bufio.NewReader(os.Stdin)
 	for {
 		input, err := reader.ReadString('\n')
@@ -49,3 +135,110 @@ func main() {
 		}
 	}
 }
+
+// runProfile searches the fen with a single thread and prints the best move
+// and totals, with details each completed depth is printed too. Ctrl-C stops
+// the search and what it found so far is printed
+func runProfile(fen string, depth int, moveTime time.Duration, details bool) {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	if moveTime > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, moveTime)
+		defer cancel()
+	}
+	if depth <= 0 {
+		depth = data.MaxDepth
+	}
+
+	h := search.NewEngineHolder(1, eval.Get("custom"))
+	h.UseBook = false
+	game := engine.ParseFen(fen)
+
+	start := util.GetTimeMs()
+	move := h.Hint(ctx, game.Position(), depth)
+	e := h.Engines[0]
+
+	if details {
+		for _, d := range e.DepthBreakdown {
+			fmt.Printf("depth %2d nodes %10d qnodes %10d time %6dms move %v score %v\n",
+				d.Depth, d.Nodes, d.QNodes, d.Time, io.PrintMove(d.Move), d.Score)
+		}
+	}
+	fmt.Printf("bestmove %v score %v depth %d stopped on %v\n", io.PrintMove(move.Move), e.UCIScore(move.Score), move.Depth, h.Termination)
+	fmt.Printf("nodes %d qnodes %d time %dms\n", e.NodesVisited, e.QNodesVisited, util.GetTimeMs()-start)
+	if hits, probes := e.EvalCacheStats(); probes > 0 {
+		fmt.Printf("eval cache hits %d of %d (%.1f%%)\n", hits, probes, float64(hits)*100/float64(probes))
+	}
+}
+
+// runSTS scores the engine on the positions in the epd file, printing each
+// position's result and then the total. The results are written to out too
+// unless it is empty
+func runSTS(path string, moveTime time.Duration, workers int, out string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	positions, err := epd.ReadEPD(f)
+	if err != nil {
+		return err
+	}
+	opts := sts.Options{MoveTime: moveTime, Log: os.Stdout}
+	result := sts.ScoreParallel(context.Background(), positions, opts, workers)
+	fmt.Println(result)
+	if out == "" {
+		return nil
+	}
+	return writeResult(out, result)
+}
+
+// exportable is a result that can be written as csv or json
+type exportable interface {
+	WriteCSV(w stdio.Writer) error
+	WriteJSON(w stdio.Writer) error
+}
+
+// writeResult writes r to path, as csv if the path ends in .csv and json
+// otherwise
+func writeResult(path string, r exportable) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if strings.HasSuffix(path, ".csv") {
+		err = r.WriteCSV(f)
+	} else {
+		err = r.WriteJSON(f)
+	}
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// writeBook builds a book from the pgn file and writes it to out
+func writeBook(pgnFile, out string, maxPly, minCount int) error {
+	in, err := os.Open(pgnFile)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	book, err := search.BuildBookFromPGN(in, maxPly, minCount)
+	if err != nil {
+		return err
+	}
+
+	f, err := os.Create(out)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	if _, err := book.WriteTo(f); err != nil {
+		return err
+	}
+	fmt.Printf("wrote %d entries to %v\n", len(book.Entries), out)
+	return f.Close()
+}
@@ -0,0 +1,199 @@
+package match
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math"
+	"time"
+
+	"github.com/AdamGriffiths31/ChessEngine/data"
+	"github.com/AdamGriffiths31/ChessEngine/engine"
+	"github.com/AdamGriffiths31/ChessEngine/eval"
+	"github.com/AdamGriffiths31/ChessEngine/search"
+)
+
+// defaultMaxPlies is the length a game is adjudicated a draw at when
+// Options.MaxPlies is not set
+const defaultMaxPlies = 300
+
+// DefaultOpenings are balanced positions a few moves into common openings
+var DefaultOpenings = []string{
+	"rnbqkbnr/pppp1ppp/8/4p3/4P3/8/PPPP1PPP/RNBQKBNR w KQkq - 0 2",
+	"rnbqkbnr/pp1ppppp/8/2p5/4P3/8/PPPP1PPP/RNBQKBNR w KQkq - 0 2",
+	"rnbqkbnr/pppp1ppp/4p3/8/4P3/8/PPPP1PPP/RNBQKBNR w KQkq - 0 2",
+	"rnbqkbnr/pp1ppppp/2p5/8/4P3/8/PPPP1PPP/RNBQKBNR w KQkq - 0 2",
+	"rnbqkbnr/ppp1pppp/8/3p4/3P4/8/PPP1PPPP/RNBQKBNR w KQkq - 0 2",
+	"rnbqkb1r/pppppppp/5n2/8/3P4/8/PPP1PPPP/RNBQKBNR w KQkq - 1 2",
+	"rnbqkbnr/pppppppp/8/8/2P5/8/PP1PPPPP/RNBQKBNR b KQkq - 0 1",
+	"rnbqkbnr/pppppppp/8/8/8/5N2/PPPPPPPP/RNBQKB1R b KQkq - 1 1",
+}
+
+// Options is how the games in a match are played
+type Options struct {
+	// Openings are the fens the games start from, each is played twice with
+	// the colours swapped, nil uses DefaultOpenings
+	Openings []string
+	// Depth is the depth searched for each move, zero searches until
+	// MoveTime runs out so at least one of them must be set
+	Depth int
+	// MoveTime is the time allowed for each move, zero has no limit
+	MoveTime time.Duration
+	// MaxPlies is the length a game is adjudicated a draw at, zero uses 300
+	MaxPlies int
+	// Logger receives a line with the outcome of each game, nil logs nothing
+	Logger *log.Logger
+}
+
+// Result is the score of the first configuration against the second
+type Result struct {
+	Wins   int
+	Draws  int
+	Losses int
+}
+
+// Games returns the number of games played
+func (r Result) Games() int {
+	return r.Wins + r.Draws + r.Losses
+}
+
+// Score returns the fraction of the points the first configuration scored
+func (r Result) Score() float64 {
+	if r.Games() == 0 {
+		return 0
+	}
+	return (float64(r.Wins) + float64(r.Draws)/2) / float64(r.Games())
+}
+
+// scoreBound keeps the ends of the confidence interval inside (0, 1), where
+// they have a finite Elo difference
+const scoreBound = 0.001
+
+// Elo returns the approximate Elo difference of the first configuration over
+// the second and the margin of its 95% confidence interval. A score of 0 or
+// 100% has an infinite difference and no margin
+func (r Result) Elo() (float64, float64) {
+	n := float64(r.Games())
+	if n == 0 {
+		return 0, 0
+	}
+	score := r.Score()
+	if score == 0 || score == 1 {
+		return eloFromScore(score), 0
+	}
+	variance := (float64(r.Wins)*math.Pow(1-score, 2) +
+		float64(r.Draws)*math.Pow(0.5-score, 2) +
+		float64(r.Losses)*math.Pow(score, 2)) / n
+	margin := 1.96 * math.Sqrt(variance/n)
+	lower := math.Max(score-margin, scoreBound)
+	upper := math.Min(score+margin, 1-scoreBound)
+	return eloFromScore(score), (eloFromScore(upper) - eloFromScore(lower)) / 2
+}
+
+func (r Result) String() string {
+	if score := r.Score(); r.Games() > 0 && (score == 0 || score == 1) {
+		return fmt.Sprintf("+%d =%d -%d scored %.0f%%, Elo unbounded", r.Wins, r.Draws, r.Losses, 100*score)
+	}
+	elo, margin := r.Elo()
+	return fmt.Sprintf("+%d =%d -%d Elo %.1f +/- %.1f", r.Wins, r.Draws, r.Losses, elo, margin)
+}
+
+// eloFromScore converts the fraction of points scored to an Elo difference
+func eloFromScore(score float64) float64 {
+	return -400 * math.Log10(1/score-1)
+}
+
+// Run plays a and b against each other from every opening with both colours
+func Run(a, b search.Params, opts Options) Result {
+	var result Result
+	players := [2]*search.EngineHolder{newPlayer(a), newPlayer(b)}
+	openings := opts.Openings
+	if len(openings) == 0 {
+		openings = DefaultOpenings
+	}
+	for _, fen := range openings {
+		for first := 0; first < 2; first++ {
+			game := engine.ParseFen(fen)
+			white := players[first]
+			black := players[first^1]
+			if game.Position().Side == data.Black {
+				white, black = black, white
+			}
+
+			outcome := Play(&game, white, black, opts)
+			winner := -1
+			if outcome == engine.OutcomeCheckmate {
+				winner = game.Position().Side ^ 1
+			}
+			switch {
+			case winner == -1:
+				result.Draws++
+			case (winner == data.White) == (white == players[0]):
+				result.Wins++
+			default:
+				result.Losses++
+			}
+			switch {
+			case opts.Logger == nil:
+			case outcome == engine.OutcomeNone:
+				opts.Logger.Printf("game %d: draw by adjudication\n", result.Games())
+			default:
+				opts.Logger.Printf("game %d: %v\n", result.Games(), outcome)
+			}
+		}
+	}
+	return result
+}
+
+// Play plays the game to the end with the engines choosing the moves for
+// their colour, a game reaching the ply limit is scored as a draw
+func Play(game *engine.Game, white, black *search.EngineHolder, opts Options) engine.Outcome {
+	maxPlies := opts.MaxPlies
+	if maxPlies == 0 {
+		maxPlies = defaultMaxPlies
+	}
+	for ply := 0; ply < maxPlies; ply++ {
+		p := game.Position()
+		if outcome := p.Outcome(); outcome != engine.OutcomeNone {
+			return outcome
+		}
+
+		player := white
+		if p.Side == data.Black {
+			player = black
+		}
+		move := bestMove(player, p, opts)
+		if !game.PlayMove(move) {
+			panic(fmt.Errorf("match: illegal move %v in %v", move, p.ToFEN()))
+		}
+	}
+	return engine.OutcomeNone
+}
+
+// bestMove searches the position within the options' limits, falling back
+// to the first legal move if no depth was completed
+func bestMove(h *search.EngineHolder, p *engine.Position, opts Options) int {
+	ctx := context.Background()
+	if opts.MoveTime > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, opts.MoveTime)
+		defer cancel()
+	}
+	depth := opts.Depth
+	if depth == 0 {
+		depth = data.MaxDepth
+	}
+
+	move := h.Hint(ctx, p, depth).Move
+	if move == data.NoMove {
+		move = p.LegalMoves()[0]
+	}
+	return move
+}
+
+// newPlayer returns an engine using the params
+func newPlayer(params search.Params) *search.EngineHolder {
+	h := search.NewEngineHolder(1, eval.Get("custom"))
+	h.Params = params
+	return h
+}
@@ -0,0 +1,71 @@
+package match
+
+import (
+	"bytes"
+	"log"
+	"math"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/AdamGriffiths31/ChessEngine/search"
+)
+
+func TestRunRecordsResults(t *testing.T) {
+	opts := Options{
+		Openings: []string{
+			"6k1/5ppp/8/8/8/8/8/R5K1 w - - 0 1",
+			"4k3/8/8/8/8/8/8/4KB2 w - - 0 1",
+			"4k3/8/8/8/8/8/r7/4K3 w - - 0 1",
+		},
+		Depth:    3,
+		MoveTime: time.Second,
+		MaxPlies: 6,
+	}
+	var logged bytes.Buffer
+	opts.Logger = log.New(&logged, "", 0)
+	result := Run(search.Params{}, search.Params{DisableQuiescence: true}, opts)
+	if result.Games() != 6 {
+		t.Fatalf("Expected 6 games but got %v", result.Games())
+	}
+	if lines := strings.Split(strings.TrimSpace(logged.String()), "\n"); len(lines) != 6 || !strings.HasPrefix(lines[5], "game 6: ") {
+		t.Errorf("Expected a line for each game but got %q", logged.String())
+	}
+	if result.Wins != 1 || result.Losses != 1 || result.Draws != 4 {
+		t.Errorf("Expected +1 =4 -1 but got %v", result)
+	}
+}
+
+func TestElo(t *testing.T) {
+	tests := []struct {
+		result Result
+		elo    float64
+	}{
+		{Result{Wins: 10, Draws: 0, Losses: 10}, 0},
+		{Result{Wins: 3, Draws: 0, Losses: 1}, 190.8},
+		{Result{Wins: 1, Draws: 2, Losses: 3}, -120.4},
+	}
+	for _, test := range tests {
+		elo, margin := test.result.Elo()
+		if math.Abs(elo-test.elo) > 0.1 {
+			t.Errorf("%v: expected Elo %v but got %v", test.result, test.elo, elo)
+		}
+		if !(margin > 0) || math.IsInf(margin, 0) {
+			t.Errorf("%v: expected a positive margin but got %v", test.result, margin)
+		}
+		if s := test.result.String(); strings.Contains(s, "NaN") || strings.Contains(s, "Inf") {
+			t.Errorf("Expected a finite result but got %v", s)
+		}
+	}
+
+	perfect := Result{Wins: 3}
+	if elo, margin := perfect.Elo(); !math.IsInf(elo, 1) || margin != 0 {
+		t.Errorf("%v: expected an infinite Elo with no margin but got %v +/- %v", perfect, elo, margin)
+	}
+	if s := perfect.String(); s != "+3 =0 -0 scored 100%, Elo unbounded" {
+		t.Errorf("Expected the perfect score to be reported but got %v", s)
+	}
+	if s := (Result{Losses: 2}).String(); s != "+0 =0 -2 scored 0%, Elo unbounded" {
+		t.Errorf("Expected the zero score to be reported but got %v", s)
+	}
+}
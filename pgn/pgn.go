@@ -0,0 +1,179 @@
+package pgn
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// lineLength is the longest movetext line written
+const lineLength = 79
+
+// Game is a game read from a PGN file, Moves are in SAN
+type Game struct {
+	Tags   map[string]string
+	Moves  []string
+	Result string
+}
+
+// ReadGames reads every game in r, comments, variations and annotations are
+// skipped
+func ReadGames(r io.Reader) ([]Game, error) {
+	var games []Game
+	game := newGame()
+	flush := func() {
+		if len(game.Moves) > 0 || len(game.Tags) > 0 {
+			games = append(games, game)
+		}
+		game = newGame()
+	}
+
+	br := bufio.NewReader(r)
+	variations := 0
+	for {
+		ch, _, err := br.ReadRune()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		switch {
+		case ch == '[' && variations == 0:
+			tag, err := br.ReadString(']')
+			if err != nil {
+				return nil, fmt.Errorf("pgn: unterminated tag %q", tag)
+			}
+			if len(game.Moves) > 0 {
+				flush()
+			}
+			name, value, _ := strings.Cut(strings.TrimSuffix(tag, "]"), " ")
+			game.Tags[name] = strings.Trim(strings.TrimSpace(value), "\"")
+		case ch == '{':
+			if _, err := br.ReadString('}'); err != nil {
+				return nil, fmt.Errorf("pgn: unterminated comment")
+			}
+		case ch == ';':
+			if _, err := br.ReadString('\n'); err != nil && err != io.EOF {
+				return nil, err
+			}
+		case ch == '(':
+			variations++
+		case ch == ')':
+			variations--
+		case strings.ContainsRune(" \t\r\n", ch):
+		default:
+			br.UnreadRune()
+			token := readToken(br)
+			if token == "" {
+				return nil, fmt.Errorf("pgn: unexpected %q", ch)
+			}
+			if variations > 0 || token[0] == '$' {
+				continue
+			}
+			if isResult(token) {
+				game.Result = token
+				flush()
+				continue
+			}
+			if move := strings.TrimLeft(token, "0123456789."); move != "" {
+				game.Moves = append(game.Moves, move)
+			}
+		}
+	}
+	flush()
+	return games, nil
+}
+
+// WriteGame writes the game as PGN with the tags in name order, the moves are
+// numbered from the FEN tag if there is one
+func WriteGame(w io.Writer, g Game) error {
+	names := make([]string, 0, len(g.Tags))
+	for name := range g.Tags {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var sb strings.Builder
+	for _, name := range names {
+		fmt.Fprintf(&sb, "[%s \"%s\"]\n", name, g.Tags[name])
+	}
+	if len(names) > 0 {
+		sb.WriteString("\n")
+	}
+
+	number, black := 1, false
+	if fields := strings.Fields(g.Tags["FEN"]); len(fields) == 6 {
+		black = fields[1] == "b"
+		if n, err := strconv.Atoi(fields[5]); err == nil && n > 0 {
+			number = n
+		}
+	}
+
+	result := g.Result
+	if result == "" {
+		result = "*"
+	}
+	line := 0
+	write := func(token string) {
+		if line > 0 && line+1+len(token) > lineLength {
+			sb.WriteString("\n")
+			line = 0
+		} else if line > 0 {
+			sb.WriteString(" ")
+			line++
+		}
+		sb.WriteString(token)
+		line += len(token)
+	}
+	for i, move := range g.Moves {
+		switch {
+		case !black:
+			write(fmt.Sprintf("%d. %s", number, move))
+		case i == 0:
+			write(fmt.Sprintf("%d... %s", number, move))
+		default:
+			write(move)
+		}
+		if black {
+			number++
+		}
+		black = !black
+	}
+	write(result)
+	sb.WriteString("\n\n")
+
+	_, err := io.WriteString(w, sb.String())
+	return err
+}
+
+// newGame returns an empty game
+func newGame() Game {
+	return Game{Tags: map[string]string{}}
+}
+
+// readToken reads up to the next space or PGN delimiter
+func readToken(br *bufio.Reader) string {
+	var sb strings.Builder
+	for {
+		ch, _, err := br.ReadRune()
+		if err != nil {
+			break
+		}
+		if strings.ContainsRune(" \t\r\n{}();[", ch) {
+			br.UnreadRune()
+			break
+		}
+		sb.WriteRune(ch)
+	}
+	return sb.String()
+}
+
+// isResult checks if the token is a game termination marker
+func isResult(token string) bool {
+	return token == "1-0" || token == "0-1" || token == "1/2-1/2" || token == "*"
+}
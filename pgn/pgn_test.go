@@ -0,0 +1,99 @@
+package pgn
+
+import (
+	"strings"
+	"testing"
+)
+
+const twoGames = `[Event "Casual Game"]
+[White "A"]
+[Black "B"]
+[Result "1-0"]
+
+1. e4 {best by test} e5 2. Nf3 (2. f4 exf4) Nc6 $1 3. Bb5 a6 ; Ruy Lopez
+4. Ba4 1-0
+
+[Event "Second"]
+[FEN "4k3/8/8/8/8/8/8/4K2R w K - 0 1"]
+
+1.O-O Kd7 2.Rd1+ *
+`
+
+func TestReadGames(t *testing.T) {
+	games, err := ReadGames(strings.NewReader(twoGames))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(games) != 2 {
+		t.Fatalf("Expected 2 games but got %v", len(games))
+	}
+
+	tests := []struct {
+		event  string
+		moves  string
+		result string
+	}{
+		{"Casual Game", "e4 e5 Nf3 Nc6 Bb5 a6 Ba4", "1-0"},
+		{"Second", "O-O Kd7 Rd1+", "*"},
+	}
+	for i, test := range tests {
+		if games[i].Tags["Event"] != test.event {
+			t.Errorf("Expected event %v but got %v", test.event, games[i].Tags["Event"])
+		}
+		if moves := strings.Join(games[i].Moves, " "); moves != test.moves {
+			t.Errorf("Expected moves %v but got %v", test.moves, moves)
+		}
+		if games[i].Result != test.result {
+			t.Errorf("Expected result %v but got %v", test.result, games[i].Result)
+		}
+	}
+	if games[1].Tags["FEN"] != "4k3/8/8/8/8/8/8/4K2R w K - 0 1" {
+		t.Errorf("Unexpected FEN tag %v", games[1].Tags["FEN"])
+	}
+}
+
+func TestReadGamesRejectsStrayBrace(t *testing.T) {
+	if _, err := ReadGames(strings.NewReader("1. e4 } e5 *")); err == nil {
+		t.Errorf("Expected an error for a stray brace")
+	}
+}
+
+func TestWriteGame(t *testing.T) {
+	games, err := ReadGames(strings.NewReader(twoGames))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var sb strings.Builder
+	for _, g := range games {
+		if err := WriteGame(&sb, g); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	want := `[Black "B"]
+[Event "Casual Game"]
+[Result "1-0"]
+[White "A"]
+
+1. e4 e5 2. Nf3 Nc6 3. Bb5 a6 4. Ba4 1-0
+
+[Event "Second"]
+[FEN "4k3/8/8/8/8/8/8/4K2R w K - 0 1"]
+
+1. O-O Kd7 2. Rd1+ *
+
+`
+	if sb.String() != want {
+		t.Errorf("Expected\n%v\nbut got\n%v", want, sb.String())
+	}
+
+	again, err := ReadGames(strings.NewReader(sb.String()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := range games {
+		if strings.Join(again[i].Moves, " ") != strings.Join(games[i].Moves, " ") || again[i].Result != games[i].Result {
+			t.Errorf("Expected %v but got %v", games[i], again[i])
+		}
+	}
+}
@@ -0,0 +1,104 @@
+package search
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/AdamGriffiths31/ChessEngine/data"
+	"github.com/AdamGriffiths31/ChessEngine/engine"
+)
+
+// WeightedMove is a book move and how often it is played compared to the
+// other moves for the position
+type WeightedMove struct {
+	Move   int
+	Weight int
+}
+
+// OpeningBook is a source of book moves
+type OpeningBook interface {
+	// Probe returns the legal book moves for the position
+	Probe(p *engine.Position) []WeightedMove
+	// Len returns the number of moves in the book
+	Len() int
+}
+
+// Books are probed in order for a book move, the first with moves for the
+// position is used so earlier books take precedence
+var Books = []OpeningBook{PolyglotBook{}}
+
+// PolyglotBook probes the polyglot book loaded by LoadPolyBook
+type PolyglotBook struct{}
+
+func (PolyglotBook) Probe(p *engine.Position) []WeightedMove {
+	return getBookMoves(p)
+}
+
+func (PolyglotBook) Len() int {
+	return int(NumEntries)
+}
+
+// TextBook is a hand written book read from lines of "<fen> <move> <weight>"
+// with the move in coordinate notation, blank lines and lines starting with
+// # are skipped
+type TextBook struct {
+	moves map[uint64][]WeightedMove
+}
+
+// ReadTextBook reads a text book from r, returning an error naming the first
+// line that cannot be read or has an illegal move
+func ReadTextBook(r io.Reader) (*TextBook, error) {
+	book := &TextBook{moves: map[uint64][]WeightedMove{}}
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		fields := strings.Fields(text)
+		if len(fields) < 6 {
+			return nil, fmt.Errorf("line %d: expected a fen, a move and a weight", line)
+		}
+		weight, err := strconv.Atoi(fields[len(fields)-1])
+		if err != nil || weight < 0 {
+			return nil, fmt.Errorf("line %d: bad weight %v", line, fields[len(fields)-1])
+		}
+		game := engine.ParseFen(strings.Join(fields[:len(fields)-2], " "))
+		p := game.Position()
+		move := p.ParseMove([]byte(fields[len(fields)-2]))
+		if move == data.NoMove || !isLegalMove(p, move) {
+			return nil, fmt.Errorf("line %d: illegal move %v", line, fields[len(fields)-2])
+		}
+		key := PolyKeyFromBoard(p)
+		book.moves[key] = append(book.moves[key], WeightedMove{Move: move, Weight: weight})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return book, nil
+}
+
+func (b *TextBook) Probe(p *engine.Position) []WeightedMove {
+	return b.moves[PolyKeyFromBoard(p)]
+}
+
+func (b *TextBook) Len() int {
+	n := 0
+	for _, moves := range b.moves {
+		n += len(moves)
+	}
+	return n
+}
+
+// hasBookMoves checks if any of the Books has moves
+func hasBookMoves() bool {
+	for _, book := range Books {
+		if book.Len() > 0 {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,97 @@
+package search
+
+import (
+	"bytes"
+	stdio "io"
+	"log"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/AdamGriffiths31/ChessEngine/data"
+	"github.com/AdamGriffiths31/ChessEngine/engine"
+	"github.com/AdamGriffiths31/ChessEngine/eval"
+	"github.com/AdamGriffiths31/ChessEngine/io"
+)
+
+const textBook = `# hand written lines
+rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1 d2d4 3
+rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1 c2c4 1
+
+rnbqkbnr/pppppppp/8/8/3P4/8/PPP1PPPP/RNBQKBNR b KQkq d3 0 1 g8f6 1
+`
+
+func TestReadTextBook(t *testing.T) {
+	book, err := ReadTextBook(strings.NewReader(textBook))
+	if err != nil {
+		t.Fatal(err)
+	}
+	game := engine.ParseFen(data.StartFEN)
+	moves := book.Probe(game.Position())
+	if len(moves) != 2 || io.PrintMove(moves[0].Move) != "d2d4" || moves[0].Weight != 3 ||
+		io.PrintMove(moves[1].Move) != "c2c4" || moves[1].Weight != 1 {
+		t.Errorf("Expected d2d4 3 and c2c4 1 but got %v", moves)
+	}
+
+	bad := []string{
+		data.StartFEN + " e2e5 1",
+		data.StartFEN + " e2e4 x",
+		"8/8/8/8 e2e4 1",
+	}
+	for i, line := range bad {
+		if _, err := ReadTextBook(strings.NewReader("\n" + line)); err == nil || !strings.HasPrefix(err.Error(), "line 2:") {
+			t.Errorf("%v: expected an error on line 2 but got %v", i, err)
+		}
+	}
+}
+
+func TestTextBookTakesPrecedence(t *testing.T) {
+	defer LoadPolyBook(&bytes.Buffer{})
+	loadBook(t, 16, 1)
+	book, err := ReadTextBook(strings.NewReader(textBook))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func(books []OpeningBook) { Books = books }(Books)
+	Books = []OpeningBook{book, PolyglotBook{}}
+
+	tests := []struct {
+		fen  string
+		want string
+	}{
+		{data.StartFEN, "d2d4 c2c4"},
+		{"rnbqkbnr/pppppppp/8/8/4P3/8/PPPP1PPP/RNBQKBNR b KQkq e3 0 1", "c7c5 e7e5"},
+	}
+	for _, test := range tests {
+		game := engine.ParseFen(test.fen)
+		move := io.PrintMove(GetBookMove(game.Position()))
+		if !strings.Contains(test.want, move) {
+			t.Errorf("%v: expected one of %v but got %v", test.fen, test.want, move)
+		}
+	}
+}
+
+func TestTextBookTurnsBookOn(t *testing.T) {
+	book, err := ReadTextBook(strings.NewReader(textBook))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if book.Len() != 3 {
+		t.Errorf("Expected 3 moves but got %v", book.Len())
+	}
+	defer func(books []OpeningBook) { Books = books }(Books)
+	Books = []OpeningBook{book, PolyglotBook{}}
+
+	h := NewEngineHolder(1, eval.Get("custom"))
+	h.Logger = log.New(stdio.Discard, "", 0)
+	initPolyBookFile(h, filepath.Join(t.TempDir(), "missing.bin"))
+	if !h.UseBook {
+		t.Errorf("Expected the text book to turn the book on without a polyglot book")
+	}
+
+	Books = []OpeningBook{&TextBook{}, PolyglotBook{}}
+	initPolyBookFile(h, filepath.Join(t.TempDir(), "missing.bin"))
+	if h.UseBook {
+		t.Errorf("Expected the book to be off with only empty books")
+	}
+}
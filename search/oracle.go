@@ -0,0 +1,71 @@
+package search
+
+import (
+	"github.com/AdamGriffiths31/ChessEngine/data"
+	"github.com/AdamGriffiths31/ChessEngine/engine"
+)
+
+// EndgameOracle knows the result of some endgames without searching them
+type EndgameOracle interface {
+	// Probe returns the score of the position for the side to move and
+	// true, or false if the result is not known
+	Probe(p *engine.Position) (int, bool)
+}
+
+// oracleMaxPieces is the most pieces, kings included, a position can have
+// for the oracle to be asked about it
+const oracleMaxPieces = 5
+
+// oracleWinScore is the score of a win known to the oracle, it is above any
+// evaluation and below the mate scores so a real mate is still preferred
+const oracleWinScore = 5000
+
+// KPKOracle knows two results in king and pawn against king: a rook pawn with
+// the defending king in front of it on its file is a draw, and a pawn the
+// defending king cannot catch before it promotes wins
+type KPKOracle struct{}
+
+func (KPKOracle) Probe(p *engine.Position) (int, bool) {
+	b := &p.Board
+	pawns := b.WhitePawn | b.BlackPawn
+	if b.CountBits(b.Pieces) != 3 || b.CountBits(pawns) != 1 {
+		return 0, false
+	}
+
+	// squares are flipped for a black pawn so the pawn always moves up
+	strong, strongKing, weakKing := data.White, b.WhiteKing, b.BlackKing
+	flip := 0
+	if b.BlackPawn != 0 {
+		strong, strongKing, weakKing = data.Black, b.BlackKing, b.WhiteKing
+		flip = 56
+	}
+	pawn := engine.FirstSquare(pawns) ^ flip
+	strongSq := engine.FirstSquare(strongKing) ^ flip
+	weakSq := engine.FirstSquare(weakKing) ^ flip
+	file, rank := pawn%8, pawn/8
+
+	if (file == 0 || file == 7) && weakSq%8 == file && weakSq/8 > rank {
+		return 0, true
+	}
+
+	// the rule of the square, the king on the pawn's path would block it
+	if strongSq%8 == file && strongSq/8 > rank {
+		return 0, false
+	}
+	moves := 7 - rank
+	if rank == 1 {
+		moves--
+	}
+	distance := engine.KingDistance(weakSq, 56+file)
+	if p.Side != strong {
+		distance--
+	}
+	if distance <= moves {
+		return 0, false
+	}
+	score := oracleWinScore + 10*rank
+	if p.Side != strong {
+		score = -score
+	}
+	return score, true
+}
@@ -0,0 +1,62 @@
+package search
+
+import (
+	"testing"
+
+	"github.com/AdamGriffiths31/ChessEngine/data"
+	"github.com/AdamGriffiths31/ChessEngine/engine"
+	"github.com/AdamGriffiths31/ChessEngine/eval"
+	"github.com/AdamGriffiths31/ChessEngine/util"
+)
+
+func TestKPKOracle(t *testing.T) {
+	tests := []struct {
+		fen   string
+		score int
+		found bool
+	}{
+		{"k7/8/8/8/8/8/P7/7K w - - 0 1", 0, true},
+		{"8/8/8/8/p7/8/8/K6k b - - 0 1", 0, true},
+		{"7k/8/8/8/P7/8/8/K7 w - - 0 1", oracleWinScore + 30, true},
+		{"7k/8/8/8/P7/8/8/K7 b - - 0 1", -oracleWinScore - 30, true},
+		{"k7/8/8/8/p7/8/8/7K b - - 0 1", oracleWinScore + 40, true},
+		{"8/8/8/3k4/P7/8/8/K7 w - - 0 1", 0, false},
+		{"7k/K7/8/8/P7/8/8/8 w - - 0 1", 0, false},
+		{"7k/8/8/8/P7/8/8/KR6 w - - 0 1", 0, false},
+	}
+	for _, test := range tests {
+		game := engine.ParseFen(test.fen)
+		score, found := KPKOracle{}.Probe(game.Position())
+		if score != test.score || found != test.found {
+			t.Errorf("%v: expected %v %v but got %v %v", test.fen, test.score, test.found, score, found)
+		}
+	}
+}
+
+// fixedOracle scores every position it is asked about the same
+type fixedOracle struct {
+	score  int
+	probes int
+}
+
+func (o *fixedOracle) Probe(p *engine.Position) (int, bool) {
+	o.probes++
+	return o.score, true
+}
+
+func TestSearchUsesOracle(t *testing.T) {
+	oracle := &fixedOracle{score: 777}
+	h := NewEngineHolder(1, eval.Get("custom"))
+	h.UseBook = false
+	h.Oracle = oracle
+	game := engine.ParseFen("8/8/4k3/8/8/8/8/R3K3 w - - 0 1")
+	h.Engines[0].Position = game.Position()
+	h.Search(&data.SearchInfo{Depth: 6, StartTime: util.GetTimeMs()})
+
+	if h.Move.Score != -777 {
+		t.Errorf("Expected the oracle's score of each reply to give -777 but got %v", h.Move.Score)
+	}
+	if oracle.probes == 0 || h.Engines[0].OracleHits != oracle.probes {
+		t.Errorf("Expected %v oracle hits but got %v", oracle.probes, h.Engines[0].OracleHits)
+	}
+}
@@ -1,67 +1,144 @@
 package search
 
 import (
+	"bytes"
 	"encoding/binary"
 	"fmt"
 	"io"
+	"log"
 	"math/rand"
 	"os"
+	"time"
 	"unsafe"
 
 	"github.com/AdamGriffiths31/ChessEngine/data"
 	"github.com/AdamGriffiths31/ChessEngine/engine"
 )
 
+// BookLogger logs book moves that are rejected as illegal in the position
+var BookLogger = log.New(os.Stderr, "book: ", 0)
+
+// bookRand chooses between the book moves for a position
+var bookRand = rand.New(rand.NewSource(time.Now().UnixNano()))
+
+// SetBookSeed seeds the choice between book moves so the same moves are
+// played again
+func SetBookSeed(seed int64) {
+	bookRand = rand.New(rand.NewSource(seed))
+}
+
+// InitPolyBook loads performance.bin from the working directory, turning the
+// book on if any of the Books has moves
 func InitPolyBook(h *EngineHolder) {
-	h.UseBook = false
-	file, err := os.Open("performance.bin")
+	initPolyBookFile(h, "performance.bin")
+}
+
+// initPolyBookFile loads the polyglot book at path, a missing or unreadable
+// book is logged to the holder's logger and left empty. The book is turned
+// on if any of the Books has moves, so a text book works without it
+func initPolyBookFile(h *EngineHolder, path string) {
+	if err := loadPolyBookFile(path); err != nil {
+		h.Logger.Printf("no opening book: %v\n", err)
+		PolyEntry, NumEntries = nil, 0
+	}
+	h.UseBook = hasBookMoves()
+}
+
+// loadPolyBookFile loads the polyglot book at path
+func loadPolyBookFile(path string) error {
+	file, err := os.Open(path)
 	if err != nil {
-		fmt.Println(os.Getwd())
-		h.UseBook = false
-		return
+		return err
 	}
 	defer file.Close()
 
-	fi, err := os.Stat("performance.bin")
+	if err := LoadPolyBook(file); err != nil {
+		return fmt.Errorf("read error %v", err)
+	}
+	return nil
+}
+
+// LoadPolyBook replaces the book entries with the polyglot book in r
+func LoadPolyBook(r io.Reader) error {
+	b, err := io.ReadAll(r)
 	if err != nil {
-		panic(fmt.Errorf("InitPolyBook: size  error %v", err))
+		return err
 	}
-	size := fi.Size()
 
-	NumEntries = uint64(size) / uint64(unsafe.Sizeof(PolyBookEntry{}))
+	NumEntries = uint64(len(b)) / uint64(unsafe.Sizeof(PolyBookEntry{}))
 
 	PolyEntry = make([]PolyBookEntry, NumEntries)
 
-	reader := io.Reader(file)
-	_ = binary.Read(reader, binary.LittleEndian, &PolyEntry)
+	return binary.Read(bytes.NewReader(b), binary.LittleEndian, &PolyEntry)
+}
+
+// BookWeighted chooses between the book moves for a position in proportion
+// to their weights, without it each move is equally likely
+var BookWeighted = false
+
+// GetBookMove returns a move from the first of the Books with moves for the
+// position, or data.NoMove if none has any
+func GetBookMove(p *engine.Position) int {
+	for _, book := range Books {
+		if moves := book.Probe(p); len(moves) > 0 {
+			return chooseBookMove(moves)
+		}
+	}
+	return data.NoMove
+}
 
-	if NumEntries > 0 {
-		h.UseBook = true
+// chooseBookMove picks one of the moves at random
+func chooseBookMove(moves []WeightedMove) int {
+	if !BookWeighted {
+		return moves[bookRand.Intn(len(moves))].Move
+	}
+	total := 0
+	for _, m := range moves {
+		total += m.Weight
+	}
+	if total == 0 {
+		return moves[bookRand.Intn(len(moves))].Move
 	}
+	pick := bookRand.Intn(total)
+	for _, m := range moves {
+		if pick < m.Weight {
+			return m.Move
+		}
+		pick -= m.Weight
+	}
+	return moves[len(moves)-1].Move
 }
 
-func GetBookMove(p *engine.Position) int {
-	var bookMoves [32]int
+// getBookMoves returns up to 32 legal moves for the position from the
+// polyglot book
+func getBookMoves(p *engine.Position) []WeightedMove {
+	var bookMoves []WeightedMove
 	polyKey := PolyKeyFromBoard(p)
-	count := 0
 	for i := 0; i < int(NumEntries); i++ {
 		if polyKey == littleEndianToBigEndianUint64(PolyEntry[i].Key) {
 			move := littleEndianToBigEndianUint16(PolyEntry[i].Move)
 			tempMove := ConvertPolyMove(move, p)
-			if tempMove != data.NoMove {
-				bookMoves[count] = tempMove
-				count++
-				if count > 32 {
-					break
-				}
+			if tempMove == data.NoMove || !isLegalMove(p, tempMove) {
+				BookLogger.Printf("rejected move %04x for key %016x", move, polyKey)
+				continue
+			}
+			bookMoves = append(bookMoves, WeightedMove{Move: tempMove, Weight: int(littleEndianToBigEndianUint16(PolyEntry[i].Weight))})
+			if len(bookMoves) == 32 {
+				break
 			}
 		}
 	}
-	if count != 0 {
-		randMove := rand.Intn(count)
-		return bookMoves[randMove]
+	return bookMoves
+}
+
+// isLegalMove checks the move does not leave the side to move in check
+func isLegalMove(p *engine.Position, move int) bool {
+	isAllowed, enPas, castlePerm, fifty := p.MakeMove(move)
+	if !isAllowed {
+		return false
 	}
-	return data.NoMove
+	p.TakeMoveBack(move, enPas, castlePerm, fifty)
+	return true
 }
 
 func ConvertPolyMove(polyMove uint16, p *engine.Position) int {
@@ -87,9 +164,23 @@ func ConvertPolyMove(polyMove uint16, p *engine.Position) int {
 		move = fmt.Sprintf("%s%s%s%s", ff, fr, tf, tr)
 	}
 
+	// polyglot castles are stored as the king taking its own rook
+	if castle, ok := polyCastles[move]; ok && p.Board.PieceAt(int(polyMove>>6&63)) == kingOn[move[1]] {
+		move = castle
+	}
+
 	return p.ParseMove([]byte(move))
 }
 
+var polyCastles = map[string]string{
+	"e1h1": "e1g1",
+	"e1a1": "e1c1",
+	"e8h8": "e8g8",
+	"e8a8": "e8c8",
+}
+
+var kingOn = map[byte]int{'1': data.WK, '8': data.BK}
+
 func PolyKeyFromBoard(p *engine.Position) uint64 {
 	finalKey := uint64(0)
 	for sq := 0; sq < 64; sq++ {
@@ -139,15 +230,15 @@ func hasPawnForCapture(p *engine.Position) bool {
 	if p.Side == data.Black {
 		target = data.BP
 	}
-	if p.EnPassant != data.NoSquare {
+	if p.EnPassant != data.NoSquare && p.EnPassant != data.Empty {
 		if p.Side == data.White {
 			sqWithPawn = p.EnPassant - 10
 		} else {
 			sqWithPawn = p.EnPassant + 10
 		}
-		if p.Board.PieceAt(sqWithPawn+1) == target {
+		if p.Board.PieceAt(data.Square120ToSquare64[sqWithPawn+1]) == target {
 			return true
-		} else if p.Board.PieceAt(sqWithPawn-1) == target {
+		} else if p.Board.PieceAt(data.Square120ToSquare64[sqWithPawn-1]) == target {
 			return true
 		}
 	}
@@ -0,0 +1,114 @@
+package search
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+
+	"github.com/AdamGriffiths31/ChessEngine/data"
+	"github.com/AdamGriffiths31/ChessEngine/engine"
+	"github.com/AdamGriffiths31/ChessEngine/pgn"
+)
+
+// Book is an opening book sorted by key and then weight as polyglot expects
+type Book struct {
+	Entries []PolyBookEntry
+}
+
+// BuildBookFromPGN replays the games in r up to maxPly plies, weighting each
+// move by the number of games it was played in. Moves played in fewer than
+// minCount games are left out
+func BuildBookFromPGN(r io.Reader, maxPly, minCount int) (*Book, error) {
+	games, err := pgn.ReadGames(r)
+	if err != nil {
+		return nil, err
+	}
+
+	counts := map[PolyBookEntry]int{}
+	for i, g := range games {
+		fen := data.StartFEN
+		if tag, ok := g.Tags["FEN"]; ok {
+			fen = tag
+		}
+		game := engine.ParseFen(fen)
+		p := game.Position()
+		for ply, san := range g.Moves {
+			if ply >= maxPly {
+				break
+			}
+			move := p.ParseSAN(san)
+			if move == data.NoMove {
+				return nil, fmt.Errorf("game %d: illegal move %v at ply %d", i+1, san, ply+1)
+			}
+			counts[PolyBookEntry{Key: PolyKeyFromBoard(p), Move: polyMoveFromMove(move)}]++
+			p.MakeMove(move)
+			p.Play = 0
+			p.PositionHistory.RemovePositionHistory()
+		}
+	}
+
+	book := &Book{}
+	for entry, count := range counts {
+		if count < minCount {
+			continue
+		}
+		entry.Weight = uint16(math.Min(float64(count), math.MaxUint16))
+		book.Entries = append(book.Entries, entry)
+	}
+	sort.Slice(book.Entries, func(i, j int) bool {
+		a, b := book.Entries[i], book.Entries[j]
+		if a.Key != b.Key {
+			return a.Key < b.Key
+		}
+		if a.Weight != b.Weight {
+			return a.Weight > b.Weight
+		}
+		return a.Move < b.Move
+	})
+	return book, nil
+}
+
+// WriteTo writes the book in the polyglot .bin format
+func (b *Book) WriteTo(w io.Writer) (int64, error) {
+	if err := binary.Write(w, binary.BigEndian, b.Entries); err != nil {
+		return 0, err
+	}
+	return int64(binary.Size(b.Entries)), nil
+}
+
+// polyMoveFromMove encodes the move as a polyglot move, castles are stored
+// as the king taking its own rook
+func polyMoveFromMove(move int) uint16 {
+	from := data.FromSquare(move)
+	to := data.ToSquare(move)
+	if move&data.MFLAGGCA != 0 {
+		switch to {
+		case data.G1:
+			to = data.H1
+		case data.C1:
+			to = data.A1
+		case data.G8:
+			to = data.H8
+		case data.C8:
+			to = data.A8
+		}
+	}
+
+	polyMove := uint16(data.FilesBoard[to] | data.RanksBoard[to]<<3 |
+		data.FilesBoard[from]<<6 | data.RanksBoard[from]<<9)
+	if promoted := data.Promoted(move); promoted != data.Empty {
+		switch {
+		case data.PieceKnight[promoted] == data.True:
+			polyMove |= 1 << 12
+		case data.PieceRookQueen[promoted] == data.False:
+			polyMove |= 2 << 12
+		case data.PieceBishopQueen[promoted] == data.False:
+			polyMove |= 3 << 12
+		default:
+			polyMove |= 4 << 12
+		}
+	}
+	return polyMove
+}
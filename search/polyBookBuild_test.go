@@ -0,0 +1,70 @@
+package search
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/AdamGriffiths31/ChessEngine/data"
+	"github.com/AdamGriffiths31/ChessEngine/engine"
+	"github.com/AdamGriffiths31/ChessEngine/io"
+)
+
+const bookGames = `[Event "1"]
+
+1. e4 c5 2. Nf3 d6 3. d4 cxd4 1-0
+
+[Event "2"]
+
+1. e4 e5 2. Nf3 Nc6 3. Bc4 Bc5 4. O-O Nf6 1/2-1/2
+`
+
+// loadBook builds a book from the games and loads it as the engine book
+func loadBook(t *testing.T, maxPly, minCount int) {
+	book, err := BuildBookFromPGN(strings.NewReader(bookGames), maxPly, minCount)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	if _, err := book.WriteTo(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if err := LoadPolyBook(&buf); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestBuildBookFromPGN(t *testing.T) {
+	defer LoadPolyBook(&bytes.Buffer{})
+	loadBook(t, 16, 1)
+
+	tests := []struct {
+		fen  string
+		want string
+	}{
+		{data.StartFEN, "e2e4"},
+		{"rnbqkbnr/pppppppp/8/8/4P3/8/PPPP1PPP/RNBQKBNR b KQkq e3 0 1", "c7c5 e7e5"},
+		{"r1bqk1nr/pppp1ppp/2n5/2b1p3/2B1P3/5N2/PPPP1PPP/RNBQK2R w KQkq - 0 1", "e1g1"},
+	}
+	for _, test := range tests {
+		game := engine.ParseFen(test.fen)
+		move := io.PrintMove(GetBookMove(game.Position()))
+		if !strings.Contains(test.want, move) {
+			t.Errorf("%v: expected one of %v but got %v", test.fen, test.want, move)
+		}
+	}
+}
+
+func TestBuildBookFromPGNLimits(t *testing.T) {
+	defer LoadPolyBook(&bytes.Buffer{})
+	loadBook(t, 1, 1)
+	game := engine.ParseFen("rnbqkbnr/pppppppp/8/8/4P3/8/PPPP1PPP/RNBQKBNR b KQkq e3 0 1")
+	if move := GetBookMove(game.Position()); move != data.NoMove {
+		t.Errorf("Expected no move past the ply limit but got %v", io.PrintMove(move))
+	}
+
+	loadBook(t, 16, 2)
+	if NumEntries != 1 {
+		t.Errorf("Expected only 1.e4 to be played twice but got %v entries", NumEntries)
+	}
+}
@@ -0,0 +1,83 @@
+package search
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+	"os"
+
+	"github.com/AdamGriffiths31/ChessEngine/data"
+	"github.com/AdamGriffiths31/ChessEngine/engine"
+	"github.com/AdamGriffiths31/ChessEngine/pgn"
+)
+
+// bookLearnStep is how much the weight of a book move changes after a win
+// or a loss by the side that played it, a draw leaves it as it is
+const bookLearnStep = 4
+
+// RecordBookResult adjusts the weights of the book moves played in the game
+// by how the game went for the side playing them, result is white's score of
+// 1, 0.5 or 0. Only the loaded book is changed, SaveBook keeps the changes.
+// Nothing records results unless asked so the book does not learn by default
+func RecordBookResult(g pgn.Game, result float64) error {
+	fen := data.StartFEN
+	if tag, ok := g.Tags["FEN"]; ok {
+		fen = tag
+	}
+	game := engine.ParseFen(fen)
+	p := game.Position()
+	for ply, san := range g.Moves {
+		move := p.ParseSAN(san)
+		if move == data.NoMove {
+			return fmt.Errorf("illegal move %v at ply %d", san, ply+1)
+		}
+		score := result
+		if p.Side == data.Black {
+			score = 1 - result
+		}
+		// the game has left the book once a move is not in it
+		if !adjustBookWeight(PolyKeyFromBoard(p), polyMoveFromMove(move), int(math.Round((score-0.5)*2*bookLearnStep))) {
+			return nil
+		}
+		p.MakeMove(move)
+		p.Play = 0
+		p.PositionHistory.RemovePositionHistory()
+	}
+	return nil
+}
+
+// adjustBookWeight adds delta to the weight of the move in the position with
+// the polyglot key, keeping it between 1 and the largest weight. It returns
+// false if the move is not in the book
+func adjustBookWeight(key uint64, move uint16, delta int) bool {
+	for i := 0; i < int(NumEntries); i++ {
+		entry := &PolyEntry[i]
+		if littleEndianToBigEndianUint64(entry.Key) != key || littleEndianToBigEndianUint16(entry.Move) != move {
+			continue
+		}
+		weight := int(littleEndianToBigEndianUint16(entry.Weight)) + delta
+		if weight < 1 {
+			weight = 1
+		} else if weight > math.MaxUint16 {
+			weight = math.MaxUint16
+		}
+		entry.Weight = littleEndianToBigEndianUint16(uint16(weight))
+		return true
+	}
+	return false
+}
+
+// SaveBook writes the loaded book to path in the polyglot .bin format
+func SaveBook(path string) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	// the entries were read as little endian so writing them the same way
+	// gives back the polyglot big endian layout
+	if err := binary.Write(f, binary.LittleEndian, PolyEntry); err != nil {
+		return err
+	}
+	return f.Close()
+}
@@ -0,0 +1,71 @@
+package search
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/AdamGriffiths31/ChessEngine/engine"
+	"github.com/AdamGriffiths31/ChessEngine/io"
+	"github.com/AdamGriffiths31/ChessEngine/pgn"
+)
+
+// bookShare returns the weight of the move as a share of the total weight
+// of the book moves in the position
+func bookShare(p *engine.Position, move string) float64 {
+	total, weight := 0, 0
+	for _, m := range getBookMoves(p) {
+		total += m.Weight
+		if io.PrintMove(m.Move) == move {
+			weight = m.Weight
+		}
+	}
+	return float64(weight) / float64(total)
+}
+
+func TestRecordBookResult(t *testing.T) {
+	defer LoadPolyBook(&bytes.Buffer{})
+	loadBook(t, 16, 1)
+
+	game := engine.ParseFen("rnbqkbnr/pppppppp/8/8/4P3/8/PPPP1PPP/RNBQKBNR b KQkq e3 0 1")
+	p := game.Position()
+	before := bookShare(p, "e7e5")
+	won := pgn.Game{Moves: []string{"e4", "e5", "Nf3", "Nc6"}}
+	for i := 0; i < 3; i++ {
+		if err := RecordBookResult(won, 0); err != nil {
+			t.Fatal(err)
+		}
+	}
+	after := bookShare(p, "e7e5")
+	if after <= before {
+		t.Errorf("Expected wins to raise the share of e7e5 from %v but got %v", before, after)
+	}
+
+	BookWeighted = true
+	defer func() { BookWeighted = false }()
+	SetBookSeed(1)
+	picks := map[string]int{}
+	for i := 0; i < 200; i++ {
+		picks[io.PrintMove(GetBookMove(p))]++
+	}
+	if picks["e7e5"] <= picks["c7c5"] {
+		t.Errorf("Expected e7e5 to be picked more often than c7c5 but got %v", picks)
+	}
+
+	path := filepath.Join(t.TempDir(), "learnt.bin")
+	if err := SaveBook(path); err != nil {
+		t.Fatal(err)
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if err := LoadPolyBook(f); err != nil {
+		t.Fatal(err)
+	}
+	if saved := bookShare(p, "e7e5"); saved != after {
+		t.Errorf("Expected the saved book to give e7e5 a share of %v but got %v", after, saved)
+	}
+}
@@ -0,0 +1,183 @@
+package search
+
+import (
+	"bytes"
+	stdio "io"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/AdamGriffiths31/ChessEngine/data"
+	"github.com/AdamGriffiths31/ChessEngine/engine"
+	"github.com/AdamGriffiths31/ChessEngine/eval"
+	"github.com/AdamGriffiths31/ChessEngine/io"
+	"github.com/AdamGriffiths31/ChessEngine/util"
+)
+
+// loadEntries loads a book holding the given moves for the position
+func loadEntries(t *testing.T, p *engine.Position, moves []string) {
+	book := &Book{}
+	for i, move := range moves {
+		book.Entries = append(book.Entries, PolyBookEntry{
+			Key:    PolyKeyFromBoard(p),
+			Move:   polyMove(move),
+			Weight: uint16(len(moves) - i),
+		})
+	}
+	var buf bytes.Buffer
+	if _, err := book.WriteTo(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if err := LoadPolyBook(&buf); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// polyMove encodes a coordinate move without promotion as a polyglot move
+func polyMove(move string) uint16 {
+	return uint16(move[2]-'a') | uint16(move[3]-'1')<<3 | uint16(move[0]-'a')<<6 | uint16(move[1]-'1')<<9
+}
+
+func TestGetBookMoveRejectsIllegalMoves(t *testing.T) {
+	defer LoadPolyBook(&bytes.Buffer{})
+	var logged bytes.Buffer
+	defer func(l *log.Logger) { BookLogger = l }(BookLogger)
+	BookLogger = log.New(&logged, "", 0)
+
+	// the knight is pinned so e2c3 is pseudo legal but not legal
+	game := engine.ParseFen("4k3/4r3/8/8/8/8/4N3/4K3 w - - 0 1")
+	p := game.Position()
+
+	loadEntries(t, p, []string{"e2c3", "e1d1"})
+	for i := 0; i < 10; i++ {
+		if move := io.PrintMove(GetBookMove(p)); move != "e1d1" {
+			t.Fatalf("Expected e1d1 but got %v", move)
+		}
+	}
+	if !strings.Contains(logged.String(), "rejected move") {
+		t.Errorf("Expected the illegal move to be logged")
+	}
+
+	loadEntries(t, p, []string{"e2c3", "a1a8"})
+	if move := GetBookMove(p); move != data.NoMove {
+		t.Errorf("Expected no move but got %v", io.PrintMove(move))
+	}
+}
+
+func TestBookMaxPly(t *testing.T) {
+	defer LoadPolyBook(&bytes.Buffer{})
+	fen := "r1bq1rk1/pp2ppbp/2np1np1/8/3NP3/2N1BP2/PPPQ2PP/R3KB1R w KQ - 0 15"
+	game := engine.ParseFen(fen)
+	loadEntries(t, game.Position(), []string{"h2h4"})
+	book := game.Position().ParseMove([]byte("h2h4"))
+
+	tests := []struct {
+		maxPly  int
+		useBook bool
+	}{
+		{0, true},
+		{40, true},
+		{20, false},
+	}
+	for _, test := range tests {
+		h := NewEngineHolder(1, eval.Get("custom"))
+		h.UseBook = true
+		h.Params.BookMaxPly = test.maxPly
+		h.Engines[0].Position = game.Position().Copy()
+		h.Search(&data.SearchInfo{Depth: 1, StartTime: util.GetTimeMs()})
+		if (h.Move.Move == book) != test.useBook {
+			t.Errorf("BookMaxPly %v: expected book used %v but got %v", test.maxPly, test.useBook, io.PrintMove(h.Move.Move))
+		}
+	}
+}
+
+func TestSetBookSeedRepeatsMoves(t *testing.T) {
+	defer LoadPolyBook(&bytes.Buffer{})
+	loadBook(t, 16, 1)
+	game := engine.ParseFen("rnbqkbnr/pppppppp/8/8/4P3/8/PPPP1PPP/RNBQKBNR b KQkq e3 0 1")
+
+	draw := func() []int {
+		SetBookSeed(3)
+		moves := make([]int, 20)
+		for i := range moves {
+			moves[i] = GetBookMove(game.Position())
+		}
+		return moves
+	}
+	first, second := draw(), draw()
+	seen := map[int]bool{}
+	for i := range first {
+		if first[i] != second[i] {
+			t.Fatalf("Expected the same moves for the same seed but got %v and %v", first, second)
+		}
+		seen[first[i]] = true
+	}
+	if len(seen) != 2 {
+		t.Errorf("Expected both book moves to be chosen but got %v", seen)
+	}
+}
+
+func TestInitPolyBookLogsBadBook(t *testing.T) {
+	defer LoadPolyBook(&bytes.Buffer{})
+	stdout := os.Stdout
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	os.Stdout = w
+	defer func() { os.Stdout = stdout }()
+
+	var logged bytes.Buffer
+	h := NewEngineHolder(1, eval.Get("custom"))
+	h.Logger = log.New(&logged, "", 0)
+	dir := t.TempDir()
+	for _, path := range []string{filepath.Join(dir, "missing.bin"), dir} {
+		logged.Reset()
+		h.UseBook = true
+		initPolyBookFile(h, path)
+		if h.UseBook {
+			t.Errorf("%v: expected the book to be turned off", path)
+		}
+		if !strings.Contains(logged.String(), "no opening book") {
+			t.Errorf("%v: expected a warning to be logged but got %q", path, logged.String())
+		}
+	}
+
+	w.Close()
+	os.Stdout = stdout
+	if written, _ := stdio.ReadAll(r); len(written) > 0 {
+		t.Errorf("Expected nothing on stdout but got %q", written)
+	}
+}
+
+func TestPolyKeyFromBoard(t *testing.T) {
+	// reference keys from the polyglot book format specification
+	tests := []struct {
+		moves []string
+		key   uint64
+	}{
+		{nil, 0x463b96181691fc9c},
+		{[]string{"e2e4"}, 0x823c9b50fd114196},
+		{[]string{"e2e4", "d7d5"}, 0x0756b94461c50fb0},
+		{[]string{"e2e4", "d7d5", "e4e5"}, 0x662fafb965db29d4},
+		{[]string{"e2e4", "d7d5", "e4e5", "f7f5"}, 0x22a48b5a8e47ff78},
+		{[]string{"e2e4", "d7d5", "e4e5", "f7f5", "e1e2"}, 0x652a607ca3f242c1},
+		{[]string{"e2e4", "d7d5", "e4e5", "f7f5", "e1e2", "e8f7"}, 0x00fdd303c946bdd9},
+		{[]string{"a2a4", "b7b5", "h2h4", "b5b4", "c2c4"}, 0x3c8123ea7b067637},
+		{[]string{"a2a4", "b7b5", "h2h4", "b5b4", "c2c4", "b4c3", "a1a3"}, 0x5c3f9b829b279560},
+	}
+	for _, test := range tests {
+		game := engine.ParseFen(data.StartFEN)
+		p := game.Position()
+		for _, move := range test.moves {
+			if !p.MakeGameMove(p.ParseMove([]byte(move))) {
+				t.Fatalf("%v: illegal move %v", test.moves, move)
+			}
+		}
+		if key := PolyKeyFromBoard(p); key != test.key {
+			t.Errorf("%v: expected %#x but got %#x", test.moves, test.key, key)
+		}
+	}
+}
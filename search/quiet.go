@@ -0,0 +1,22 @@
+package search
+
+import (
+	"github.com/AdamGriffiths31/ChessEngine/data"
+	"github.com/AdamGriffiths31/ChessEngine/engine"
+)
+
+// IsQuiet checks if the position is calm enough for its static evaluation to
+// be trusted, as when picking positions for an evaluation tuning set. It is
+// not quiet if the side to move is in check or has a capture or promotion
+// that wins material by SEE, which quiescence would have to resolve
+func IsQuiet(p *engine.Position) bool {
+	if p.IsKingAttacked(p.Side ^ 1) {
+		return false
+	}
+	for _, move := range p.LegalMoves() {
+		if move&(data.MFLAGCAP|data.MFLAGPRO) != 0 && p.SEE(move) > 0 {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,26 @@
+package search
+
+import (
+	"testing"
+
+	"github.com/AdamGriffiths31/ChessEngine/engine"
+)
+
+func TestIsQuiet(t *testing.T) {
+	tests := []struct {
+		name, fen string
+		want      bool
+	}{
+		{"hanging queen", "rnb1kbnr/pppp1ppp/8/4p1q1/3P4/2N5/PPP1PPPP/R1BQKBNR w KQkq - 0 1", false},
+		{"in check", "rnbqk1nr/pppp1ppp/8/4p3/1b1P4/8/PPP1PPPP/RNBQKBNR w KQkq - 0 1", false},
+		{"promotion", "8/4P3/8/8/8/8/k7/4K3 w - - 0 1", false},
+		{"calm middlegame", "r1bq1rk1/pp2bppp/2np1n2/2p1p3/2P1P3/2NP1N2/PP2BPPP/R1BQ1RK1 w - - 0 1", true},
+		{"even trade", "rnbqkbnr/ppp1pppp/8/3p4/4P3/8/PPPP1PPP/RNBQKBNR w KQkq - 0 1", true},
+	}
+	for _, tt := range tests {
+		game := engine.ParseFen(tt.fen)
+		if got := IsQuiet(game.Position()); got != tt.want {
+			t.Errorf("%v: expected %v but got %v", tt.name, tt.want, got)
+		}
+	}
+}
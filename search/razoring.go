@@ -0,0 +1,62 @@
+package search
+
+import "github.com/AdamGriffiths31/ChessEngine/data"
+
+// RazoringMode is how a node whose static evaluation is far below alpha is
+// razored
+type RazoringMode int
+
+const (
+	// RazorNone searches every node normally
+	RazorNone RazoringMode = iota
+	// RazorQSearch drops to quiescence and returns its score if it is still
+	// at or below alpha
+	RazorQSearch
+	// RazorReturn returns the static evaluation plus the margin without
+	// searching
+	RazorReturn
+)
+
+func (m RazoringMode) String() string {
+	switch m {
+	case RazorQSearch:
+		return "qsearch"
+	case RazorReturn:
+		return "return"
+	}
+	return "none"
+}
+
+// razorMaxDepth is the deepest remaining depth at which a node is razored
+const razorMaxDepth = 3
+
+// defaultRazorMargin is how far below alpha per ply of depth left the static
+// evaluation must be before razoring when RazorMargin is not set
+const defaultRazorMargin = 200
+
+// razor tries to prove the node fails low without searching its moves, it
+// returns the score and true if it does
+func (e *Engine) razor(alpha, beta, depthLeft, searchHeight, staticEval int, info *data.SearchInfo) (int, bool) {
+	margin := e.Parent.Params.RazorMargin
+	if margin == 0 {
+		margin = defaultRazorMargin
+	}
+	if depthLeft > razorMaxDepth || staticEval+margin*depthLeft > alpha {
+		return 0, false
+	}
+
+	e.RazorAttempts++
+	switch e.Parent.Params.Razoring {
+	case RazorQSearch:
+		score := e.quiescence(alpha, beta, searchHeight, 0, info)
+		if score <= alpha {
+			e.RazorCutoffs++
+			return score, true
+		}
+	case RazorReturn:
+		e.RazorCutoffs++
+		return staticEval + margin*depthLeft, true
+	}
+	e.RazorFailed++
+	return 0, false
+}
@@ -1,10 +1,12 @@
 package search
 
 import (
-	"errors"
+	"context"
 	"fmt"
+	stdio "io"
 	"math"
 	"sync"
+	"time"
 
 	"github.com/AdamGriffiths31/ChessEngine/data"
 	"github.com/AdamGriffiths31/ChessEngine/engine"
@@ -12,26 +14,100 @@ import (
 	"github.com/AdamGriffiths31/ChessEngine/util"
 )
 
-var errTimeout = errors.New("Search timeout")
+// stalemateMargin is how far behind the side to move must be before
+// quiescence looks for a stalemate to save the game
+const stalemateMargin = 300
+
+// defaultContinuationAging is the number of times the continuation scores are
+// halved at the start of each search
+const defaultContinuationAging = 1
+
+// defaultQuiescenceMaxDepth is how many plies quiescence searches captures
+// for when QuiescenceMaxDepth is not set
+const defaultQuiescenceMaxDepth = 16
+
+// quiescenceCheckPlies is how many plies past the quiescence limit a side in
+// check keeps searching rather than standing pat
+const quiescenceCheckPlies = 4
+
+// seePruneMaxDepth is the deepest remaining depth at which moves that lose
+// material are pruned
+const seePruneMaxDepth = 3
+
+// defaultSEEPruneThreshold is the material per ply of depth a move can lose
+// before it is pruned when SEEPruneThreshold is not set
+const defaultSEEPruneThreshold = 100
+
+// defaultLMRMinDepth is the depth from which late quiet moves are reduced
+// when LMRMinDepth is not set
+const defaultLMRMinDepth = 3
+
+// defaultLMRMinMoves is how many moves are searched at full depth before
+// the rest are reduced when LMRMinMoves is not set
+const defaultLMRMinMoves = 4
+
+// defaultLMRDivisor is the divisor of the reduction formula in hundredths
+// when LMRDivisor is not set
+const defaultLMRDivisor = 225
+
+// defaultLMRHistoryThreshold is the history score at which a move is reduced
+// a ply less, or at minus which it is reduced a ply more, when
+// LMRHistoryThreshold is not set
+const defaultLMRHistoryThreshold = engine.DefaultHistoryMax / 2
+
+// aspirationMinDepth is the last depth searched with a full window
+const aspirationMinDepth = 5
+
+// defaultAspirationDelta is the distance of each aspiration bound from the
+// previous score when AspirationDelta is not set
+const defaultAspirationDelta = 50
+
+// defaultAspirationMaxWindow is how far a bound can be widened before it is
+// dropped when AspirationMaxWindow is not set
+const defaultAspirationMaxWindow = 1000
+
+// repetitionWinningMargin is how far ahead the side to move at the root must
+// be before moves back to a position already played are scored as draws
+const repetitionWinningMargin = 200
+
+// swindleThreshold is how far behind the side to move at the root has to be
+// before swindle mode rewards complicated positions
+const swindleThreshold = 300
+
+// swindleMoveBonus and swindlePieceBonus are added in swindle mode for each
+// move the root side has and each piece left on the board
+const (
+	swindleMoveBonus  = 2
+	swindlePieceBonus = 4
+)
 
 func (h *EngineHolder) Search(info *data.SearchInfo) {
 	e := h.Engines[0]
 	e.IsMainEngine = true
-	if h.UseBook {
+	h.Termination = TerminationNone
+	if h.UseBook && (h.Params.BookMaxPly == 0 || e.Position.GamePly < h.Params.BookMaxPly) {
 		bestMove := GetBookMove(e.Position)
 		if bestMove != data.NoMove {
-			fmt.Printf("bestmove %s\n", io.PrintMove(bestMove))
+			h.Move.Move = bestMove
+			fmt.Fprintf(h.output(), "bestmove %s\n", io.PrintMove(bestMove))
 			return
 		}
-		fmt.Printf("No book move found for %v\n", e.Position.Side)
+		h.Logger.Printf("No book move found for %v\n", e.Position.Side)
 	}
 	h.ClearForSearch()
+	h.adjustTime(info)
+	if h.Params.Weakness > 0 && info.Depth > skillMaxDepth(h.Params.Weakness) {
+		info.Depth = skillMaxDepth(h.Params.Weakness)
+	}
 
+	// the workers are stopped through their own context so the caller's is
+	// only read, it tells if the search was stopped from outside
+	h.workerCtx, h.stopWorkers = context.WithCancel(h.Ctx)
 	var wg sync.WaitGroup
 
 	for _, engine := range h.Engines {
 		wg.Add(1)
-		fmt.Printf("worker added with key %v\n", engine.Position.PositionKey)
+		h.Logger.Printf("worker added with key %v\n", engine.Position.PositionKey)
 		go func(e *Engine) {
 			e.SearchRoot(info)
 			wg.Done()
@@ -39,9 +115,59 @@ func (h *EngineHolder) Search(info *data.SearchInfo) {
 	}
 
 	wg.Wait()
+	if h.Params.Weakness > 0 {
+		h.Move = h.skillMove(info)
+	}
+	h.Move.Ponder = e.ponderMove(h.Move.Move)
+
+	if h.Move.Ponder != data.NoMove {
+		fmt.Fprintf(h.output(), "bestmove %v ponder %v\n", io.PrintMove(h.Move.Move), io.PrintMove(h.Move.Ponder))
+		return
+	}
+	fmt.Fprintf(h.output(), "bestmove %v \n", io.PrintMove(h.Move.Move))
+}
 
-	fmt.Printf("bestmove %v \n", io.PrintMove(h.Move.Move))
+// ponderMove returns the reply to move the table expects, it is the second
+// move of the principal variation. NoMove is returned if the table has no
+// legal reply, such as after a mate
+func (e *Engine) ponderMove(move int) int {
+	if move == data.NoMove {
+		return data.NoMove
+	}
+	isAllowed, enPas, castle, fifty := e.Position.MakeMove(move)
+	if !isAllowed {
+		return data.NoMove
+	}
+	reply := e.Parent.TranspositionTable.Probe(e.Position.PositionKey)
+	if reply != data.NoMove && !e.Position.IsLegalMove(reply) {
+		reply = data.NoMove
+	}
+	e.Position.TakeMoveBack(move, enPas, castle, fifty)
+	return reply
+}
 
+// Hint searches a copy of the position to the given depth without using the
+// book and returns the best move and its score, cancelling ctx stops the
+// search early with the best move found so far. Nothing is written to the
+// output and the holder's context and move are left as they were
+func (h *EngineHolder) Hint(ctx context.Context, p *engine.Position, depth int) data.Move {
+	useBook, output := h.UseBook, h.Output
+	hctx, cancel, move := h.Ctx, h.CancelSearch, h.Move
+	h.UseBook, h.Output = false, stdio.Discard
+	defer func() {
+		h.UseBook, h.Output = useBook, output
+		h.Ctx, h.CancelSearch, h.Move = hctx, cancel, move
+	}()
+
+	for _, eng := range h.Engines {
+		eng.SetPosition(p.Copy())
+	}
+	searchCtx, cancelSearch := context.WithCancel(ctx)
+	defer cancelSearch()
+	h.Ctx, h.CancelSearch = searchCtx, cancelSearch
+	h.Move = data.Move{}
+	h.Search(&data.SearchInfo{Depth: depth, StartTime: util.GetTimeMs()})
+	return h.Move
 }
 
 func (e *EngineHolder) ClearForSearch() {
@@ -49,56 +175,157 @@ func (e *EngineHolder) ClearForSearch() {
 }
 
 func (e *Engine) ClearForSearch() {
-	e.Position.FiftyMove = 0
+	e.Position.VerifyHash = e.Parent.Params.VerifyHash
 
 	e.Position.PositionHistory.ClearPositionHistory()
 
-	for i := 0; i < 13; i++ {
-		for j := 0; j < 120; j++ {
-			e.Position.MoveHistory.History[i][j] = 0
-		}
-	}
+	e.Position.MoveHistory.SetHistoryDecay(e.Parent.Params.HistoryDecay)
+	e.Position.MoveHistory.AgeHistory()
+	e.Position.MoveHistory.HistoryMax = e.Parent.Params.HistoryMax
 
-	for i := 0; i < 2; i++ {
+	for i := 0; i < engine.MaxKillers; i++ {
 		for j := 0; j < data.MaxDepth; j++ {
 			e.Position.MoveHistory.Killers[i][j] = 0
 		}
 	}
+	e.Position.MoveHistory.KillerSlots = e.Parent.Params.KillerMoves
+
+	e.Position.MoveHistory.Moves = [data.MaxDepth]int{}
+	e.Position.MoveHistory.CounterMoves = [13][120]int{}
+	e.Position.MoveHistory.CountermoveBonus = e.Parent.Params.CountermoveBonus
+
+	aging := e.Parent.Params.ContinuationAging
+	if aging == 0 {
+		aging = defaultContinuationAging
+	}
+	if e.continuation == nil {
+		e.continuation = &engine.ContinuationHistory{}
+	}
+	e.continuation.Age(uint(aging))
+	e.Position.MoveHistory.Continuation = e.continuation
+	e.Position.MoveHistory.ContinuationWeight = e.Parent.Params.ContinuationWeight
+
+	e.QMaxDepthReached = 0
+	e.SEEPruned = 0
+	e.LMRReductions = 0
+	e.RazorAttempts = 0
+	e.OracleHits = 0
+	e.RazorCutoffs = 0
+	e.RazorFailed = 0
+	e.AspirationFailHigh = 0
+	e.AspirationFailLow = 0
+	e.NodesVisited = 0
+	e.QNodesVisited = 0
+	e.DepthBreakdown = nil
+	if c, ok := e.evaluator.(ICachingEvaluator); ok {
+		c.ClearCache()
+	}
 }
 
 // SearchRoot start the search from the root position
 func (e *Engine) SearchRoot(searchInfo *data.SearchInfo) {
-	defer recoverFromTimeout()
-
 	searchInfo.Stopped = false
 	searchInfo.ForceStop = false
-	window := 50
 	e.ClearForSearch()
-	alpha, beta := e.getInitialAlphaBeta()
+	e.rootSide = e.Position.Side
+	if a, ok := e.evaluator.(IAttachableEvaluator); ok {
+		a.Attach(e.Position)
+	}
 
-	for depth := 1; depth <= searchInfo.Depth; depth++ {
-		score := e.alphaBeta(alpha, beta, depth, 0, true, searchInfo)
+	score := 0
+	reason := TerminationDepth
+	for depth := 1; depth <= searchInfo.Depth || (searchInfo.Infinite == data.True && depth < data.MaxDepth); depth++ {
+		start, nodes, qNodes := util.GetTimeMs(), e.NodesVisited, e.QNodesVisited
+		if depth > aspirationMinDepth && !e.Parent.Params.DisableAspiration {
+			score = e.aspirationSearch(score, depth, searchInfo)
+		} else {
+			alpha, beta := e.getInitialAlphaBeta()
+			score = e.alphaBeta(alpha, beta, depth, 0, true, searchInfo)
+		}
 		if searchInfo.Stopped {
+			reason = e.stoppedReason(searchInfo)
 			break
 		}
 		e.Position.PositionHistory.ClearPositionHistory()
-		if depth >= 5 {
-			if score <= alpha || score >= beta {
-				alpha, beta = e.getInitialAlphaBeta()
-				continue
-			}
-
-			alpha = score - window
-			beta = score + window
-		}
+		e.DepthBreakdown = append(e.DepthBreakdown, DepthInfo{
+			Depth:  depth,
+			Nodes:  e.NodesVisited - nodes,
+			QNodes: e.QNodesVisited - qNodes,
+			Time:   util.GetTimeMs() - start,
+			Move:   e.Parent.TranspositionTable.Probe(e.Position.PositionKey),
+			Score:  score,
+		})
 
 		if e.IsMainEngine {
 			e.printSearchInfo(score, depth, searchInfo.Node, searchInfo.StartTime)
 		}
+		if searchInfo.Infinite != data.True && e.isProvenMate(score, depth) {
+			reason = TerminationMate
+			break
+		}
 	}
 
 	if e.IsMainEngine {
-		e.Parent.CancelSearch()
+		if searchInfo.Infinite == data.True {
+			e.waitForStop(searchInfo)
+			reason = TerminationCancelled
+		}
+		e.Parent.Termination = reason
+		e.Parent.stopWorkers()
+	}
+}
+
+// waitForStop blocks an infinite search that has reached the maximum depth
+// until it is told to stop, so the best move is not sent early
+func (e *Engine) waitForStop(info *data.SearchInfo) {
+	for !info.ForceStop {
+		select {
+		case <-e.Parent.workerCtx.Done():
+			return
+		case <-time.After(10 * time.Millisecond):
+		}
+	}
+}
+
+// aspirationSearch searches a window around the previous score, only the
+// bound that fails is widened, first by the delta and then by doubling
+func (e *Engine) aspirationSearch(previous, depth int, info *data.SearchInfo) int {
+	delta := e.Parent.Params.AspirationDelta
+	if delta == 0 {
+		delta = defaultAspirationDelta
+	}
+	maxWindow := e.Parent.Params.AspirationMaxWindow
+	if maxWindow == 0 {
+		maxWindow = defaultAspirationMaxWindow
+	}
+
+	alpha := util.Max(previous-delta, -data.ABInfinite)
+	beta := util.Min(previous+delta, data.ABInfinite)
+	widen := delta
+	for fails := 0; ; fails++ {
+		score := e.alphaBeta(alpha, beta, depth, 0, true, info)
+		if info.Stopped || (score > alpha && score < beta) {
+			return score
+		}
+
+		if fails < 2 {
+			widen += delta
+		} else {
+			widen *= 2
+		}
+		if score <= alpha {
+			e.AspirationFailLow++
+			alpha = util.Max(score-widen, -data.ABInfinite)
+			if widen > maxWindow {
+				alpha = -data.ABInfinite
+			}
+		} else {
+			e.AspirationFailHigh++
+			beta = util.Min(score+widen, data.ABInfinite)
+			if widen > maxWindow {
+				beta = data.ABInfinite
+			}
+		}
 	}
 }
 
@@ -113,16 +340,25 @@ func (e *Engine) getInitialAlphaBeta() (alpha, beta int) {
 func (e *Engine) printSearchInfo(score, depth int, nodes int64, startTime int64) {
 	bestMove := e.Parent.TranspositionTable.Probe(e.Position.PositionKey)
 	e.Parent.Move.Move = bestMove
-	fmt.Printf("info score cp %d depth %d nodes %v time %d pv %v\n", score, depth, nodes, util.GetTimeMs()-startTime, io.PrintMove(bestMove))
+	e.Parent.Move.Score = score
+	e.Parent.Move.Depth = depth
+	fmt.Fprintf(e.Parent.output(), "info score %v depth %d nodes %v time %d pv %v\n", e.UCIScore(score), depth, nodes, util.GetTimeMs()-startTime, io.PrintMove(bestMove))
 	//fmt.Printf("Ordering: %.2f\n", e.Position.FailHighFirst/e.Position.FailHigh)
 }
 
-// recoverFromTimeout if the search times out, recover from the panic
-func recoverFromTimeout() {
-	err := recover()
-	if err != nil && err != errTimeout {
-		panic(err)
+// UCIScore formats a search score for the info line, mate scores are given in
+// moves and anything else in centipawns
+func (e *Engine) UCIScore(score int) string {
+	if score > data.Mate {
+		return fmt.Sprintf("mate %d", (data.ABInfinite-score-e.Position.Play+1)/2)
+	}
+	if score < -data.Mate {
+		return fmt.Sprintf("mate %d", -(data.ABInfinite+score-e.Position.Play)/2)
+	}
+	if c, ok := e.evaluator.(ICentipawnEvaluator); ok {
+		score = c.Centipawns(e.Position, score)
 	}
+	return fmt.Sprintf("cp %d", NormalizeScore(score, e.Parent.Params.DrawBand))
 }
 
 // alphaBeta performs the alpha beta search
@@ -136,18 +372,31 @@ func (e *Engine) alphaBeta(alpha, beta, depthLeft, searchHeight int, nullAllowed
 	}
 
 	if depthLeft <= 0 {
-		return e.quiescence(alpha, beta, searchHeight, info)
+		if e.Parent.Params.DisableQuiescence {
+			e.NodesVisited++
+			return e.evaluate()
+		}
+		return e.quiescence(alpha, beta, searchHeight, 0, info)
 	}
 
 	e.Checkup(info)
 	pvNode := beta != alpha+1
 	e.NodesVisited++
 
-	if e.isRepetitionOrFiftyMove() {
-		return 0
+	// the root is searched even if it has been seen before so there is a
+	// move to play
+	if searchHeight > 0 && e.isRepetitionOrFiftyMove() {
+		return e.drawScore()
+	}
+
+	if searchHeight > 0 && e.Parent.Oracle != nil && e.Position.Board.CountBits(e.Position.Board.Pieces) <= oracleMaxPieces {
+		if score, ok := e.Parent.Oracle.Probe(e.Position); ok {
+			e.OracleHits++
+			return score
+		}
 	}
 
-	staticEval := e.evaluator.Evaluate((e.Position))
+	staticEval := e.evaluate()
 
 	if searchHeight > data.MaxDepth-1 {
 		return staticEval
@@ -168,15 +417,18 @@ func (e *Engine) alphaBeta(alpha, beta, depthLeft, searchHeight int, nullAllowed
 		depthLeft++
 	}
 
+	// the table may hold a root move outside of the ones being searched
+	restricted := searchHeight == 0 && (len(info.SearchMoves) > 0 || len(info.ExcludeMoves) > 0)
+
 	score := -data.ABInfinite
 	pvMove := data.NoMove
-	if e.Parent.TranspositionTable.Get(e.Position.PositionKey, e.Position.Play, &pvMove, &score, alpha, beta, depthLeft) {
+	if !e.Parent.Params.DisableTT && !restricted && e.Parent.TranspositionTable.Get(e.Position.PositionKey, e.Position.Play, &pvMove, &score, alpha, beta, depthLeft) {
 		e.Parent.TranspositionTable.Cut++
 		return score
 	}
 
 	// Reverse Futility Pruning
-	if !pvNode && depthLeft <= 8 && !inCheck {
+	if !e.Parent.Params.DisableReverseFutility && !pvNode && depthLeft <= 8 && !inCheck {
 		var score = staticEval - data.PieceVal[data.WP]*depthLeft
 		if score >= beta {
 			return staticEval
@@ -184,16 +436,19 @@ func (e *Engine) alphaBeta(alpha, beta, depthLeft, searchHeight int, nullAllowed
 	}
 
 	// Razoring
-	// if depthLeft <= 5 && staticEval+200*depthLeft <= alpha {
-	// 	score := e.quiescence(alpha, beta, searchHeight, info)
-	// 	if score <= alpha {
-	// 		return score
-	// 	}
-	// }
-
-	doNullMove := nullAllowed && !inCheck && e.Position.Play != 0 && depthLeft >= 4 && !e.Position.IsEndGame()
+	if e.Parent.Params.Razoring != RazorNone && !pvNode && !inCheck {
+		if score, ok := e.razor(alpha, beta, depthLeft, searchHeight, staticEval, info); ok {
+			return score
+		}
+		if info.Stopped {
+			return 0
+		}
+	}
+
+	doNullMove := !e.Parent.Params.DisableNullMove && nullAllowed && !inCheck && e.Position.Play != 0 && depthLeft >= 4 && !e.Position.IsEndGame()
 	if doNullMove {
 		_, enPas, castle := e.Position.MakeNullMove()
+		e.Position.MoveHistory.SetMove(data.NoMove, e.Position.Play)
 		e.Position.PositionHistory.AddPositionHistory(e.Position.PositionKey)
 		score = -e.alphaBeta(-beta, -beta+1, depthLeft-4, searchHeight+1, false, info)
 		e.Position.PositionHistory.RemovePositionHistory()
@@ -202,7 +457,19 @@ func (e *Engine) alphaBeta(alpha, beta, depthLeft, searchHeight int, nullAllowed
 			return 0
 		}
 		if score >= beta && math.Abs(float64(score)) < data.Mate {
-			return beta
+			verifyDepth := e.Parent.Params.NullMoveVerifyDepth
+			if verifyDepth == 0 || depthLeft < verifyDepth {
+				return beta
+			}
+			// Verify the cutoff with a reduced search that cannot null move
+			// so a side in zugzwang is not assumed to be winning
+			score = e.alphaBeta(beta-1, beta, depthLeft-4, searchHeight, false, info)
+			if info.Stopped {
+				return 0
+			}
+			if score >= beta {
+				return beta
+			}
 		}
 	}
 
@@ -223,14 +490,51 @@ func (e *Engine) alphaBeta(alpha, beta, depthLeft, searchHeight int, nullAllowed
 			}
 		}
 	}
+	seePrune := !e.Parent.Params.DisableSEEPrune && !pvNode && !inCheck && depthLeft <= seePruneMaxDepth
+	// a root side that is well ahead scores going back to a position already
+	// played as no better than a draw so it makes progress instead
+	avoidRepetition := searchHeight == 0 && staticEval >= repetitionWinningMargin
+	// moves that save a piece from being won are not reduced, the piece would
+	// often be lost at the reduced depth
+	lmrExempt := !e.Parent.Params.DisableLMRExemptions && !inCheck && depthLeft >= e.lmrMinDepth()
 	for i := 0; i < ml.Count; i++ {
 		e.PickNextMove(i, ml)
+		if restricted && !isRootMove(ml.Moves[i].Move, info) {
+			continue
+		}
+		if seePrune && legal > 0 && ml.Moves[i].Move != pvMove && e.losesMaterial(ml.Moves[i].Move, depthLeft) {
+			e.SEEPruned++
+			continue
+		}
+		escapes := false
+		if lmrExempt && legal > 0 && ml.Moves[i].Move&data.MFLAGCAP == 0 {
+			escapes = e.Position.SEEOnSquare(data.FromSquare(ml.Moves[i].Move), e.Position.Side^1) > 0
+		}
 		isAllowed, enPas, CastleRight, fifty := e.Position.MakeMove(ml.Moves[i].Move)
 		if !isAllowed {
 			continue
 		}
+		e.Parent.TranspositionTable.Prefetch(e.Position.PositionKey)
+		e.Position.MoveHistory.SetMove(ml.Moves[i].Move, e.Position.Play)
 		legal++
-		score = -e.alphaBeta(-beta, -alpha, depthLeft-1, searchHeight+1, true, info)
+		reduction := 0
+		if !inCheck && !escapes && legal > 1 && ml.Moves[i].Move != pvMove {
+			reduction = e.lateMoveReduction(ml.Moves[i].Move, depthLeft, legal)
+		}
+		if reduction > 0 {
+			e.LMRReductions++
+			score = -e.alphaBeta(-alpha-1, -alpha, depthLeft-1-reduction, searchHeight+1, true, info)
+			if score > alpha && !info.Stopped {
+				score = -e.alphaBeta(-beta, -alpha, depthLeft-1, searchHeight+1, true, info)
+			}
+		} else {
+			score = -e.alphaBeta(-beta, -alpha, depthLeft-1, searchHeight+1, true, info)
+		}
+		if avoidRepetition && e.Position.Positions.Count(e.Position.PositionKey) > 0 {
+			if draw := -e.drawScore(); score > draw {
+				score = draw
+			}
+		}
 		e.Position.TakeMoveBack(ml.Moves[i].Move, enPas, CastleRight, fifty)
 		if info.Stopped {
 			return 0
@@ -244,8 +548,14 @@ func (e *Engine) alphaBeta(alpha, beta, depthLeft, searchHeight int, nullAllowed
 						e.Position.FailHighFirst++
 					}
 					if ml.Moves[i].Move&data.MFLAGCAP == 0 {
-						e.Position.MoveHistory.Killers[1][e.Position.Play] = e.Position.MoveHistory.Killers[0][e.Position.Play]
-						e.Position.MoveHistory.Killers[0][e.Position.Play] = ml.Moves[i].Move
+						e.Position.MoveHistory.StoreKiller(ml.Moves[i].Move, e.Position.Play)
+						if prev := e.Position.MoveHistory.PreviousMove(e.Position.Play); prev != data.NoMove {
+							prevTo := data.ToSquare(prev)
+							prevPiece := e.Position.Board.PieceAt(data.Square120ToSquare64[prevTo])
+							piece := e.Position.Board.PieceAt(data.Square120ToSquare64[data.FromSquare(bestMove)])
+							e.Position.MoveHistory.StoreCountermove(prevPiece, prevTo, bestMove)
+							e.Position.MoveHistory.UpdateContinuation(prevPiece, prevTo, piece, data.ToSquare(bestMove), depthLeft*depthLeft)
+						}
 					}
 					e.Position.FailHigh++
 					e.Parent.TranspositionTable.Store(e.Position.PositionKey, e.Position.Play, bestMove, beta, data.PVBeta, depthLeft)
@@ -255,7 +565,7 @@ func (e *Engine) alphaBeta(alpha, beta, depthLeft, searchHeight int, nullAllowed
 				alpha = score
 
 				if ml.Moves[i].Move&data.MFLAGCAP == 0 {
-					e.Position.MoveHistory.History[e.Position.Board.PieceAt(data.Square120ToSquare64[data.FromSquare(bestMove)])][data.ToSquare(bestMove)] += e.Position.Play
+					e.Position.MoveHistory.AddHistory(e.Position.Board.PieceAt(data.Square120ToSquare64[data.FromSquare(bestMove)]), data.ToSquare(bestMove), e.Position.Play)
 				}
 			}
 		}
@@ -265,7 +575,7 @@ func (e *Engine) alphaBeta(alpha, beta, depthLeft, searchHeight int, nullAllowed
 		if e.Position.IsKingAttacked(e.Position.Side ^ 1) {
 			return -data.ABInfinite + e.Position.Play
 		} else {
-			return 0
+			return e.drawScore()
 		}
 	}
 	if !(alpha >= oldAlpha) {
@@ -279,54 +589,100 @@ func (e *Engine) alphaBeta(alpha, beta, depthLeft, searchHeight int, nullAllowed
 	return alpha
 }
 
+// isRootMove checks the move is one of the root moves the search is
+// restricted to and has not been excluded
+func isRootMove(move int, info *data.SearchInfo) bool {
+	return (len(info.SearchMoves) == 0 || containsMove(info.SearchMoves, move)) &&
+		!containsMove(info.ExcludeMoves, move)
+}
+
+// containsMove checks if the move is in moves
+func containsMove(moves []int, move int) bool {
+	for _, m := range moves {
+		if m == move {
+			return true
+		}
+	}
+	return false
+}
+
 // quiescence is the quiescence search function.
-func (e *Engine) quiescence(alpha, beta, searchHeight int, info *data.SearchInfo) int {
+func (e *Engine) quiescence(alpha, beta, searchHeight, qPly int, info *data.SearchInfo) int {
 	e.Position.CheckBitboard()
 
 	if e.isRepetitionOrFiftyMove() {
-		return 0
+		return e.drawScore()
 	}
 
 	e.Checkup(info)
 
 	e.NodesVisited++
+	e.QNodesVisited++
 
 	if searchHeight > data.MaxDepth-1 {
-		return e.evaluator.Evaluate((e.Position))
+		return e.evaluate()
+	}
+
+	// no capture can give either side mating material back
+	if e.Position.IsInsufficientMaterial() {
+		return e.drawScore()
 	}
 
 	score := -data.ABInfinite
 	pvMove := data.NoMove
-	if e.Parent.TranspositionTable.Get(e.Position.PositionKey, e.Position.Play, &pvMove, &score, alpha, beta, 0) {
+	if !e.Parent.Params.DisableTT && e.Parent.TranspositionTable.Get(e.Position.PositionKey, e.Position.Play, &pvMove, &score, alpha, beta, 0) {
 		e.Parent.TranspositionTable.Cut++
 		return score
 	}
 
-	score = e.evaluator.Evaluate((e.Position))
+	score = e.evaluate()
 
 	if !(score > -data.ABInfinite) && !(score < data.ABInfinite) {
 		panic(fmt.Errorf("quiescence score error  %v", score))
 	}
 
-	if score >= beta {
-		return beta
+	if qPly > e.QMaxDepthReached {
+		e.QMaxDepthReached = qPly
 	}
-
-	bigDelta := 1000 //Queen Value
-
-	if score < alpha-bigDelta {
-		return alpha
-	}
-
-	if alpha < score {
-		alpha = score
+	inCheck := e.Position.IsKingAttacked(e.Position.Side ^ 1)
+	if limit := e.quiescenceMaxDepth(); qPly >= limit {
+		if qPly >= limit+quiescenceCheckPlies || !inCheck {
+			return score
+		}
 	}
 
 	flag := data.PVAlpha
 	bestMove := data.NoMove
-	bestScore := -data.ABInfinite
+	bestScore := score
 	ml := &engine.MoveList{}
-	e.Position.GenerateAllCaptures(ml)
+	if inCheck {
+		// The side in check cannot stand pat, every evasion is searched
+		bestScore = -data.ABInfinite + e.Position.Play
+		e.Position.GenerateAllMoves(ml)
+	} else {
+		if score <= -stalemateMargin && e.isStalemate() {
+			return e.drawScore()
+		}
+
+		if score >= beta {
+			return beta
+		}
+
+		bigDelta := 1000 //Queen Value
+
+		if score < alpha-bigDelta {
+			return alpha
+		}
+
+		if alpha < score {
+			alpha = score
+		}
+
+		e.Position.GenerateAllCaptures(ml)
+		if qPly == 0 && !e.Parent.Params.DisableQSearchChecks {
+			e.addQuietChecks(ml)
+		}
+	}
 	for i := 0; i < ml.Count; i++ {
 		e.PickNextMove(i, ml)
 		move := ml.Moves[i].Move
@@ -334,7 +690,8 @@ func (e *Engine) quiescence(alpha, beta, searchHeight int, info *data.SearchInfo
 		if !isAllowed {
 			continue
 		}
-		score = -e.quiescence(-beta, -alpha, searchHeight+1, info)
+		e.Parent.TranspositionTable.Prefetch(e.Position.PositionKey)
+		score = -e.quiescence(-beta, -alpha, searchHeight+1, qPly+1, info)
 		e.Position.TakeMoveBack(move, enPas, CastleRight, fifty)
 		if info.Stopped {
 			return 0
@@ -358,6 +715,120 @@ func (e *Engine) quiescence(alpha, beta, searchHeight int, info *data.SearchInfo
 	return bestScore
 }
 
+// addQuietChecks adds the quiet moves that give check to the captures so the
+// first ply of quiescence can find checking sequences
+func (e *Engine) addQuietChecks(ml *engine.MoveList) {
+	quiet := &engine.MoveList{}
+	e.Position.GenerateAllMoves(quiet)
+	for i := 0; i < quiet.Count; i++ {
+		move := quiet.Moves[i].Move
+		if move&data.MFLAGCAP != 0 || data.Promoted(move) != data.Empty || !e.Position.MoveGivesCheck(move) {
+			continue
+		}
+		ml.Moves[ml.Count] = engine.Move{Move: move}
+		ml.Count++
+	}
+}
+
+// quiescenceMaxDepth returns how many plies quiescence searches before
+// standing pat
+func (e *Engine) quiescenceMaxDepth() int {
+	if e.Parent.Params.QuiescenceMaxDepth == 0 {
+		return defaultQuiescenceMaxDepth
+	}
+	return e.Parent.Params.QuiescenceMaxDepth
+}
+
+// losesMaterial checks if the move loses more than the SEE threshold for
+// each ply of depth left, promotions are never pruned
+func (e *Engine) losesMaterial(move, depthLeft int) bool {
+	if data.Promoted(move) != data.Empty {
+		return false
+	}
+	threshold := e.Parent.Params.SEEPruneThreshold
+	if threshold == 0 {
+		threshold = defaultSEEPruneThreshold
+	}
+	return e.Position.SEE(move) < -threshold*depthLeft
+}
+
+// lateMoveReduction returns how many plies less than normal the move, which
+// has just been made, is searched. Only quiet moves late in the list that
+// are not killers and do not give check are reduced, and unless
+// DisableLMRExemptions is set passed pawns pushed to the sixth or seventh
+// rank are not either. The reduction grows with the depth and the number of
+// moves tried and shrinks for moves with a good history score
+func (e *Engine) lateMoveReduction(move, depthLeft, moveNumber int) int {
+	params := &e.Parent.Params
+	if params.DisableLMR {
+		return 0
+	}
+	minMoves, divisor := params.LMRMinMoves, params.LMRDivisor
+	if minMoves == 0 {
+		minMoves = defaultLMRMinMoves
+	}
+	if divisor == 0 {
+		divisor = defaultLMRDivisor
+	}
+	if depthLeft < e.lmrMinDepth() || moveNumber <= minMoves {
+		return 0
+	}
+	if move&data.MFLAGCAP != 0 || data.Promoted(move) != data.Empty {
+		return 0
+	}
+	if !params.DisableLMRExemptions && e.isAdvancedPassedPawn(data.ToSquare(move)) {
+		return 0
+	}
+	// the move has been made so the killers are those of the parent ply
+	if e.Position.MoveHistory.KillerIndex(move, e.Position.Play-1) != -1 {
+		return 0
+	}
+	if e.Position.IsKingAttacked(e.Position.Side ^ 1) {
+		return 0
+	}
+
+	reduction := int(math.Log(float64(depthLeft)) * math.Log(float64(moveNumber)) * 100 / float64(divisor))
+	threshold := params.LMRHistoryThreshold
+	if threshold == 0 {
+		threshold = defaultLMRHistoryThreshold
+	}
+	piece := e.Position.Board.PieceAt(data.Square120ToSquare64[data.ToSquare(move)])
+	if history := e.Position.MoveHistory.History[piece][data.ToSquare(move)]; history >= threshold {
+		reduction--
+	} else if history <= -threshold {
+		reduction++
+	}
+	if reduction > depthLeft-2 {
+		reduction = depthLeft - 2
+	}
+	if reduction < 0 {
+		return 0
+	}
+	return reduction
+}
+
+// lmrMinDepth returns the depth from which late moves are reduced
+func (e *Engine) lmrMinDepth() int {
+	if e.Parent.Params.LMRMinDepth == 0 {
+		return defaultLMRMinDepth
+	}
+	return e.Parent.Params.LMRMinDepth
+}
+
+// isAdvancedPassedPawn checks if the piece on sq, which belongs to the side
+// that just moved, is a passed pawn on its sixth or seventh rank
+func (e *Engine) isAdvancedPassedPawn(sq int) bool {
+	sq64 := data.Square120ToSquare64[sq]
+	rank := data.RanksBoard[sq]
+	switch e.Position.Board.PieceAt(sq64) {
+	case data.WP:
+		return rank >= data.Rank6 && data.WhitePassedMask[sq64]&e.Position.Board.BlackPawn == 0
+	case data.BP:
+		return rank <= data.Rank3 && data.BlackPassedMask[sq64]&e.Position.Board.WhitePawn == 0
+	}
+	return false
+}
+
 // PickNextMove picks the next move to be searched
 func (e *Engine) PickNextMove(moveNum int, ml *engine.MoveList) {
 	bestScore := 0
@@ -373,9 +844,111 @@ func (e *Engine) PickNextMove(moveNum int, ml *engine.MoveList) {
 	ml.Moves[bestNum] = holder
 }
 
+// isStalemate checks if the side to move is not in check and has no legal
+// moves, the king is checked first so most positions exit early
+func (e *Engine) isStalemate() bool {
+	p := e.Position
+	enemy := p.Side ^ 1
+	if p.IsKingAttacked(enemy) {
+		return false
+	}
+
+	king := p.Board.GetPieces(p.Side, data.WK)
+	kingMoves := engine.PreCalculatedKingMoves[engine.FirstSquare(king)] &^ p.Board.GetPiecesBitboard(p.Side)
+	for ; kingMoves != 0; kingMoves &= kingMoves - 1 {
+		if !p.SquaresUnderAttack(enemy, engine.FirstSquare(kingMoves)) {
+			return false
+		}
+	}
+
+	ml := &engine.MoveList{}
+	p.GenerateAllMoves(ml)
+	for i := 0; i < ml.Count; i++ {
+		isAllowed, enPas, castleRight, fifty := p.MakeMove(ml.Moves[i].Move)
+		if isAllowed {
+			p.TakeMoveBack(ml.Moves[i].Move, enPas, castleRight, fifty)
+			return false
+		}
+	}
+	return true
+}
+
+// evaluate returns the static evaluation for the side to move, in swindle
+// mode a root side that is clearly lost prefers positions with more moves and
+// pieces where the opponent has more chances to go wrong
+func (e *Engine) evaluate() int {
+	score := e.evaluator.Evaluate(e.Position)
+	if !e.Parent.Params.Swindle {
+		return score
+	}
+
+	rootScore := score
+	if e.Position.Side != e.rootSide {
+		rootScore = -score
+	}
+	if rootScore >= -swindleThreshold {
+		return score
+	}
+
+	bonus := swindlePieceBonus*e.Position.Board.CountBits(e.Position.Board.Pieces) +
+		swindleMoveBonus*e.rootMoveCount()
+	if e.Position.Side != e.rootSide {
+		return score - bonus
+	}
+	return score + bonus
+}
+
+// rootMoveCount returns the number of pseudo legal moves the side to move at
+// the root has in the current position
+func (e *Engine) rootMoveCount() int {
+	ml := &engine.MoveList{}
+	if e.Position.Side == e.rootSide {
+		e.Position.GenerateAllMoves(ml)
+		return ml.Count
+	}
+	_, enPas, castle := e.Position.MakeNullMove()
+	e.Position.GenerateAllMoves(ml)
+	e.Position.TakeNullMoveBack(enPas, castle)
+	return ml.Count
+}
+
+// drawScore returns the score of a draw for the side to move, contempt makes
+// a draw look worse for the side to move at the root
+func (e *Engine) drawScore() int {
+	contempt := e.contempt()
+	if e.Position.Side == e.rootSide {
+		return -contempt
+	}
+	return contempt
+}
+
+// contempt tapers from Contempt with every piece on the board to
+// EndgameContempt once only pawns are left
+func (e *Engine) contempt() int {
+	params := &e.Parent.Params
+	if params.Contempt == params.EndgameContempt {
+		return params.Contempt
+	}
+	phase := gamePhase(e.Position)
+	return (params.Contempt*phase + params.EndgameContempt*(256-phase)) / 256
+}
+
+// gamePhase returns the game phase from 0, only pawns left, to 256 with every
+// piece on the board, weighting the pieces as the evaluation does
+func gamePhase(p *engine.Position) int {
+	b := &p.Board
+	phase := 4*b.CountBits(b.WhiteQueen|b.BlackQueen) +
+		2*b.CountBits(b.WhiteRook|b.BlackRook) +
+		b.CountBits(b.WhiteKnight|b.BlackKnight|b.WhiteBishop|b.BlackBishop)
+	if phase > 24 {
+		phase = 24
+	}
+	return (phase*256 + 12) / 24
+}
+
 // isRepetitionOrFiftyMove checks if the position is a repetition or a fifty move draw
 func (e *Engine) isRepetitionOrFiftyMove() bool {
-	if e.Position.FiftyMove >= 50 {
+	if e.Position.FiftyMove >= 100 {
 		return true
 	}
 
@@ -386,9 +959,9 @@ func (e *Engine) isRepetitionOrFiftyMove() bool {
 		}
 	}
 
-	previouslySeen := e.Position.Positions[e.Position.PositionKey]
-
-	return previouslySeen >= 2
+	// one more occurrence of a position played twice in the game is a
+	// threefold repetition
+	return e.Position.Positions.Count(e.Position.PositionKey) >= 2
 }
 
 // Checkup checks if the search should be stopped
@@ -397,10 +970,14 @@ func (e *Engine) Checkup(info *data.SearchInfo) {
 		if (info.TimeSet == data.True && util.GetTimeMs() > info.StopTime) || info.ForceStop {
 			info.Stopped = true
 		}
+		if info.NodeLimit > 0 && int64(e.NodesVisited) >= info.NodeLimit {
+			info.Stopped = true
+		}
 		select {
-		case <-e.Parent.Ctx.Done():
-			fmt.Printf("Ending early (%v)\n", e.IsMainEngine)
-			panic(errTimeout)
+		case <-e.Parent.workerCtx.Done():
+			// every node returns as soon as it sees Stopped so the moves
+			// made are taken back on the way up
+			info.Stopped = true
 		default:
 		}
 	}
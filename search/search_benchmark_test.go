@@ -0,0 +1,29 @@
+package search
+
+import (
+	"testing"
+	"time"
+
+	"github.com/AdamGriffiths31/ChessEngine/data"
+	"github.com/AdamGriffiths31/ChessEngine/engine"
+	"github.com/AdamGriffiths31/ChessEngine/eval"
+	"github.com/AdamGriffiths31/ChessEngine/util"
+)
+
+// BenchmarkSearch runs a single threaded fixed depth search over the first
+// benchmark positions and reports the nodes searched per second
+func BenchmarkSearch(b *testing.B) {
+	var nodes int
+	start := time.Now()
+	for i := 0; i < b.N; i++ {
+		for _, fen := range fens[:4] {
+			h := NewEngineHolder(1, eval.Get("custom"))
+			h.UseBook = false
+			game := engine.ParseFen(fen)
+			h.Engines[0].Position = game.Position().Copy()
+			h.Search(&data.SearchInfo{Depth: 7, StartTime: util.GetTimeMs()})
+			nodes += h.Engines[0].NodesVisited
+		}
+	}
+	b.ReportMetric(float64(nodes)/time.Since(start).Seconds(), "nodes/s")
+}
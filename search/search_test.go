@@ -0,0 +1,878 @@
+package search
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/AdamGriffiths31/ChessEngine/data"
+	"github.com/AdamGriffiths31/ChessEngine/engine"
+	"github.com/AdamGriffiths31/ChessEngine/eval"
+	"github.com/AdamGriffiths31/ChessEngine/io"
+	"github.com/AdamGriffiths31/ChessEngine/util"
+)
+
+// searchPosition runs a single threaded fixed depth search on the fen and
+// returns the holder once the search has finished
+func searchPosition(fen string, depth int, setup func(h *EngineHolder)) *EngineHolder {
+	h := NewEngineHolder(1, eval.Get("custom"))
+	h.UseBook = false
+	if setup != nil {
+		setup(h)
+	}
+	game := engine.ParseFen(fen)
+	for _, eng := range h.Engines {
+		eng.Position = game.Position().Copy()
+	}
+	h.Search(&data.SearchInfo{Depth: depth, StartTime: util.GetTimeMs()})
+	return h
+}
+
+func TestQuiescenceStandsPatWithoutCaptures(t *testing.T) {
+	h := NewEngineHolder(1, eval.Get("custom"))
+	e := h.Engines[0]
+	game := engine.ParseFen("4k3/4p3/8/8/8/8/4P3/4K3 w - - 0 1")
+	e.Position = game.Position().Copy()
+	want := e.evaluator.Evaluate(e.Position)
+	if got := e.quiescence(-data.ABInfinite, data.ABInfinite, 0, 0, &data.SearchInfo{}); got != want {
+		t.Errorf("Expected the static evaluation %v but got %v", want, got)
+	}
+}
+
+func TestDisableQuiescenceHangsQueen(t *testing.T) {
+	fen := "4k3/8/4p3/3p4/8/8/8/3QK3 w - - 0 1"
+	game := engine.ParseFen(fen)
+	capture := game.Position().ParseMove([]byte("d1d5"))
+
+	h := searchPosition(fen, 1, nil)
+	if h.Move.Move == capture {
+		t.Errorf("Expected quiescence to avoid %v", io.PrintMove(capture))
+	}
+
+	h = searchPosition(fen, 1, func(h *EngineHolder) {
+		h.Params.DisableQuiescence = true
+	})
+	if h.Move.Move != capture {
+		t.Errorf("Expected %v but got %v", io.PrintMove(capture), io.PrintMove(h.Move.Move))
+	}
+}
+
+func TestAvoidsStalemateWhenWinning(t *testing.T) {
+	for _, fen := range []string{
+		"8/8/8/8/8/1K6/7Q/k7 w - - 0 1",
+		"8/8/8/8/8/7k/8/6QK w - - 0 1",
+	} {
+		h := searchPosition(fen, 1, func(h *EngineHolder) {
+			h.Params.DisableQuiescence = true
+		})
+		game := engine.ParseFen(fen)
+		game.Position().MakeGameMove(h.Move.Move)
+		if outcome := game.Position().Outcome(); outcome == engine.OutcomeStalemate {
+			t.Errorf("%v: expected to avoid stalemate but played %v", fen, io.PrintMove(h.Move.Move))
+		}
+	}
+}
+
+func TestQuiescenceScoresStalemateAsDraw(t *testing.T) {
+	h := NewEngineHolder(1, eval.Get("custom"))
+	e := h.Engines[0]
+	game := engine.ParseFen("6k1/7p/8/8/8/p7/P1q5/K7 w - - 0 1")
+	e.Position = game.Position()
+
+	score := e.quiescence(-data.ABInfinite, data.ABInfinite, 0, 0, &data.SearchInfo{Depth: 1, StartTime: util.GetTimeMs()})
+	if score != 0 {
+		t.Errorf("Expected stalemate to score 0 but got %v", score)
+	}
+}
+
+func TestQuiescenceScoresInsufficientMaterialAsDraw(t *testing.T) {
+	h := NewEngineHolder(1, func() interface{} { return lostEvaluator{} })
+	e := h.Engines[0]
+	game := engine.ParseFen("8/8/4k3/8/2B5/8/3K4/8 w - - 0 1")
+	e.Position = game.Position()
+
+	score := e.quiescence(-data.ABInfinite, data.ABInfinite, 0, 0, &data.SearchInfo{Depth: 1, StartTime: util.GetTimeMs()})
+	if score != 0 {
+		t.Errorf("Expected king and bishop against king to score 0 but got %v", score)
+	}
+}
+
+func TestFindsStalemateSave(t *testing.T) {
+	// at depth 1 the stalemate after Rg8+ Kxg8 is only reached in
+	// quiescence, a full width search would find it without the check there
+	fen := "7k/7p/8/8/8/p1pq4/P7/K5R1 w - - 0 1"
+	game := engine.ParseFen(fen)
+	save := game.Position().ParseMove([]byte("g1g8"))
+
+	h := searchPosition(fen, 1, nil)
+	if h.Move.Move != save || h.Move.Score != 0 {
+		t.Errorf("Expected %v to draw but got %v scoring %v", io.PrintMove(save), io.PrintMove(h.Move.Move), h.Move.Score)
+	}
+}
+
+func TestUCIScorePawnUp(t *testing.T) {
+	h := searchPosition("4k3/1pp2ppp/8/8/8/8/PPP2PPP/4K3 w - - 0 1", 4, nil)
+	score := h.Engines[0].UCIScore(h.Move.Score)
+	var cp int
+	if _, err := fmt.Sscanf(score, "cp %d", &cp); err != nil {
+		t.Fatalf("Expected a centipawn score but got %v", score)
+	}
+	if cp < 60 || cp > 140 {
+		t.Errorf("Expected about cp 100 but got %v", score)
+	}
+}
+
+func TestUCIScoreMate(t *testing.T) {
+	h := searchPosition("6k1/5ppp/8/8/8/8/8/R5K1 w - - 0 1", 3, nil)
+	if score := h.Engines[0].UCIScore(h.Move.Score); score != "mate 1" {
+		t.Errorf("Expected mate 1 but got %v", score)
+	}
+}
+
+func TestContinuationHistoryUpdated(t *testing.T) {
+	h := searchPosition(data.StartFEN, 5, nil)
+	e := h.Engines[0]
+	if e.Position.MoveHistory.Continuation != e.continuation {
+		t.Fatalf("Expected the position to use the engine's continuation history")
+	}
+
+	updated := 0
+	for _, a := range e.continuation {
+		for _, b := range a {
+			for _, c := range b {
+				for _, score := range c {
+					if score != 0 {
+						updated++
+					}
+				}
+			}
+		}
+	}
+	if updated == 0 {
+		t.Errorf("Expected continuation scores to be updated by the search")
+	}
+}
+
+func TestSetPositionKeepsHistory(t *testing.T) {
+	h := searchPosition(data.StartFEN, 4, nil)
+	e := h.Engines[0]
+	before := e.Position.MoveHistory.History
+
+	game := engine.ParseFen(data.StartFEN)
+	e.SetPosition(game.Position().Copy())
+	if e.Position.MoveHistory.History != before {
+		t.Errorf("Expected the history to be kept by SetPosition")
+	}
+
+	e.ClearForSearch()
+	for piece := range before {
+		for sq := range before[piece] {
+			if want := before[piece][sq] / engine.DefaultHistoryDecay; e.Position.MoveHistory.History[piece][sq] != want {
+				t.Fatalf("Expected history %v to be aged to %v but got %v", before[piece][sq], want, e.Position.MoveHistory.History[piece][sq])
+			}
+		}
+	}
+}
+
+func TestQuiescenceMaxDepth(t *testing.T) {
+	fen := "2rr2k1/1b1q1pp1/1n3n1p/3p4/3P4/1N3N1P/1B1Q1PP1/2RR2K1 w - - 0 1"
+	full := searchPosition(fen, 1, nil)
+
+	h := searchPosition(fen, 1, func(h *EngineHolder) {
+		h.Params.QuiescenceMaxDepth = 2
+	})
+	e := h.Engines[0]
+	if e.QMaxDepthReached > 2+quiescenceCheckPlies {
+		t.Errorf("Expected quiescence to stop by ply %v but reached %v", 2+quiescenceCheckPlies, e.QMaxDepthReached)
+	}
+	if e.NodesVisited > full.Engines[0].NodesVisited {
+		t.Errorf("Expected at most %v nodes but searched %v", full.Engines[0].NodesVisited, e.NodesVisited)
+	}
+	if h.Move.Score < -300 || h.Move.Score > 300 {
+		t.Errorf("Expected a level score but got %v", h.Move.Score)
+	}
+}
+
+func TestSEEPruning(t *testing.T) {
+	fen := "4k3/8/2p5/3p4/8/8/8/3QK3 w - - 0 1"
+	h := NewEngineHolder(1, eval.Get("custom"))
+	e := h.Engines[0]
+	game := engine.ParseFen(fen)
+	e.Position = game.Position()
+	if !e.losesMaterial(e.Position.ParseMove([]byte("d1d5")), 1) {
+		t.Errorf("Expected d1d5 to be pruned")
+	}
+	if e.losesMaterial(e.Position.ParseMove([]byte("d1d4")), 1) {
+		t.Errorf("Expected d1d4 to be searched")
+	}
+
+	fen = "r1bqkb1r/pppp1ppp/2n2n2/4p3/2B1P3/5N2/PPPP1PPP/RNBQK2R w KQkq - 4 4"
+	h = searchPosition(fen, 5, nil)
+	if h.Engines[0].SEEPruned == 0 {
+		t.Errorf("Expected losing moves to be pruned")
+	}
+	h = searchPosition(fen, 5, func(h *EngineHolder) {
+		h.Params.DisableSEEPrune = true
+	})
+	if h.Engines[0].SEEPruned != 0 {
+		t.Errorf("Expected no moves to be pruned but %v were", h.Engines[0].SEEPruned)
+	}
+}
+
+func TestSEEPruningKeepsMatingSacrifice(t *testing.T) {
+	fen := "3r3k/6pp/7N/8/8/1Q6/6PP/6K1 w - - 0 1"
+	game := engine.ParseFen(fen)
+	sacrifice := game.Position().ParseMove([]byte("b3g8"))
+
+	h := searchPosition(fen, 4, nil)
+	if h.Move.Move != sacrifice {
+		t.Errorf("Expected %v but got %v", io.PrintMove(sacrifice), io.PrintMove(h.Move.Move))
+	}
+}
+
+func TestNullMoveVerification(t *testing.T) {
+	// Black must play a2 or Ka2 and is mated either way, passing would leave
+	// white without a quick win
+	fen := "7n/5p2/5Pp1/6P1/8/p7/2K5/k1N5 b - - 0 1"
+	nullWindow := func(verifyDepth int) int {
+		h := NewEngineHolder(1, eval.Get("custom"))
+		h.Params.NullMoveVerifyDepth = verifyDepth
+		e := h.Engines[0]
+		game := engine.ParseFen(fen)
+		e.Position = game.Position()
+		e.ClearForSearch()
+		// null moves are not tried at the root
+		e.Position.Play = 1
+		return e.alphaBeta(-201, -200, 8, 1, true, &data.SearchInfo{Depth: 8, StartTime: util.GetTimeMs()})
+	}
+
+	if score := nullWindow(0); score < -200 {
+		t.Errorf("Expected the null move to fail high but got %v", score)
+	}
+	if score := nullWindow(6); score >= -200 {
+		t.Errorf("Expected the verification search to fail low but got %v", score)
+	}
+}
+
+func TestQuiescenceFindsQuietMate(t *testing.T) {
+	h := NewEngineHolder(1, eval.Get("custom"))
+	e := h.Engines[0]
+	game := engine.ParseFen("6k1/5ppp/8/8/8/8/5PPP/R5K1 w - - 0 1")
+	e.Position = game.Position()
+
+	score := e.quiescence(-data.ABInfinite, data.ABInfinite, 0, 0, &data.SearchInfo{Depth: 1, StartTime: util.GetTimeMs()})
+	if score < data.Mate {
+		t.Errorf("Expected the back rank mate to be found but got %v", score)
+	}
+}
+
+func TestDisableQSearchChecks(t *testing.T) {
+	quiescence := func(disable bool) int {
+		h := NewEngineHolder(1, eval.Get("custom"))
+		h.Params.DisableQSearchChecks = disable
+		e := h.Engines[0]
+		// Nc7+ forks the king and rook
+		game := engine.ParseFen("r3k3/7p/8/3N4/8/8/7P/4K3 w - - 0 1")
+		e.Position = game.Position()
+		return e.quiescence(-data.ABInfinite, data.ABInfinite, 0, 0, &data.SearchInfo{Depth: 1, StartTime: util.GetTimeMs()})
+	}
+
+	checks, captures := quiescence(false), quiescence(true)
+	if checks <= 0 {
+		t.Errorf("Expected the fork to win the rook but got %v", checks)
+	}
+	if captures >= 0 {
+		t.Errorf("Expected captures only to miss the fork but got %v", captures)
+	}
+}
+
+func TestDisablePruning(t *testing.T) {
+	fen := "r1bqkb1r/pppp1ppp/2n2n2/4p3/2B1P3/5N2/PPPP1PPP/RNBQK2R w KQkq - 4 4"
+	base := searchPosition(fen, 6, nil).Engines[0].NodesVisited
+
+	toggles := map[string]func(h *EngineHolder){
+		"DisableNullMove":        func(h *EngineHolder) { h.Params.DisableNullMove = true },
+		"DisableReverseFutility": func(h *EngineHolder) { h.Params.DisableReverseFutility = true },
+		"DisableTT":              func(h *EngineHolder) { h.Params.DisableTT = true },
+		"DisableAspiration":      func(h *EngineHolder) { h.Params.DisableAspiration = true },
+		"DisableSEEPrune":        func(h *EngineHolder) { h.Params.DisableSEEPrune = true },
+		"DisableLMR":             func(h *EngineHolder) { h.Params.DisableLMR = true },
+	}
+	for name, toggle := range toggles {
+		if nodes := searchPosition(fen, 6, toggle).Engines[0].NodesVisited; nodes <= base {
+			t.Errorf("%v: expected more than %v nodes but searched %v", name, base, nodes)
+		}
+	}
+
+	fen = "r3k3/pp4pp/8/1N6/8/8/PP4PP/4K3 w - - 0 1"
+	game := engine.ParseFen(fen)
+	fork := game.Position().ParseMove([]byte("b5c7"))
+	h := searchPosition(fen, 4, func(h *EngineHolder) {
+		for _, toggle := range toggles {
+			toggle(h)
+		}
+	})
+	if h.Move.Move != fork {
+		t.Errorf("Expected %v but got %v", io.PrintMove(fork), io.PrintMove(h.Move.Move))
+	}
+}
+
+func TestAspirationWindows(t *testing.T) {
+	fen := "r1bqkb1r/pppp1ppp/2n2n2/4p3/2B1P3/5N2/PPPP1PPP/RNBQK2R w KQkq - 4 4"
+	full := searchPosition(fen, 7, func(h *EngineHolder) { h.Params.DisableAspiration = true })
+	def := searchPosition(fen, 7, nil)
+	narrow := searchPosition(fen, 7, func(h *EngineHolder) {
+		h.Params.AspirationDelta = 10
+		h.Params.AspirationMaxWindow = 10
+	})
+
+	fails := func(h *EngineHolder) int {
+		return h.Engines[0].AspirationFailHigh + h.Engines[0].AspirationFailLow
+	}
+	if fails(narrow) <= fails(def) {
+		t.Errorf("Expected more than %v aspiration fails with a narrow window but got %v", fails(def), fails(narrow))
+	}
+	for _, h := range []*EngineHolder{def, narrow} {
+		if h.Move.Move != full.Move.Move {
+			t.Errorf("Expected %v but got %v", io.PrintMove(full.Move.Move), io.PrintMove(h.Move.Move))
+		}
+	}
+
+	// a window no wider than a centipawn opens fully after the first fail,
+	// as the search used to
+	fen = "r3k2r/p1ppqpb1/bn2pnp1/3PN3/1p2P3/2N2Q1p/PPPBBPPP/R3K2R w KQkq - 0 1"
+	gradual := searchPosition(fen, 7, nil)
+	fixed := searchPosition(fen, 7, func(h *EngineHolder) { h.Params.AspirationMaxWindow = 1 })
+	if fails(gradual) == 0 {
+		t.Errorf("Expected the aspiration window to fail")
+	}
+	if gradual.Engines[0].NodesVisited >= fixed.Engines[0].NodesVisited {
+		t.Errorf("Expected fewer than %v nodes widening gradually but searched %v", fixed.Engines[0].NodesVisited, gradual.Engines[0].NodesVisited)
+	}
+}
+
+// lostEvaluator scores every position as lost for black
+type lostEvaluator struct{}
+
+func (lostEvaluator) Evaluate(p *engine.Position) int {
+	if p.Side == data.Black {
+		return -500
+	}
+	return 500
+}
+
+func TestSwindlePrefersMobility(t *testing.T) {
+	fen := "1n5k/6pp/8/8/8/8/8/K7 b - - 0 1"
+	game := engine.ParseFen(fen)
+	active := game.Position().ParseMove([]byte("b8c6"))
+
+	h := NewEngineHolder(1, func() interface{} { return lostEvaluator{} })
+	h.UseBook = false
+	h.Params.Swindle = true
+	h.Engines[0].Position = game.Position()
+	h.Search(&data.SearchInfo{Depth: 1, StartTime: util.GetTimeMs()})
+	if h.Move.Move != active {
+		t.Errorf("Expected %v but got %v", io.PrintMove(active), io.PrintMove(h.Move.Move))
+	}
+}
+
+func TestSearchScoresRepetitionAsDraw(t *testing.T) {
+	h := NewEngineHolder(1, eval.Get("custom"))
+	e := h.Engines[0]
+	game := engine.ParseFen("4k3/8/8/8/8/8/8/R3K3 w - - 0 1")
+	e.Position = game.Position()
+	e.ClearForSearch()
+	e.Position.Positions[e.Position.PositionKey] = 2
+
+	score := e.alphaBeta(-data.ABInfinite, data.ABInfinite, 3, 1, true, &data.SearchInfo{Depth: 3, StartTime: util.GetTimeMs()})
+	if score != 0 {
+		t.Errorf("Expected a repeated position to score 0 but got %v", score)
+	}
+}
+
+func TestSearchScoresThreefoldFromGame(t *testing.T) {
+	cycle := []string{"Nf3", "Nf6", "Ng1", "Ng8"}
+	for cycles, want := range []bool{false, true} {
+		game := engine.ParseFen(data.StartFEN)
+		p := game.Position()
+		moves := append(append([]string{}, cycle...), cycle[:3]...)
+		if cycles == 0 {
+			moves = cycle[:3]
+		}
+		for _, san := range moves {
+			p.MakeGameMove(p.ParseSAN(san))
+		}
+
+		h := NewEngineHolder(1, eval.Get("custom"))
+		e := h.Engines[0]
+		e.Position = p
+		e.ClearForSearch()
+		move := p.ParseSAN("Ng8")
+		e.Position.MakeMove(move)
+		if got := e.isRepetitionOrFiftyMove(); got != want {
+			t.Errorf("%v Ng8: expected a draw %v but got %v", moves, want, got)
+		}
+	}
+}
+
+func TestSearchRepeatedRootFindsMove(t *testing.T) {
+	game := engine.ParseFen(data.StartFEN)
+	p := game.Position()
+	for _, san := range []string{"Nf3", "Nf6", "Ng1", "Ng8"} {
+		p.MakeGameMove(p.ParseSAN(san))
+	}
+
+	h := NewEngineHolder(1, eval.Get("custom"))
+	h.UseBook = false
+	h.Engines[0].Position = p
+	h.Search(&data.SearchInfo{Depth: 3, StartTime: util.GetTimeMs()})
+	if h.Move.Move == data.NoMove {
+		t.Errorf("Expected a move from a root seen twice")
+	}
+}
+
+func TestSearchSkipsIllegalMoves(t *testing.T) {
+	fen := "4k3/4r3/8/8/8/8/4N3/4K3 w - - 0 1"
+	h := searchPosition(fen, 4, nil)
+	game := engine.ParseFen(fen)
+	p := game.Position()
+	if h.Move.Move == data.NoMove || !isLegalMove(p, h.Move.Move) {
+		t.Errorf("Expected a legal move but got %v", io.PrintMove(h.Move.Move))
+	}
+}
+
+// rootMoveEvaluator records the root move of every line it evaluates
+type rootMoveEvaluator struct {
+	IEvaluator
+	rootMoves map[int]bool
+}
+
+func (r rootMoveEvaluator) Evaluate(p *engine.Position) int {
+	if p.Play > 0 {
+		r.rootMoves[p.MoveHistory.Moves[1]] = true
+	}
+	return r.IEvaluator.Evaluate(p)
+}
+
+func TestSearchMoves(t *testing.T) {
+	game := engine.ParseFen("4k3/8/8/3q4/8/8/8/3RK3 w - - 0 1")
+	quiet := game.Position().ParseMove([]byte("e1f2"))
+
+	rootMoves := map[int]bool{}
+	evaluator := rootMoveEvaluator{eval.Get("custom")().(IEvaluator), rootMoves}
+	h := NewEngineHolder(1, func() interface{} { return evaluator })
+	h.UseBook = false
+	h.Engines[0].Position = game.Position()
+	for depth := 1; depth <= 5; depth++ {
+		h.Search(&data.SearchInfo{Depth: depth, StartTime: util.GetTimeMs(), SearchMoves: []int{quiet}})
+		if h.Move.Move != quiet {
+			t.Errorf("depth %v: expected %v but got %v", depth, io.PrintMove(quiet), io.PrintMove(h.Move.Move))
+		}
+	}
+	for move := range rootMoves {
+		if move != quiet {
+			t.Errorf("Expected only %v to be searched at the root but %v was", io.PrintMove(quiet), io.PrintMove(move))
+		}
+	}
+}
+
+func TestExcludeMoves(t *testing.T) {
+	fen := "4k3/8/4p3/3q4/4P3/8/8/3RK3 w - - 0 1"
+	game := engine.ParseFen(fen)
+	pawnTakes := game.Position().ParseMove([]byte("e4d5"))
+	rookTakes := game.Position().ParseMove([]byte("d1d5"))
+
+	h := searchPosition(fen, 4, nil)
+	if h.Move.Move != pawnTakes {
+		t.Fatalf("Expected %v but got %v", io.PrintMove(pawnTakes), io.PrintMove(h.Move.Move))
+	}
+
+	h = NewEngineHolder(1, eval.Get("custom"))
+	h.UseBook = false
+	h.Engines[0].Position = game.Position()
+	h.Search(&data.SearchInfo{Depth: 4, StartTime: util.GetTimeMs(), ExcludeMoves: []int{pawnTakes}})
+	if h.Move.Move != rookTakes {
+		t.Errorf("Expected %v but got %v", io.PrintMove(rookTakes), io.PrintMove(h.Move.Move))
+	}
+}
+
+func TestSearchVerifyHash(t *testing.T) {
+	fen := "r3k2r/p1ppqpb1/bn2pnp1/3PN3/1p2P3/2N2Q1p/PPPBBPPP/R3K2R w KQkq - 0 1"
+	h := searchPosition(fen, 4, func(h *EngineHolder) {
+		h.Params.VerifyHash = true
+	})
+	if !h.Engines[0].Position.VerifyHash {
+		t.Errorf("Expected the position to be verified during the search")
+	}
+}
+
+func TestHintFindsCapture(t *testing.T) {
+	game := engine.ParseFen("4k3/8/8/3q4/8/8/8/3RK3 w - - 0 1")
+	capture := game.Position().ParseMove([]byte("d1d5"))
+	fen := game.Position().ToFEN()
+
+	h := NewEngineHolder(1, eval.Get("custom"))
+	h.UseBook = true
+	var out bytes.Buffer
+	h.Output = &out
+	ctx, previous := h.Ctx, data.Move{Move: capture, Depth: 1}
+	h.Move = previous
+	hint := h.Hint(context.Background(), game.Position(), 4)
+	if hint.Move != capture {
+		t.Errorf("Expected %v but got %v", io.PrintMove(capture), io.PrintMove(hint.Move))
+	}
+	if game.Position().ToFEN() != fen {
+		t.Errorf("Expected the position to be left at %v but got %v", fen, game.Position().ToFEN())
+	}
+	if !h.UseBook {
+		t.Errorf("Expected the book setting to be restored")
+	}
+	if out.Len() > 0 {
+		t.Errorf("Expected nothing to be written but got %q", out.String())
+	}
+	if h.Ctx != ctx || h.Ctx.Err() != nil || h.Move != previous {
+		t.Errorf("Expected the context and move %+v to be restored but got %+v", previous, h.Move)
+	}
+}
+
+func TestHintCancelledMidSearchKeepsLastDepth(t *testing.T) {
+	game := engine.ParseFen("r1bqkb1r/pppp1ppp/2n2n2/4p3/2B1P3/5N2/PPPP1PPP/RNBQK2R w KQkq - 0 1")
+	h := NewEngineHolder(1, eval.Get("custom"))
+	h.UseBook = false
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+
+	hint := h.Hint(ctx, game.Position(), data.MaxDepth)
+	if !game.Position().IsLegalMove(hint.Move) {
+		t.Fatalf("Expected a legal move from the cancelled search but got %v", io.PrintMove(hint.Move))
+	}
+	if h.Termination != TerminationCancelled {
+		t.Errorf("Expected the search to be cancelled but it stopped on %v", h.Termination)
+	}
+	breakdown := h.Engines[0].DepthBreakdown
+	if len(breakdown) == 0 || breakdown[len(breakdown)-1].Depth >= data.MaxDepth {
+		t.Fatalf("Expected some but not every depth to complete but got %v", breakdown)
+	}
+	if last := breakdown[len(breakdown)-1]; last.Move != hint.Move {
+		t.Errorf("Expected the move of depth %v %v but got %v", last.Depth, io.PrintMove(last.Move), io.PrintMove(hint.Move))
+	}
+}
+
+func TestPonderMove(t *testing.T) {
+	tests := []struct {
+		name   string
+		fen    string
+		ponder string
+	}{
+		// either rook check is only met by blocking with the rook
+		{"forced reply", "7k/3r2pp/8/8/8/8/1R6/R5K1 w - - 0 1", "d7d8"},
+		// there is no reply to mate
+		{"mate", "6k1/5ppp/8/8/8/8/8/3R2K1 w - - 0 1", ""},
+	}
+	for _, tt := range tests {
+		game := engine.ParseFen(tt.fen)
+		h := NewEngineHolder(1, eval.Get("custom"))
+		h.UseBook = false
+		hint := h.Hint(context.Background(), game.Position(), 4)
+		ponder := ""
+		if hint.Ponder != data.NoMove {
+			ponder = io.PrintMove(hint.Ponder)
+		}
+		if ponder != tt.ponder {
+			t.Errorf("%v: expected ponder %q after %v but got %q", tt.name, tt.ponder, io.PrintMove(hint.Move), ponder)
+		}
+	}
+}
+
+func TestTerminationReason(t *testing.T) {
+	tests := []struct {
+		name   string
+		fen    string
+		info   data.SearchInfo
+		cancel bool
+		want   TerminationReason
+	}{
+		{"depth", data.StartFEN, data.SearchInfo{Depth: 3}, false, TerminationDepth},
+		{"time", data.StartFEN, data.SearchInfo{Depth: data.MaxDepth, TimeSet: data.True}, false, TerminationTime},
+		{"nodes", data.StartFEN, data.SearchInfo{Depth: data.MaxDepth, NodeLimit: 10000}, false, TerminationNodes},
+		{"mate", "6k1/5ppp/8/8/8/8/8/R5K1 w - - 0 1", data.SearchInfo{Depth: data.MaxDepth}, false, TerminationMate},
+		{"cancel", data.StartFEN, data.SearchInfo{Depth: data.MaxDepth}, true, TerminationCancelled},
+	}
+	for _, tt := range tests {
+		h := NewEngineHolder(1, eval.Get("custom"))
+		h.UseBook = false
+		game := engine.ParseFen(tt.fen)
+		h.Engines[0].Position = game.Position().Copy()
+
+		info := tt.info
+		info.StartTime = util.GetTimeMs()
+		info.StopTime = info.StartTime + 10
+		done := make(chan struct{})
+		go func() {
+			h.Search(&info)
+			close(done)
+		}()
+		if tt.cancel {
+			time.Sleep(100 * time.Millisecond)
+			h.CancelSearch()
+		}
+		select {
+		case <-done:
+		case <-time.After(10 * time.Second):
+			t.Fatalf("%v: expected the search to stop", tt.name)
+		}
+
+		if h.Termination != tt.want {
+			t.Errorf("%v: expected %v but got %v", tt.name, tt.want, h.Termination)
+		}
+	}
+}
+
+func TestNodeLimitAppliesToEachSearch(t *testing.T) {
+	h := NewEngineHolder(1, eval.Get("custom"))
+	h.UseBook = false
+	e := h.Engines[0]
+	fens := []string{data.StartFEN, "r1bqkb1r/pppp1ppp/2n2n2/4p3/2B1P3/5N2/PPPP1PPP/RNBQK2R w KQkq - 4 4"}
+	for _, fen := range fens {
+		game := engine.ParseFen(fen)
+		e.Position = game.Position().Copy()
+		h.Ctx, h.CancelSearch = context.WithCancel(context.Background())
+		h.Search(&data.SearchInfo{Depth: data.MaxDepth, NodeLimit: 20480, StartTime: util.GetTimeMs()})
+
+		if h.Termination != TerminationNodes || len(e.DepthBreakdown) < 4 {
+			t.Errorf("%v: expected to reach depth 4 before stopping on nodes but stopped on %v after %v depths",
+				fen, h.Termination, len(e.DepthBreakdown))
+		}
+	}
+}
+
+func TestInfiniteSearchStops(t *testing.T) {
+	h := NewEngineHolder(1, eval.Get("custom"))
+	h.UseBook = false
+	game := engine.ParseFen(data.StartFEN)
+	e := h.Engines[0]
+	e.Position = game.Position().Copy()
+	key := e.Position.PositionKey
+
+	done := make(chan struct{})
+	go func() {
+		h.Search(&data.SearchInfo{Depth: 1, Infinite: data.True, StartTime: util.GetTimeMs()})
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		t.Fatalf("Expected the search to run until cancelled")
+	case <-time.After(300 * time.Millisecond):
+	}
+	h.CancelSearch()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatalf("Expected the search to stop after being cancelled")
+	}
+
+	if e.Position.PositionKey != key || e.Position.Play != 0 {
+		t.Errorf("Expected the position to be restored after stopping")
+	}
+	if h.Termination != TerminationCancelled {
+		t.Errorf("Expected %v but got %v", TerminationCancelled, h.Termination)
+	}
+	if h.Move.Move == data.NoMove || !isLegalMove(game.Position(), h.Move.Move) {
+		t.Errorf("Expected a legal move but got %v", io.PrintMove(h.Move.Move))
+	}
+}
+
+func TestContemptAvoidsDraw(t *testing.T) {
+	fen := "rnbqkbnr/pppppppp/8/8/4P3/8/PPPP1PPP/RNBQKBNR b KQkq - 0 1"
+	search := func(contempt int) int {
+		h := NewEngineHolder(1, eval.Get("custom"))
+		h.UseBook = false
+		h.Params.Contempt = contempt
+		game := engine.ParseFen(fen)
+		p := game.Position()
+		// Nf6 repeats a position already seen twice
+		repeat := p.ParseMove([]byte("g8f6"))
+		_, enPas, castlePerm, fifty := p.MakeMove(repeat)
+		p.Positions[p.PositionKey] = 2
+		p.TakeMoveBack(repeat, enPas, castlePerm, fifty)
+		h.Engines[0].Position = p
+		h.Search(&data.SearchInfo{Depth: 5, StartTime: util.GetTimeMs()})
+		return h.Move.Move
+	}
+
+	game := engine.ParseFen(fen)
+	repeat := game.Position().ParseMove([]byte("g8f6"))
+	if move := search(0); move != repeat {
+		t.Errorf("Expected the draw %v without contempt but got %v", io.PrintMove(repeat), io.PrintMove(move))
+	}
+	if move := search(100); move == repeat {
+		t.Errorf("Expected contempt to avoid the draw %v", io.PrintMove(repeat))
+	}
+}
+
+func TestContemptTapersByPhase(t *testing.T) {
+	drawScore := func(fen string) int {
+		h := NewEngineHolder(1, eval.Get("custom"))
+		h.Params.Contempt = 50
+		game := engine.ParseFen(fen)
+		e := h.Engines[0]
+		e.Position = game.Position()
+		e.rootSide = e.Position.Side
+		return e.drawScore()
+	}
+
+	full := drawScore(data.StartFEN)
+	pawns := drawScore("4k3/4p3/8/8/8/8/4P3/4K3 w - - 0 1")
+	if full != -50 || pawns != 0 {
+		t.Errorf("Expected a draw scored -50 with every piece and 0 with only pawns but got %v and %v", full, pawns)
+	}
+	if middle := drawScore("r3k3/pppq1ppp/8/8/8/8/PPPQ1PPP/R3K3 w - - 0 1"); middle >= 0 || middle <= full {
+		t.Errorf("Expected a draw with some pieces between %v and 0 but got %v", full, middle)
+	}
+}
+
+func TestDepthBreakdown(t *testing.T) {
+	h := searchPosition("r3k2r/p1ppqpb1/bn2pnp1/3PN3/1p2P3/2N2Q1p/PPPBBPPP/R3K2R w KQkq - 0 1", 5, nil)
+	e := h.Engines[0]
+
+	if len(e.DepthBreakdown) != h.Move.Depth {
+		t.Fatalf("Expected %v depths but got %v", h.Move.Depth, len(e.DepthBreakdown))
+	}
+	nodes, qNodes := 0, 0
+	for i, info := range e.DepthBreakdown {
+		if info.Depth != i+1 {
+			t.Errorf("Expected depth %v but got %v", i+1, info.Depth)
+		}
+		nodes += info.Nodes
+		qNodes += info.QNodes
+	}
+	if nodes != e.NodesVisited || qNodes != e.QNodesVisited || qNodes == 0 {
+		t.Errorf("Expected %v nodes and %v qnodes but the breakdown has %v and %v", e.NodesVisited, e.QNodesVisited, nodes, qNodes)
+	}
+
+	last := e.DepthBreakdown[len(e.DepthBreakdown)-1]
+	if last.Move != h.Move.Move || last.Score != h.Move.Score {
+		t.Errorf("Expected the last depth to match %v %v but got %v %v", io.PrintMove(h.Move.Move), h.Move.Score, io.PrintMove(last.Move), last.Score)
+	}
+}
+
+func TestLMRDivisor(t *testing.T) {
+	fen := "r1bqkb1r/pppp1ppp/2n2n2/4p3/2B1P3/5N2/PPPP1PPP/RNBQK2R w KQkq - 4 4"
+	h := NewEngineHolder(1, eval.Get("custom"))
+	e := h.Engines[0]
+	game := engine.ParseFen(fen)
+	e.Position = game.Position()
+	move := e.Position.ParseMove([]byte("a2a3"))
+	e.Position.MakeMove(move)
+	reduction := e.lateMoveReduction(move, 10, 20)
+	h.Params.LMRDivisor = 450
+	if smaller := e.lateMoveReduction(move, 10, 20); smaller >= reduction {
+		t.Errorf("Expected a larger divisor to reduce less than %v but got %v", reduction, smaller)
+	}
+
+	h = searchPosition(fen, 6, nil)
+	reductions := h.Engines[0].LMRReductions
+	if reductions == 0 {
+		t.Fatalf("Expected late moves to be reduced")
+	}
+	h = searchPosition(fen, 6, func(h *EngineHolder) {
+		h.Params.LMRDivisor = 1000
+	})
+	if h.Engines[0].LMRReductions >= reductions {
+		t.Errorf("Expected fewer than %v reductions but got %v", reductions, h.Engines[0].LMRReductions)
+	}
+}
+
+func TestLMRKeepsPassedPawnPush(t *testing.T) {
+	fen := "8/8/8/1P6/8/8/6k1/K7 w - - 0 1"
+	h := NewEngineHolder(1, eval.Get("custom"))
+	e := h.Engines[0]
+	game := engine.ParseFen(fen)
+	e.Position = game.Position()
+	push := e.Position.ParseMove([]byte("b5b6"))
+	e.Position.MakeMove(push)
+	if reduction := e.lateMoveReduction(push, 10, 20); reduction != 0 {
+		t.Errorf("Expected b5b6 not to be reduced but it was reduced by %v", reduction)
+	}
+	h.Params.DisableLMRExemptions = true
+	if e.lateMoveReduction(push, 10, 20) == 0 {
+		t.Errorf("Expected b5b6 to be reduced with the exemptions disabled")
+	}
+
+	h = searchPosition(fen, 8, nil)
+	if h.Move.Move != push {
+		t.Errorf("Expected %v but got %v", io.PrintMove(push), io.PrintMove(h.Move.Move))
+	}
+}
+
+func TestRazoringModes(t *testing.T) {
+	fen := "r1bqkb1r/pppp1ppp/2n2n2/4p3/2B1P3/5N2/PPPP1PPP/RNBQK2R w KQkq - 4 4"
+	for _, mode := range []RazoringMode{RazorQSearch, RazorReturn} {
+		h := searchPosition(fen, 6, func(h *EngineHolder) {
+			h.Params.Razoring = mode
+		})
+		game := engine.ParseFen(fen)
+		if !isLegalMove(game.Position(), h.Move.Move) {
+			t.Errorf("%v: expected a legal move but got %v", mode, io.PrintMove(h.Move.Move))
+		}
+		if h.Engines[0].RazorAttempts == 0 {
+			t.Errorf("%v: expected razoring to be tried", mode)
+		}
+
+		h = NewEngineHolder(1, eval.Get("custom"))
+		h.Params.Razoring = mode
+		e := h.Engines[0]
+		game = engine.ParseFen(fen)
+		e.Position = game.Position()
+		e.ClearForSearch()
+		_, ok := e.razor(1000, 1001, 2, 0, 0, &data.SearchInfo{})
+		if !ok {
+			t.Errorf("%v: expected a node far below alpha to be razored", mode)
+		}
+		if called := e.QNodesVisited > 0; called != (mode == RazorQSearch) {
+			t.Errorf("%v: expected quiescence to be called %v but it was %v", mode, mode == RazorQSearch, called)
+		}
+	}
+}
+
+func TestSearchKeepsFiftyMoveClock(t *testing.T) {
+	fen := "4k3/8/8/8/8/8/8/R3K3 w - - 0 1"
+	h := searchPosition(fen, 4, nil)
+	if h.Move.Score < 300 {
+		t.Fatalf("Expected a winning score with a fresh clock but got %v", h.Move.Score)
+	}
+
+	h = NewEngineHolder(1, eval.Get("custom"))
+	h.UseBook = false
+	game := engine.ParseFen(fen)
+	h.Engines[0].Position = game.Position()
+	h.Engines[0].Position.FiftyMove = 99
+	h.Search(&data.SearchInfo{Depth: 4, StartTime: util.GetTimeMs()})
+	if h.Move.Score != 0 {
+		t.Errorf("Expected every move to reach the fifty move rule but got %v", h.Move.Score)
+	}
+}
+
+func TestWinningSideAvoidsRepetition(t *testing.T) {
+	// after these moves the knight going back to e3 repeats the start
+	game, err := engine.ParseFenWithMoves("8/5k2/8/8/8/4N3/5PPP/6K1 b - - 0 1", []string{"f7e7", "e3d5", "e7f7"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := game.Position()
+	repeat := p.ParseMove([]byte("d5e3"))
+
+	h := NewEngineHolder(1, eval.Get("custom"))
+	h.UseBook = false
+	h.Engines[0].Position = p
+	h.Search(&data.SearchInfo{Depth: 6, StartTime: util.GetTimeMs()})
+	if h.Move.Move == repeat {
+		t.Errorf("Expected a move other than %v", io.PrintMove(repeat))
+	}
+	if h.Move.Score < repetitionWinningMargin {
+		t.Errorf("Expected a winning score but got %v", h.Move.Score)
+	}
+}
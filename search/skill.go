@@ -0,0 +1,90 @@
+package search
+
+import (
+	"context"
+
+	"github.com/AdamGriffiths31/ChessEngine/data"
+)
+
+// MaxWeakness is the weakest the engine can be made to play
+const MaxWeakness = 20
+
+// skillCandidates is how many of the best root moves a weakened engine
+// chooses between
+const skillCandidates = 4
+
+// skillMaxDepth is the deepest a search at the weakness goes, each step of
+// weakness takes off half a ply
+func skillMaxDepth(weakness int) int {
+	return 1 + (MaxWeakness-weakness)/2
+}
+
+// skillMove finds the next best root moves after the move of the search just
+// finished by searching again with the moves already found excluded, then
+// picks between them. A candidate's chance of being picked falls the further
+// it scores below the best, and falls more slowly the weaker the engine is.
+// The searches share the limits of the first, so the moves found before the
+// time runs out or the search is stopped through Ctx or info are used
+func (h *EngineHolder) skillMove(info *data.SearchInfo) data.Move {
+	best := h.Move
+	e := h.Engines[0]
+	legal := len(e.Position.LegalMoves())
+	if best.Move == data.NoMove || legal < 2 {
+		return best
+	}
+
+	isMain, breakdown := e.IsMainEngine, e.DepthBreakdown
+	e.IsMainEngine = false
+	defer func() { e.IsMainEngine, e.DepthBreakdown = isMain, breakdown }()
+
+	candidates := []data.Move{best}
+	excluded := append([]int{}, info.ExcludeMoves...)
+	for len(candidates) < skillCandidates && len(candidates) < legal {
+		if h.Termination != TerminationDepth || info.ForceStop || h.Ctx.Err() != nil {
+			break
+		}
+		excluded = append(excluded, candidates[len(candidates)-1].Move)
+		h.workerCtx, h.stopWorkers = context.WithCancel(h.Ctx)
+		e.SearchRoot(&data.SearchInfo{
+			Depth:        best.Depth,
+			TimeSet:      info.TimeSet,
+			StartTime:    info.StartTime,
+			StopTime:     info.StopTime,
+			NodeLimit:    info.NodeLimit,
+			SearchMoves:  info.SearchMoves,
+			ExcludeMoves: excluded,
+		})
+		h.stopWorkers()
+		n := len(e.DepthBreakdown)
+		if n == 0 {
+			break
+		}
+		last := e.DepthBreakdown[n-1]
+		if last.Depth < best.Depth || last.Move == data.NoMove || containsMove(excluded, last.Move) {
+			break
+		}
+		candidates = append(candidates, data.Move{Move: last.Move, Score: last.Score, Depth: last.Depth})
+	}
+	return h.pickSkillMove(candidates)
+}
+
+// pickSkillMove picks between the candidates, best first, the same way as
+// Stockfish. Each score is pulled towards the best by the weakness and a
+// random push of up to a pawn is added, the highest total is played
+func (h *EngineHolder) pickSkillMove(candidates []data.Move) data.Move {
+	weakness := 120 - 2*(MaxWeakness-h.Params.Weakness)
+	top := candidates[0].Score
+	delta := top - candidates[len(candidates)-1].Score
+	if delta > data.PieceVal[data.WP] {
+		delta = data.PieceVal[data.WP]
+	}
+
+	chosen, max := candidates[0], -data.ABInfinite
+	for _, c := range candidates {
+		push := (weakness*(top-c.Score) + delta*h.rng.Intn(weakness)) / 128
+		if c.Score+push > max {
+			chosen, max = c, c.Score+push
+		}
+	}
+	return chosen
+}
@@ -0,0 +1,105 @@
+package search
+
+import (
+	"context"
+	"math/rand"
+	"testing"
+	"time"
+
+	"github.com/AdamGriffiths31/ChessEngine/data"
+	"github.com/AdamGriffiths31/ChessEngine/engine"
+	"github.com/AdamGriffiths31/ChessEngine/eval"
+	"github.com/AdamGriffiths31/ChessEngine/io"
+	"github.com/AdamGriffiths31/ChessEngine/util"
+)
+
+// skillHints returns the moves of n searches of the fen at the weakness
+func skillHints(fen string, weakness, depth, n int) map[int]int {
+	h := NewEngineHolder(1, eval.Get("custom"))
+	h.UseBook = false
+	h.Params.Weakness = weakness
+	h.rng = rand.New(rand.NewSource(1))
+	moves := map[int]int{}
+	for i := 0; i < n; i++ {
+		h.NewGame()
+		game := engine.ParseFen(fen)
+		moves[h.Hint(context.Background(), game.Position(), depth).Move]++
+	}
+	return moves
+}
+
+func TestWeakestSkillSometimesPlaysOtherMoves(t *testing.T) {
+	moves := skillHints(data.StartFEN, MaxWeakness, 6, 20)
+	if len(moves) < 2 {
+		t.Errorf("Expected more than one move at the weakest skill but got %v", moves)
+	}
+	game := engine.ParseFen(data.StartFEN)
+	for move := range moves {
+		if !game.Position().IsLegalMove(move) {
+			t.Errorf("Expected legal moves but got %v", io.PrintMove(move))
+		}
+	}
+}
+
+func TestFullSkillAlwaysPlaysBestMove(t *testing.T) {
+	fen := "r1bqkb1r/pppp1ppp/2n2n2/4p3/2B1P3/5N2/PPPP1PPP/RNBQK2R w KQkq - 0 1"
+	moves := skillHints(fen, 0, 4, 5)
+	if len(moves) != 1 {
+		t.Errorf("Expected the same move every time at full skill but got %v", moves)
+	}
+}
+
+func TestSkillCapsDepth(t *testing.T) {
+	h := NewEngineHolder(1, eval.Get("custom"))
+	h.UseBook = false
+	h.Params.Weakness = MaxWeakness
+	game := engine.ParseFen(data.StartFEN)
+	if move := h.Hint(context.Background(), game.Position(), 8); move.Depth != skillMaxDepth(MaxWeakness) {
+		t.Errorf("Expected the search to stop at depth %v but got %v", skillMaxDepth(MaxWeakness), move.Depth)
+	}
+}
+
+func TestSkillKeepsToTheClock(t *testing.T) {
+	h := NewEngineHolder(1, eval.Get("custom"))
+	h.UseBook = false
+	h.Params.Weakness = 1
+	h.Params.DisableQuickMoves = true
+	start := time.Now()
+	timedSearch(h, "r1bqkb1r/pppp1ppp/2n2n2/4p3/2B1P3/5N2/PPPP1PPP/RNBQK2R w KQkq - 0 1", 300)
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Errorf("Expected a weakened search to keep to 300ms but took %v", elapsed)
+	}
+	if h.Move.Move == data.NoMove {
+		t.Errorf("Expected a move")
+	}
+}
+
+func TestSkillStopsWhenCancelled(t *testing.T) {
+	h := NewEngineHolder(1, eval.Get("custom"))
+	h.UseBook = false
+	h.Params.Weakness = 1
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	game := engine.ParseFen("r1bqkb1r/pppp1ppp/2n2n2/4p3/2B1P3/5N2/PPPP1PPP/RNBQK2R w KQkq - 0 1")
+	start := time.Now()
+	if move := h.Hint(ctx, game.Position(), data.MaxDepth); move.Move == data.NoMove {
+		t.Errorf("Expected a move")
+	}
+	if elapsed := time.Since(start); elapsed > 400*time.Millisecond {
+		t.Errorf("Expected a cancelled weakened search to stop but took %v", elapsed)
+	}
+}
+
+func TestSkillLeavesCallerContext(t *testing.T) {
+	h := NewEngineHolder(1, eval.Get("custom"))
+	h.UseBook = false
+	h.Params.Weakness = MaxWeakness
+	ctx, cancel := h.Ctx, h.CancelSearch
+	defer cancel()
+	game := engine.ParseFen(data.StartFEN)
+	h.Engines[0].SetPosition(game.Position())
+	h.Search(&data.SearchInfo{Depth: 4, StartTime: util.GetTimeMs()})
+	if h.Ctx != ctx || ctx.Err() != nil {
+		t.Errorf("Expected the search to leave the caller's context as it was")
+	}
+}
@@ -0,0 +1,63 @@
+package search
+
+import (
+	"github.com/AdamGriffiths31/ChessEngine/data"
+	"github.com/AdamGriffiths31/ChessEngine/util"
+)
+
+// TerminationReason is why the last search stopped
+type TerminationReason int
+
+const (
+	// TerminationNone is used before a search has finished
+	TerminationNone TerminationReason = iota
+	// TerminationDepth means every depth asked for was searched
+	TerminationDepth
+	// TerminationTime means the time for the move ran out
+	TerminationTime
+	// TerminationNodes means the node limit was reached
+	TerminationNodes
+	// TerminationMate means a mate was found that deeper searches cannot
+	// improve on
+	TerminationMate
+	// TerminationCancelled means the search was told to stop from outside
+	TerminationCancelled
+)
+
+func (r TerminationReason) String() string {
+	switch r {
+	case TerminationDepth:
+		return "depth"
+	case TerminationTime:
+		return "time"
+	case TerminationNodes:
+		return "nodes"
+	case TerminationMate:
+		return "mate"
+	case TerminationCancelled:
+		return "cancelled"
+	}
+	return "none"
+}
+
+// stoppedReason works out why a search was stopped, a helper thread can stop
+// the search first so the reason is read from the limits rather than from
+// whichever thread noticed
+func (e *Engine) stoppedReason(info *data.SearchInfo) TerminationReason {
+	if info.ForceStop || e.Parent.Ctx.Err() != nil {
+		return TerminationCancelled
+	}
+	if info.TimeSet == data.True && util.GetTimeMs() > info.StopTime {
+		return TerminationTime
+	}
+	return TerminationNodes
+}
+
+// isProvenMate checks if the score is a mate the search has looked deep
+// enough to see all of, searching deeper cannot find a shorter one
+func (e *Engine) isProvenMate(score, depth int) bool {
+	if score > -data.Mate && score < data.Mate {
+		return false
+	}
+	return data.ABInfinite-util.Abs(score)-e.Position.Play <= depth
+}
@@ -0,0 +1,71 @@
+package search
+
+import (
+	"github.com/AdamGriffiths31/ChessEngine/data"
+	"github.com/AdamGriffiths31/ChessEngine/engine"
+)
+
+// MaxTimeJitter is the largest percent of the time for a move TimeJitter can
+// take off
+const MaxTimeJitter = 50
+
+// recaptureTimeDivisor divides the time for a move that is an obvious
+// recapture
+const recaptureTimeDivisor = 4
+
+// adjustTime shortens the time for a timed search. With one legal move only
+// depth 1 is searched, an obvious recapture gets a share of the time and
+// TimeJitter takes a random share off what is left
+func (h *EngineHolder) adjustTime(info *data.SearchInfo) {
+	if info.TimeSet != data.True {
+		return
+	}
+	budget := info.StopTime - info.StartTime
+	if !h.Params.DisableQuickMoves {
+		p := h.Engines[0].Position
+		moves := p.LegalMoves()
+		if len(moves) == 1 {
+			info.Depth = 1
+			return
+		}
+		if isObviousRecapture(p, moves) {
+			budget /= recaptureTimeDivisor
+		}
+	}
+	if jitter := h.Params.TimeJitter; jitter > 0 {
+		if jitter > MaxTimeJitter {
+			jitter = MaxTimeJitter
+		}
+		budget -= budget * int64(h.rng.Intn(jitter+1)) / 100
+	}
+	info.StopTime = info.StartTime + budget
+}
+
+// isObviousRecapture checks if the side to move is behind on material and
+// exactly one of the moves is a capture that wins it all back, as when the
+// opponent has just taken a piece that can be taken back
+func isObviousRecapture(p *engine.Position, moves []int) bool {
+	deficit := material(p, p.Side^1) - material(p, p.Side)
+	if deficit <= 0 {
+		return false
+	}
+	recaptures := 0
+	for _, move := range moves {
+		if move&data.MFLAGCAP != 0 && p.SEE(move) >= deficit {
+			recaptures++
+		}
+	}
+	return recaptures == 1
+}
+
+// material is the value of side's pieces other than the king
+func material(p *engine.Position, side int) int {
+	total := 0
+	for sq := 0; sq < 64; sq++ {
+		piece := p.Board.PieceAt(sq)
+		if piece != data.Empty && piece != data.WK && piece != data.BK && data.PieceCol[piece] == side {
+			total += data.PieceVal[piece]
+		}
+	}
+	return total
+}
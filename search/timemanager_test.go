@@ -0,0 +1,76 @@
+package search
+
+import (
+	"math/rand"
+	"testing"
+	"time"
+
+	"github.com/AdamGriffiths31/ChessEngine/data"
+	"github.com/AdamGriffiths31/ChessEngine/engine"
+	"github.com/AdamGriffiths31/ChessEngine/eval"
+	"github.com/AdamGriffiths31/ChessEngine/util"
+)
+
+// timedSearch searches the fen with a move time of budget
+func timedSearch(h *EngineHolder, fen string, budget int64) *data.SearchInfo {
+	game := engine.ParseFen(fen)
+	h.Engines[0].SetPosition(game.Position())
+	start := util.GetTimeMs()
+	info := &data.SearchInfo{Depth: data.MaxDepth, TimeSet: data.True, StartTime: start, StopTime: start + budget}
+	h.Search(info)
+	return info
+}
+
+func TestSingleLegalMoveReturnsImmediately(t *testing.T) {
+	h := NewEngineHolder(1, eval.Get("custom"))
+	h.UseBook = false
+	start := time.Now()
+	timedSearch(h, "k7/8/8/8/8/8/1r6/K1r5 w - - 0 1", 5000)
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Expected the only move to be played at once but took %v", elapsed)
+	}
+	if got := engine.ParseFen("k7/8/8/8/8/8/1r6/K1r5 w - - 0 1"); !got.Position().IsLegalMove(h.Move.Move) {
+		t.Errorf("Expected the only legal move but got %v", h.Move.Move)
+	}
+}
+
+func TestObviousRecapture(t *testing.T) {
+	tests := []struct {
+		fen  string
+		want bool
+	}{
+		// the knight on d5 has just taken a pawn and can be taken back
+		{"4k3/8/8/3n4/4P3/8/8/4K3 w - - 0 1", true},
+		// level material
+		{data.StartFEN, false},
+		// behind but nothing to take
+		{"4k3/8/8/3n4/8/8/8/4K3 w - - 0 1", false},
+	}
+	for _, tt := range tests {
+		game := engine.ParseFen(tt.fen)
+		p := game.Position()
+		if got := isObviousRecapture(p, p.LegalMoves()); got != tt.want {
+			t.Errorf("%v: expected %v but got %v", tt.fen, tt.want, got)
+		}
+	}
+}
+
+func TestTimeJitterStaysInBounds(t *testing.T) {
+	h := NewEngineHolder(1, eval.Get("custom"))
+	h.rng = rand.New(rand.NewSource(1))
+	h.Params.TimeJitter = 100
+	game := engine.ParseFen(data.StartFEN)
+	h.Engines[0].SetPosition(game.Position())
+	seen := map[int64]bool{}
+	for i := 0; i < 50; i++ {
+		info := &data.SearchInfo{TimeSet: data.True, StartTime: 0, StopTime: 1000}
+		h.adjustTime(info)
+		if info.StopTime < 1000*(100-MaxTimeJitter)/100 || info.StopTime > 1000 {
+			t.Fatalf("Expected the time to stay within the jitter but got %v", info.StopTime)
+		}
+		seen[info.StopTime] = true
+	}
+	if len(seen) < 2 {
+		t.Errorf("Expected the time to vary but got %v", seen)
+	}
+}
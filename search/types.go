@@ -2,6 +2,11 @@ package search
 
 import (
 	"context"
+	stdio "io"
+	"log"
+	"math/rand"
+	"os"
+	"time"
 
 	"github.com/AdamGriffiths31/ChessEngine/data"
 	"github.com/AdamGriffiths31/ChessEngine/engine"
@@ -11,8 +16,49 @@ type Engine struct {
 	Position     *engine.Position
 	IsMainEngine bool
 	Parent       *EngineHolder
+	// NodesVisited is the number of nodes in the last search
 	NodesVisited int
-	evaluator    IUpdatableEvaluator
+	// QNodesVisited is the number of quiescence nodes in the last search,
+	// they are also counted in NodesVisited
+	QNodesVisited int
+	// QMaxDepthReached is the deepest quiescence ply reached in the last search
+	QMaxDepthReached int
+	// SEEPruned is the number of moves skipped for losing material
+	SEEPruned int
+	// LMRReductions is the number of moves searched at a reduced depth in
+	// the last search
+	LMRReductions int
+	// RazorAttempts is the number of nodes razoring was tried at in the last
+	// search, RazorCutoffs those it returned early from and RazorFailed those
+	// that had to be searched anyway
+	RazorAttempts int
+	RazorCutoffs  int
+	RazorFailed   int
+	// OracleHits is the number of positions in the last search scored by
+	// the endgame oracle instead of being searched
+	OracleHits int
+	// AspirationFailHigh and AspirationFailLow count the root searches that
+	// fell outside the aspiration window
+	AspirationFailHigh int
+	AspirationFailLow  int
+	// DepthBreakdown holds an entry for each depth completed in the last
+	// search
+	DepthBreakdown []DepthInfo
+	evaluator      IUpdatableEvaluator
+	continuation   *engine.ContinuationHistory
+	// rootSide is the side to move at the root, used to apply contempt
+	rootSide int
+}
+
+// DepthInfo is the work done by one iteration of the search and its result
+type DepthInfo struct {
+	Depth  int
+	Nodes  int
+	QNodes int
+	// Time is how long the iteration took in milliseconds
+	Time  int64
+	Move  int
+	Score int
 }
 
 type EngineHolder struct {
@@ -25,6 +71,174 @@ type EngineHolder struct {
 	NodeCount          uint64
 	UseBook            bool
 	EvalBuilder        func() interface{}
+	Params             Params
+	// Termination is why the last search stopped
+	Termination TerminationReason
+	// Oracle is asked for the result of positions with few pieces before
+	// they are searched, nil searches every position
+	Oracle EndgameOracle
+	// Logger receives diagnostics that are not part of the UCI protocol so
+	// they never mix with the info and bestmove lines on stdout
+	Logger *log.Logger
+	// Output receives the info and bestmove lines, nil writes them to stdout
+	Output stdio.Writer
+	// workerCtx is cancelled when the search running on Ctx finishes, so the
+	// workers stop together without cancelling the caller's context
+	workerCtx   context.Context
+	stopWorkers context.CancelFunc
+	// rng chooses between the candidate moves of a weakened search and how
+	// much time the jitter takes off
+	rng *rand.Rand
+}
+
+// Params holds the switches used to enable or disable parts of the search
+type Params struct {
+	// DisableQuiescence returns the static evaluation at the horizon instead
+	// of resolving captures
+	DisableQuiescence bool
+
+	// DisableNullMove never tries a null move to prove a cutoff
+	DisableNullMove bool
+
+	// DisableReverseFutility searches nodes whose static evaluation is far
+	// above beta instead of returning early
+	DisableReverseFutility bool
+
+	// DisableTT ignores the transposition table when searching, entries are
+	// still stored so the best move can be read back
+	DisableTT bool
+
+	// DisableAspiration searches every depth with a full window
+	DisableAspiration bool
+
+	// AspirationDelta is the distance of each aspiration bound from the
+	// previous score, zero uses 50
+	AspirationDelta int
+
+	// AspirationMaxWindow is how far a failing aspiration bound is widened
+	// before it is dropped, zero uses 1000
+	AspirationMaxWindow int
+
+	// KillerMoves is the number of killer moves stored per ply (up to
+	// engine.MaxKillers), zero uses engine.DefaultKillers
+	KillerMoves int
+
+	// CountermoveBonus is the ordering score of the quiet move that last
+	// refuted the previous move, zero uses engine.DefaultCountermoveBonus
+	CountermoveBonus int
+
+	// ContinuationWeight is the percentage of the continuation history added
+	// to a quiet move's score, zero uses engine.DefaultContinuationWeight
+	ContinuationWeight int
+
+	// ContinuationAging is how many times the continuation history is halved
+	// at the start of a search, zero halves it once
+	ContinuationAging int
+
+	// HistoryDecay is the factor the history scores are divided by at the
+	// start of each search, zero uses engine.DefaultHistoryDecay
+	HistoryDecay int
+
+	// HistoryMax is the largest magnitude a history score can reach, zero
+	// uses engine.DefaultHistoryMax
+	HistoryMax int
+
+	// QuiescenceMaxDepth is how many plies quiescence searches captures
+	// before returning the stand pat score, a side in check is given a few
+	// more plies. Zero uses a default of 16
+	QuiescenceMaxDepth int
+
+	// DisableQSearchChecks only searches captures at the first ply of
+	// quiescence, without it quiet moves that give check are searched too
+	DisableQSearchChecks bool
+
+	// DisableSEEPrune searches every move near the horizon instead of skipping
+	// captures and quiet moves that lose material to the recaptures
+	DisableSEEPrune bool
+
+	// SEEPruneThreshold is how much material per ply of depth left a move
+	// can lose before it is pruned, zero uses a pawn
+	SEEPruneThreshold int
+
+	// DisableLMR searches every move to full depth instead of reducing late
+	// quiet moves
+	DisableLMR bool
+
+	// LMRMinDepth is the depth from which late quiet moves are searched at a
+	// reduced depth, zero uses 3
+	LMRMinDepth int
+
+	// LMRMinMoves is how many moves are searched at full depth before later
+	// ones are reduced, zero uses 4
+	LMRMinMoves int
+
+	// LMRDivisor scales the reductions, a move is reduced by
+	// ln(depth)*ln(moves)*100/LMRDivisor plies so a larger divisor reduces
+	// less. Zero uses 225
+	LMRDivisor int
+
+	// LMRHistoryThreshold is the history score at which a move is reduced a
+	// ply less, a move scoring minus this is reduced a ply more. Zero uses
+	// half of engine.DefaultHistoryMax
+	LMRHistoryThreshold int
+
+	// DisableLMRExemptions reduces passed pawn pushes to the sixth and
+	// seventh rank and moves that save a piece from capture like any other
+	// late quiet move
+	DisableLMRExemptions bool
+
+	// Razoring is how nodes near the horizon whose static evaluation is far
+	// below alpha are cut short, zero searches them normally
+	Razoring RazoringMode
+
+	// RazorMargin is how far below alpha per ply of depth left the static
+	// evaluation must be for a node to be razored, zero uses 200
+	RazorMargin int
+
+	// NullMoveVerifyDepth is the depth from which a null move cutoff is only
+	// trusted after a reduced search without null moves also fails high,
+	// zero trusts every cutoff. Null moves are never tried with only pawns
+	NullMoveVerifyDepth int
+
+	// Contempt is how much worse than level a draw is scored for the side to
+	// move at the root, a negative value makes the engine seek draws. It is
+	// the contempt with every piece on the board and tapers to
+	// EndgameContempt as pieces come off
+	Contempt int
+
+	// EndgameContempt is the contempt once only pawns are left
+	EndgameContempt int
+
+	// Swindle makes a root side that is clearly lost prefer complicated
+	// positions with more moves and pieces over the objectively best line
+	Swindle bool
+
+	// BookMaxPly stops the book being used once the game has reached this
+	// many plies, zero uses the book whenever it has an entry
+	BookMaxPly int
+
+	// VerifyHash checks the board and position key against ones built from
+	// scratch after every move, panicking on a mismatch. It is slow and only
+	// meant for debugging
+	VerifyHash bool
+
+	// DrawBand reports centipawn scores this close to zero as 0 so small
+	// swings between depths are not shown, zero reports every score as is
+	DrawBand int
+
+	// Weakness from 1 to MaxWeakness caps the depth searched and has the
+	// engine sometimes play one of the next best moves instead of the best,
+	// zero plays at full strength
+	Weakness int
+
+	// DisableQuickMoves uses the full time for a move even when there is
+	// only one legal move or an obvious recapture
+	DisableQuickMoves bool
+
+	// TimeJitter takes a random share of up to this percent off the time for
+	// each move so the engine does not always think for as long, it is
+	// capped at MaxTimeJitter and zero always uses the full time
+	TimeJitter int
 }
 
 type IEvaluator interface {
@@ -35,6 +249,25 @@ type IUpdatableEvaluator interface {
 	Evaluate(p *engine.Position) int
 }
 
+// IAttachableEvaluator is implemented by evaluators that keep part of their
+// score up to date on the position as moves are made
+type IAttachableEvaluator interface {
+	Attach(p *engine.Position)
+}
+
+// ICachingEvaluator is implemented by evaluators that cache scores by
+// position key, the cache is cleared before each search
+type ICachingEvaluator interface {
+	ClearCache()
+	CacheStats() (hits, probes int)
+}
+
+// ICentipawnEvaluator is implemented by evaluators whose scores are not in
+// centipawns, Centipawns converts a score for the position
+type ICentipawnEvaluator interface {
+	Centipawns(p *engine.Position, score int) int
+}
+
 type EvaluatorAdapter struct {
 	evaluator IEvaluator
 }
@@ -44,8 +277,10 @@ func (e *EvaluatorAdapter) Evaluate(p *engine.Position) int {
 }
 
 func NewEngineHolder(numberOfThreads int, evalBuilder func() interface{}) *EngineHolder {
-	t := &EngineHolder{EvalBuilder: evalBuilder}
+	t := &EngineHolder{EvalBuilder: evalBuilder, Logger: log.New(os.Stderr, "", 0), Oracle: KPKOracle{}}
+	t.rng = rand.New(rand.NewSource(time.Now().UnixNano()))
 	t.Ctx, t.CancelSearch = context.WithCancel(context.Background())
+	t.workerCtx, t.stopWorkers = context.WithCancel(t.Ctx)
 	engines := make([]*Engine, numberOfThreads)
 	for i := 0; i < numberOfThreads; i++ {
 		engine := NewEngine(t)
@@ -60,6 +295,67 @@ func NewEngineHolder(numberOfThreads int, evalBuilder func() interface{}) *Engin
 	return t
 }
 
+// output returns where the info and bestmove lines are written
+func (h *EngineHolder) output() stdio.Writer {
+	if h.Output == nil {
+		return os.Stdout
+	}
+	return h.Output
+}
+
+// SetTranspositionTableSize sets the table to sizeMB, an existing table is
+// resized keeping its entries
+func (h *EngineHolder) SetTranspositionTableSize(sizeMB int) {
+	if h.TranspositionTable == nil {
+		h.TranspositionTable = engine.NewCacheWithSize(sizeMB)
+		return
+	}
+	h.TranspositionTable.Resize(sizeMB)
+}
+
+// SetPosition sets the position to search, the history scores from the last
+// search are kept so they can be aged rather than lost
+func (e *Engine) SetPosition(p *engine.Position) {
+	if e.Position != nil {
+		p.MoveHistory.History = e.Position.MoveHistory.History
+	}
+	e.Position = p
+}
+
+// EvalCacheStats returns the evaluation cache hits and lookups of the last
+// search, both are zero if the evaluator has no cache
+func (e *Engine) EvalCacheStats() (hits, probes int) {
+	if c, ok := e.evaluator.(ICachingEvaluator); ok {
+		return c.CacheStats()
+	}
+	return 0, 0
+}
+
+// ClearTranspositionTable empties the table without resetting its stats
+func (h *EngineHolder) ClearTranspositionTable() {
+	h.TranspositionTable.ClearEntries()
+}
+
+// ResetTranspositionTableStats zeroes the table's stats without clearing it
+func (h *EngineHolder) ResetTranspositionTableStats() {
+	h.TranspositionTable.ResetStats()
+}
+
+// NewGame forgets what was learnt in the last game, the transposition table
+// is emptied without being reallocated and each engine's move ordering
+// tables are reset
+func (h *EngineHolder) NewGame() {
+	h.ClearTranspositionTable()
+	for _, e := range h.Engines {
+		if e.Position != nil {
+			e.Position.MoveHistory = engine.MoveHistory{}
+		}
+		if e.continuation != nil {
+			*e.continuation = engine.ContinuationHistory{}
+		}
+	}
+}
+
 func NewEngine(parent *EngineHolder) *Engine {
 	return &Engine{Parent: parent, Position: nil}
 }
@@ -1,6 +1,9 @@
 package search
 
-import "github.com/AdamGriffiths31/ChessEngine/data"
+import (
+	"github.com/AdamGriffiths31/ChessEngine/data"
+	"github.com/AdamGriffiths31/ChessEngine/util"
+)
 
 func (e *Engine) MateIn(height int) int {
 	return data.ABInfinite - height
@@ -9,3 +12,27 @@ func (e *Engine) MateIn(height int) int {
 func (e *Engine) MatedIn(height int) int {
 	return -data.ABInfinite + height
 }
+
+// NormalizeScore returns 0 for scores within drawBand of zero, mate scores
+// are left alone
+func NormalizeScore(score, drawBand int) int {
+	if _, isMate := MateDistance(score); isMate {
+		return score
+	}
+	if util.Abs(score) <= drawBand {
+		return 0
+	}
+	return score
+}
+
+// MateDistance returns the number of plies to mate for a mate score, negative
+// when the side to move is being mated
+func MateDistance(score int) (plies int, isMate bool) {
+	switch {
+	case score > data.Mate:
+		return data.ABInfinite - score, true
+	case score < -data.Mate:
+		return -(data.ABInfinite + score), true
+	}
+	return 0, false
+}
@@ -0,0 +1,46 @@
+package search
+
+import (
+	"testing"
+
+	"github.com/AdamGriffiths31/ChessEngine/data"
+)
+
+func TestNormalizeScore(t *testing.T) {
+	tests := []struct {
+		score, band, want int
+	}{
+		{2, 0, 2},
+		{2, 5, 0},
+		{-5, 5, 0},
+		{6, 5, 6},
+		{-6, 5, -6},
+		{data.ABInfinite - 3, 50, data.ABInfinite - 3},
+		{-data.ABInfinite + 4, 50, -data.ABInfinite + 4},
+	}
+	for _, test := range tests {
+		if got := NormalizeScore(test.score, test.band); got != test.want {
+			t.Errorf("NormalizeScore(%v, %v): expected %v but got %v", test.score, test.band, test.want, got)
+		}
+	}
+}
+
+func TestMateDistance(t *testing.T) {
+	tests := []struct {
+		score  int
+		plies  int
+		isMate bool
+	}{
+		{data.ABInfinite - 1, 1, true},
+		{data.ABInfinite - 5, 5, true},
+		{-data.ABInfinite + 2, -2, true},
+		{data.Mate, 0, false},
+		{150, 0, false},
+	}
+	for _, test := range tests {
+		plies, isMate := MateDistance(test.score)
+		if plies != test.plies || isMate != test.isMate {
+			t.Errorf("MateDistance(%v): expected %v %v but got %v %v", test.score, test.plies, test.isMate, plies, isMate)
+		}
+	}
+}
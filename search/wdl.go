@@ -0,0 +1,36 @@
+package search
+
+import (
+	"math"
+
+	"github.com/AdamGriffiths31/ChessEngine/data"
+)
+
+// WinProbabilityScale is the score advantage that gives odds of 10 to 1 in
+// the logistic model used by ScoreToWinProbability
+var WinProbabilityScale = 400.0
+
+// ScoreToWinProbability converts a search score from the side to move's
+// point of view into the chance of winning, mate scores map to 1 or 0
+func ScoreToWinProbability(score int) float64 {
+	if score > data.Mate {
+		return 1
+	}
+	if score < -data.Mate {
+		return 0
+	}
+	return 1 / (1 + math.Pow(10, -float64(score)/WinProbabilityScale))
+}
+
+// WinProbabilityToScore is the inverse of ScoreToWinProbability, certain wins
+// and losses are returned as the smallest mate scores
+func WinProbabilityToScore(p float64) int {
+	if p >= 1 {
+		return data.Mate
+	}
+	if p <= 0 {
+		return -data.Mate
+	}
+	score := -WinProbabilityScale * math.Log10(1/p-1)
+	return int(math.Max(-data.Mate, math.Min(data.Mate, math.Round(score))))
+}
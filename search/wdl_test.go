@@ -0,0 +1,40 @@
+package search
+
+import (
+	"math"
+	"testing"
+
+	"github.com/AdamGriffiths31/ChessEngine/data"
+)
+
+func TestScoreToWinProbability(t *testing.T) {
+	tests := []struct {
+		score int
+		want  float64
+	}{
+		{0, 0.5},
+		{100, 0.640},
+		{-100, 0.360},
+		{data.ABInfinite - 3, 1},
+		{-data.ABInfinite + 4, 0},
+	}
+	for _, test := range tests {
+		if got := ScoreToWinProbability(test.score); math.Abs(got-test.want) > 0.001 {
+			t.Errorf("%v: expected %v but got %v", test.score, test.want, got)
+		}
+	}
+}
+
+func TestWinProbabilityToScore(t *testing.T) {
+	for _, score := range []int{-500, -100, 0, 37, 100, 800} {
+		if got := WinProbabilityToScore(ScoreToWinProbability(score)); got != score {
+			t.Errorf("Expected %v but got %v", score, got)
+		}
+	}
+	if got := WinProbabilityToScore(1); got != data.Mate {
+		t.Errorf("Expected %v but got %v", data.Mate, got)
+	}
+	if got := WinProbabilityToScore(0); got != -data.Mate {
+		t.Errorf("Expected %v but got %v", -data.Mate, got)
+	}
+}
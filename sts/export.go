@@ -0,0 +1,41 @@
+package sts
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"strconv"
+)
+
+// csvHeader names the columns written by WriteCSV
+var csvHeader = []string{"id", "fen", "move", "status", "points", "max_points", "nodes", "depth", "time_ms", "tt_hit_rate"}
+
+// WriteCSV writes a row for each position under a header row
+func (r SuiteResult) WriteCSV(w io.Writer) error {
+	cw := csv.NewWriter(w)
+	cw.Write(csvHeader)
+	for _, p := range r.DetailedResults {
+		cw.Write([]string{
+			p.ID,
+			p.FEN,
+			p.Move,
+			p.Status.String(),
+			strconv.Itoa(p.Points),
+			strconv.Itoa(p.MaxPoints),
+			strconv.Itoa(p.Nodes),
+			strconv.Itoa(p.Depth),
+			strconv.FormatInt(p.Time.Milliseconds(), 10),
+			strconv.FormatFloat(p.TTHitRate, 'f', 4, 64),
+		})
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// WriteJSON writes the result as json, it can be read back with
+// json.Unmarshal
+func (r SuiteResult) WriteJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(r)
+}
@@ -0,0 +1,45 @@
+package sts
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+var exportResult = SuiteResult{Points: 13, MaxPoints: 20, DetailedResults: []PositionResult{
+	{ID: "STS test.001", FEN: "4k3/8/8/3q4/8/8/3R4/4K3 w - -", Move: "Rxd5", Depth: 3, Nodes: 120, Time: 15 * time.Millisecond,
+		TTHitRate: 0.25, Scored: true, Points: 10, MaxPoints: 10, Status: Correct},
+	{ID: "STS test.002", FEN: "4k3/8/8/8/8/8/3R4/4K3 w - -", Move: "Ke2", Depth: 3, Nodes: 80, Time: 9 * time.Millisecond,
+		Scored: true, Points: 3, MaxPoints: 10, Status: Partial},
+}}
+
+func TestWriteJSONRoundTrips(t *testing.T) {
+	var b bytes.Buffer
+	if err := exportResult.WriteJSON(&b); err != nil {
+		t.Fatal(err)
+	}
+	var got SuiteResult
+	if err := json.Unmarshal(b.Bytes(), &got); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, exportResult) {
+		t.Errorf("Expected %+v but got %+v", exportResult, got)
+	}
+}
+
+func TestWriteCSV(t *testing.T) {
+	var b bytes.Buffer
+	if err := exportResult.WriteCSV(&b); err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(b.String()), "\n")
+	if len(lines) != 3 || lines[0] != strings.Join(csvHeader, ",") {
+		t.Fatalf("Expected a header and 2 rows but got %q", lines)
+	}
+	if want := "STS test.001,4k3/8/8/3q4/8/8/3R4/4K3 w - -,Rxd5,correct,10,10,120,3,15,0.2500"; lines[1] != want {
+		t.Errorf("Expected %q but got %q", want, lines[1])
+	}
+}
@@ -0,0 +1,216 @@
+// Package sts scores the engine on Strategic Test Suite style EPD files,
+// where the c0 opcode gives the points each good move is worth
+package sts
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/AdamGriffiths31/ChessEngine/data"
+	"github.com/AdamGriffiths31/ChessEngine/engine"
+	"github.com/AdamGriffiths31/ChessEngine/epd"
+	"github.com/AdamGriffiths31/ChessEngine/eval"
+	"github.com/AdamGriffiths31/ChessEngine/search"
+)
+
+// bestMovePoints is what a best move is worth in a position without c0
+// scores
+const bestMovePoints = 10
+
+// Status is how the engine's move on a position was judged
+type Status int
+
+const (
+	// Correct is a move worth the most points in the position
+	Correct Status = iota
+	// Partial is a move worth some points but fewer than the best
+	Partial
+	// Wrong is a legal move worth nothing
+	Wrong
+	// NoMove is a search that gave no move, such as one cut off before it
+	// finished depth 1
+	NoMove
+	// Illegal is a move that cannot be played in the position
+	Illegal
+)
+
+func (s Status) String() string {
+	switch s {
+	case Correct:
+		return "correct"
+	case Partial:
+		return "partial"
+	case Wrong:
+		return "wrong"
+	case NoMove:
+		return "no move"
+	}
+	return "illegal"
+}
+
+// Options is how each position is searched
+type Options struct {
+	// Depth is the depth searched, zero searches until MoveTime runs out so
+	// at least one of them must be set
+	Depth int
+	// MoveTime is the time allowed for each position, zero has no limit
+	MoveTime time.Duration
+	// Params are the search params used
+	Params search.Params
+	// Log receives a line for each position as it is scored, nil logs
+	// nothing
+	Log io.Writer
+}
+
+// PositionResult is how the engine did on one position
+type PositionResult struct {
+	ID  string
+	FEN string
+	// Move is the engine's move in SAN
+	Move string
+	// Depth is the last depth the search completed
+	Depth int
+	Nodes int
+	Time  time.Duration
+	// TTHitRate is the share of transposition table probes whose entry was
+	// deep enough to use
+	TTHitRate float64
+	// Scored is true if the move is one of those the position gives points
+	// for
+	Scored    bool
+	Points    int
+	MaxPoints int
+	Status    Status
+}
+
+// SuiteResult is the points scored over a suite with the result of each
+// position in the order they were given
+type SuiteResult struct {
+	Points          int
+	MaxPoints       int
+	DetailedResults []PositionResult
+}
+
+func (r SuiteResult) String() string {
+	percent := 0.0
+	if r.MaxPoints > 0 {
+		percent = float64(r.Points) * 100 / float64(r.MaxPoints)
+	}
+	return fmt.Sprintf("%d/%d points (%.1f%%) from %d positions", r.Points, r.MaxPoints, percent, len(r.DetailedResults))
+}
+
+// Score searches each position and awards the points for the engine's move
+func Score(positions []epd.EPDPosition, opts Options) SuiteResult {
+	return ScoreParallel(context.Background(), positions, opts, 1)
+}
+
+// ScoreParallel is Score with the positions shared between workers, each
+// with its own engine and table. The table is still emptied before each
+// position so a position scores the same whichever worker searches it.
+// Positions searched after ctx is cancelled find no move and score nothing
+func ScoreParallel(ctx context.Context, positions []epd.EPDPosition, opts Options, workers int) SuiteResult {
+	if workers < 1 {
+		workers = 1
+	}
+	results := make([]PositionResult, len(positions))
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	var logMu sync.Mutex
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			h := search.NewEngineHolder(1, eval.Get("custom"))
+			h.Params = opts.Params
+			h.UseBook = false
+			for i := range jobs {
+				h.NewGame()
+				results[i] = scorePosition(ctx, h, positions[i], opts)
+				if opts.Log != nil {
+					logMu.Lock()
+					r := results[i]
+					fmt.Fprintf(opts.Log, "%v: %v %v scored %d/%d depth %d nodes %d time %v\n",
+						r.ID, r.Move, r.Status, r.Points, r.MaxPoints, r.Depth, r.Nodes, r.Time)
+					logMu.Unlock()
+				}
+			}
+		}()
+	}
+	for i := range positions {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+
+	result := SuiteResult{DetailedResults: results}
+	for _, r := range results {
+		result.Points += r.Points
+		result.MaxPoints += r.MaxPoints
+	}
+	return result
+}
+
+// scorePosition searches the position within the options' limits
+func scorePosition(ctx context.Context, h *search.EngineHolder, position epd.EPDPosition, opts Options) PositionResult {
+	if opts.MoveTime > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, opts.MoveTime)
+		defer cancel()
+	}
+	depth := opts.Depth
+	if depth == 0 {
+		depth = data.MaxDepth
+	}
+
+	game := engine.ParseFen(position.FEN)
+	p := game.Position()
+	e := h.Engines[0]
+	tt := h.TranspositionTable
+	probed, hits, start := tt.Probed, tt.Hit, time.Now()
+	move := h.Hint(ctx, p, depth).Move
+	r := PositionResult{ID: position.ID, FEN: position.FEN, Nodes: e.NodesVisited, Time: time.Since(start)}
+	if tt.Probed > probed {
+		r.TTHitRate = float64(tt.Hit-hits) / float64(tt.Probed-probed)
+	}
+	if n := len(e.DepthBreakdown); n > 0 {
+		r.Depth = e.DepthBreakdown[n-1].Depth
+	}
+	switch {
+	case move == data.NoMove:
+		r.Status = NoMove
+	case !p.IsLegalMove(move):
+		r.Status = Illegal
+	default:
+		r.Move = p.SAN(move)
+	}
+
+	points := position.MoveScores
+	if points == nil {
+		points = map[string]int{}
+		for _, bm := range position.BestMove {
+			points[bm] = bestMovePoints
+		}
+	}
+	for san, value := range points {
+		if value > r.MaxPoints {
+			r.MaxPoints = value
+		}
+		if r.Move != "" && strings.TrimRight(san, "+#!?") == strings.TrimRight(r.Move, "+#") {
+			r.Scored = true
+			r.Points = value
+		}
+	}
+	if r.Status == Correct {
+		switch {
+		case r.Points == 0:
+			r.Status = Wrong
+		case r.Points < r.MaxPoints:
+			r.Status = Partial
+		}
+	}
+	return r
+}
@@ -0,0 +1,84 @@
+package sts
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/AdamGriffiths31/ChessEngine/epd"
+)
+
+const suite = `4k3/8/8/3q4/8/8/3R4/4K3 w - - bm Rxd5; c0 "Rxd5=10, Rd4=1"; id "STS test.001";
+4k3/8/8/8/8/8/3R4/4K3 w - - bm Rd8; c0 "Ke2=3"; id "STS test.002";
+`
+
+func TestScoreFillsDetailedResults(t *testing.T) {
+	positions, err := epd.ReadEPD(strings.NewReader(suite))
+	if err != nil {
+		t.Fatal(err)
+	}
+	result := Score(positions, Options{Depth: 3})
+	if len(result.DetailedResults) != 2 {
+		t.Fatalf("Expected 2 results but got %v", len(result.DetailedResults))
+	}
+
+	first := result.DetailedResults[0]
+	if first.ID != "STS test.001" || first.Move != "Rxd5" || !first.Scored || first.Points != 10 || first.MaxPoints != 10 || first.Status != Correct {
+		t.Errorf("Expected Rxd5 to score 10/10 but got %+v", first)
+	}
+	for _, r := range result.DetailedResults {
+		if r.Depth != 3 || r.Nodes == 0 || r.FEN == "" || r.Move == "" {
+			t.Errorf("Expected the depth, nodes, fen and move to be filled but got %+v", r)
+		}
+	}
+	second := result.DetailedResults[1]
+	if second.Scored != (second.Move == "Ke2") || second.MaxPoints != 3 {
+		t.Errorf("Expected only Ke2 to score out of 3 but got %+v", second)
+	}
+	if result.Points != first.Points+second.Points || result.MaxPoints != 13 {
+		t.Errorf("Expected the totals to add up but got %v", result)
+	}
+}
+
+func TestScoreParallelMatchesSerial(t *testing.T) {
+	positions, err := epd.ReadEPD(strings.NewReader(suite + `r1bqkb1r/pppp1ppp/2n2n2/4p3/2B1P3/5N2/PPPP1PPP/RNBQK2R w KQkq - bm Ng5; c0 "Ng5=10, d3=5, O-O=4, Nc3=3"; id "STS test.003";
+r1bqk2r/pppp1ppp/2n2n2/2b1p3/2B1P3/2N2N2/PPPP1PPP/R1BQK2R w KQkq - bm d3; c0 "d3=10, O-O=8, Nxe5=2"; id "STS test.004";
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	serial := Score(positions, Options{Depth: 5})
+	parallel := ScoreParallel(context.Background(), positions, Options{Depth: 5}, 3)
+	if serial.Points != parallel.Points || serial.MaxPoints != parallel.MaxPoints {
+		t.Errorf("Expected %v in parallel but got %v", serial, parallel)
+	}
+	for i := range serial.DetailedResults {
+		s, p := serial.DetailedResults[i], parallel.DetailedResults[i]
+		if s.ID != p.ID || s.Move != p.Move || s.Nodes != p.Nodes {
+			t.Errorf("Expected %+v in parallel but got %+v", s, p)
+		}
+	}
+}
+
+func TestScoreTimeoutGivesNoMoveOrLegalMove(t *testing.T) {
+	positions, err := epd.ReadEPD(strings.NewReader(suite))
+	if err != nil {
+		t.Fatal(err)
+	}
+	result := Score(positions, Options{MoveTime: time.Nanosecond})
+	for _, r := range result.DetailedResults {
+		switch r.Status {
+		case NoMove:
+			if r.Move != "" || r.Points != 0 {
+				t.Errorf("Expected no move to score nothing but got %+v", r)
+			}
+		case Illegal:
+			t.Errorf("Expected no move or a legal one but got %+v", r)
+		default:
+			if r.Move == "" {
+				t.Errorf("Expected a move with status %v but got %+v", r.Status, r)
+			}
+		}
+	}
+}
@@ -0,0 +1,219 @@
+package tuning
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+
+	"github.com/AdamGriffiths31/ChessEngine/data"
+	"github.com/AdamGriffiths31/ChessEngine/engine"
+	custom "github.com/AdamGriffiths31/ChessEngine/eval/custom"
+	"github.com/AdamGriffiths31/ChessEngine/util"
+)
+
+// Sample is a position and the result of its game from white's
+// point of view, 1 for a win, 0.5 for a draw and 0 for a loss
+type Sample struct {
+	FEN    string
+	Result float64
+}
+
+// Weight is one tunable number in the evaluation weights, the middle or end
+// game half of a score
+type Weight struct {
+	Name  string
+	score func(w *custom.Weights) *custom.Score
+	end   bool
+}
+
+func (wt Weight) get(w *custom.Weights) int {
+	if wt.end {
+		return wt.score(w).End()
+	}
+	return wt.score(w).Middle()
+}
+
+func (wt Weight) set(w *custom.Weights, value int) {
+	s := wt.score(w)
+	if wt.end {
+		*s = custom.S(s.Middle(), value)
+	} else {
+		*s = custom.S(value, s.End())
+	}
+}
+
+// TunableWeights returns the material and single score weights, each as a
+// middle game weight named Name.mg and an end game weight named Name.eg.
+// The mobility, passed pawn and PSQT tables are left alone
+func TunableWeights() []Weight {
+	scores := []struct {
+		name  string
+		score func(w *custom.Weights) *custom.Score
+	}{
+		{"PawnValue", func(w *custom.Weights) *custom.Score { return &w.PawnValue }},
+		{"KnightValue", func(w *custom.Weights) *custom.Score { return &w.KnightValue }},
+		{"BishopValue", func(w *custom.Weights) *custom.Score { return &w.BishopValue }},
+		{"RookValue", func(w *custom.Weights) *custom.Score { return &w.RookValue }},
+		{"QueenValue", func(w *custom.Weights) *custom.Score { return &w.QueenValue }},
+		{"ThreatByPawn", func(w *custom.Weights) *custom.Score { return &w.ThreatByPawn }},
+		{"ThreatByPawnPush", func(w *custom.Weights) *custom.Score { return &w.ThreatByPawnPush }},
+		{"PassedKingDistance", func(w *custom.Weights) *custom.Score { return &w.PassedKingDistance }},
+		{"PawnIsolated", func(w *custom.Weights) *custom.Score { return &w.PawnIsolated }},
+		{"BishopPair", func(w *custom.Weights) *custom.Score { return &w.BishopPair }},
+		{"KnightPair", func(w *custom.Weights) *custom.Score { return &w.KnightPair }},
+		{"RookPair", func(w *custom.Weights) *custom.Score { return &w.RookPair }},
+		{"RookOpenFile", func(w *custom.Weights) *custom.Score { return &w.RookOpenFile }},
+		{"RookSemiOpenFile", func(w *custom.Weights) *custom.Score { return &w.RookSemiOpenFile }},
+		{"RookSeventhRank", func(w *custom.Weights) *custom.Score { return &w.RookSeventhRank }},
+		{"RookSeventhKing", func(w *custom.Weights) *custom.Score { return &w.RookSeventhKing }},
+		{"QueenOpenFile", func(w *custom.Weights) *custom.Score { return &w.QueenOpenFile }},
+		{"QueenSemiOpenFile", func(w *custom.Weights) *custom.Score { return &w.QueenSemiOpenFile }},
+		{"BackRankWeakness", func(w *custom.Weights) *custom.Score { return &w.BackRankWeakness }},
+	}
+	weights := make([]Weight, 0, 2*len(scores))
+	for _, s := range scores {
+		weights = append(weights,
+			Weight{Name: s.name + ".mg", score: s.score},
+			Weight{Name: s.name + ".eg", score: s.score, end: true})
+	}
+	return weights
+}
+
+// Texel tunes evaluation weights by minibatch gradient descent on the
+// squared error between game results and the evaluation passed through a
+// logistic curve. Gradients are found by central differences, so the
+// evaluation is treated as a black box
+type Texel struct {
+	// K scales the evaluation before the logistic curve, 1 if zero
+	K float64
+	// LearningRate is how far a weight moves for a unit of gradient,
+	// defaultLearningRate if zero
+	LearningRate float64
+	// Epochs is the number of passes through the samples, 1 if zero
+	Epochs int
+	// BatchSize is the number of samples per step, all of them if zero
+	BatchSize int
+	// Step is the change made to a weight to measure its gradient, 1 if zero
+	Step int
+	// Seed seeds the shuffle of the samples before each epoch
+	Seed int64
+	// Frozen names the weights that are not changed
+	Frozen []string
+}
+
+// defaultLearningRate suits gradients of the error per centipawn, which are
+// a few thousandths at most
+const defaultLearningRate = 5000
+
+// TexelResult is the tuned weights and the error over all the samples
+// before and after tuning
+type TexelResult struct {
+	Weights     custom.Weights
+	StartError  float64
+	FinalError  float64
+	TunedValues map[string]int
+}
+
+// Tune adjusts the weights in TunableWeights that are not frozen to fit the
+// samples, starting from start
+func (t Texel) Tune(samples []Sample, start custom.Weights) (TexelResult, error) {
+	k, rate, epochs, step := t.K, t.LearningRate, t.Epochs, t.Step
+	if k == 0 {
+		k = 1
+	}
+	if rate == 0 {
+		rate = defaultLearningRate
+	}
+	if epochs == 0 {
+		epochs = 1
+	}
+	if step == 0 {
+		step = 1
+	}
+
+	frozen := map[string]bool{}
+	for _, name := range t.Frozen {
+		frozen[name] = true
+	}
+	var weights []Weight
+	for _, wt := range TunableWeights() {
+		if !frozen[wt.Name] {
+			weights = append(weights, wt)
+		}
+		delete(frozen, wt.Name)
+	}
+	for name := range frozen {
+		return TexelResult{}, fmt.Errorf("unknown weight %q", name)
+	}
+
+	positions := make([]*engine.Position, len(samples))
+	for i, s := range samples {
+		game := engine.ParseFen(s.FEN)
+		positions[i] = game.Position()
+	}
+
+	es := custom.NewEvaluationService()
+	w := start
+	// values holds the weights unrounded so small steps add up
+	values := make([]float64, len(weights))
+	for i, wt := range weights {
+		values[i] = float64(wt.get(&w))
+	}
+	errorOf := func(w custom.Weights, batch []int) float64 {
+		es.SetWeights(w)
+		var total float64
+		for _, i := range batch {
+			eval := es.Evaluate(positions[i])
+			if positions[i].Side == data.Black {
+				eval = -eval
+			}
+			diff := samples[i].Result - sigmoid(k, eval)
+			total += diff * diff
+		}
+		return total / float64(len(batch))
+	}
+
+	all := make([]int, len(samples))
+	for i := range all {
+		all[i] = i
+	}
+	result := TexelResult{StartError: errorOf(w, all)}
+
+	batchSize := t.BatchSize
+	if batchSize <= 0 || batchSize > len(samples) {
+		batchSize = len(samples)
+	}
+	rng := rand.New(rand.NewSource(t.Seed))
+	order := append([]int{}, all...)
+	gradient := make([]float64, len(weights))
+	for epoch := 0; epoch < epochs; epoch++ {
+		rng.Shuffle(len(order), func(i, j int) { order[i], order[j] = order[j], order[i] })
+		for from := 0; from < len(order); from += batchSize {
+			batch := order[from:util.Min(from+batchSize, len(order))]
+			for i, wt := range weights {
+				value := wt.get(&w)
+				up, down := w, w
+				wt.set(&up, value+step)
+				wt.set(&down, value-step)
+				gradient[i] = (errorOf(up, batch) - errorOf(down, batch)) / float64(2*step)
+			}
+			for i, wt := range weights {
+				values[i] -= rate * gradient[i]
+				wt.set(&w, int(math.Round(values[i])))
+			}
+		}
+	}
+
+	result.Weights = w
+	result.FinalError = errorOf(w, all)
+	result.TunedValues = make(map[string]int, len(weights))
+	for _, wt := range weights {
+		result.TunedValues[wt.Name] = wt.get(&w)
+	}
+	return result, nil
+}
+
+// sigmoid maps a centipawn score to the expected result, K scales it
+func sigmoid(k float64, eval int) float64 {
+	return 1 / (1 + math.Pow(10, -k*float64(eval)/400))
+}
@@ -0,0 +1,54 @@
+package tuning
+
+import (
+	"reflect"
+	"testing"
+
+	custom "github.com/AdamGriffiths31/ChessEngine/eval/custom"
+)
+
+// materialSamples are won by whoever has more pawns
+var materialSamples = []Sample{
+	{"rnbqkbnr/2pppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1", 1},
+	{"rnbqkbnr/pppppp2/8/8/8/8/PPPPPPPP/RNBQKBNR b KQkq - 0 1", 1},
+	{"rnbqkbnr/pp1ppp1p/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1", 1},
+	{"rnbqkbnr/pppppppp/8/8/8/8/2PPPPPP/RNBQKBNR w KQkq - 0 1", 0},
+	{"rnbqkbnr/pppppppp/8/8/8/8/PPPPPP2/RNBQKBNR b KQkq - 0 1", 0},
+	{"rnbqkbnr/pppppppp/8/8/8/8/PP1PPP1P/RNBQKBNR b KQkq - 0 1", 0},
+}
+
+func TestTexelRaisesPawnValueWhenMaterialMatters(t *testing.T) {
+	start := custom.NewEvaluationService().Weights
+	texel := Texel{Epochs: 3, BatchSize: 4, Seed: 1}
+	var frozen []string
+	for _, wt := range TunableWeights() {
+		if wt.Name != "PawnValue.mg" {
+			frozen = append(frozen, wt.Name)
+		}
+	}
+	texel.Frozen = frozen
+
+	result, err := texel.Tune(materialSamples, start)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := result.Weights.PawnValue.Middle(); got <= start.PawnValue.Middle() {
+		t.Errorf("Expected the pawn value to rise from %v but got %v", start.PawnValue.Middle(), got)
+	}
+	if result.FinalError >= result.StartError {
+		t.Errorf("Expected the error to fall from %v but got %v", result.StartError, result.FinalError)
+	}
+	if result.Weights.KnightValue != start.KnightValue || result.Weights.PawnValue.End() != start.PawnValue.End() {
+		t.Errorf("Expected frozen weights to be unchanged")
+	}
+
+	if again, _ := texel.Tune(materialSamples, start); !reflect.DeepEqual(again, result) {
+		t.Errorf("Expected the same seed to give %+v but got %+v", result.TunedValues, again.TunedValues)
+	}
+}
+
+func TestTexelRejectsUnknownWeight(t *testing.T) {
+	if _, err := (Texel{Frozen: []string{"PawnValue"}}).Tune(materialSamples, custom.NewEvaluationService().Weights); err == nil {
+		t.Errorf("Expected an error for an unknown weight")
+	}
+}
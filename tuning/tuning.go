@@ -0,0 +1,143 @@
+package tuning
+
+import (
+	"math"
+	"math/rand"
+	"sort"
+
+	"github.com/AdamGriffiths31/ChessEngine/match"
+	"github.com/AdamGriffiths31/ChessEngine/search"
+)
+
+// Runner plays candidate against base and returns the candidate's share of
+// the points, from 0 to 1. Any randomness must come from rng so a sweep can
+// be repeated
+type Runner func(candidate, base search.Params, rng *rand.Rand) float64
+
+// Decision is the outcome of the sequential probability ratio test
+type Decision int
+
+const (
+	// Inconclusive means the game limit was reached before either bound
+	Inconclusive Decision = iota
+	// Stronger means the candidate is at least Elo1 better than the base
+	Stronger
+	// NotStronger means the candidate is no more than Elo0 better
+	NotStronger
+)
+
+func (d Decision) String() string {
+	switch d {
+	case Stronger:
+		return "stronger"
+	case NotStronger:
+		return "not stronger"
+	}
+	return "inconclusive"
+}
+
+// SPRT is the test each candidate is played until
+type SPRT struct {
+	// Elo0 and Elo1 are the Elo differences of the null and alternative
+	// hypotheses
+	Elo0, Elo1 float64
+	// Alpha and Beta are the false positive and false negative rates
+	Alpha, Beta float64
+	// MaxGames stops the test as inconclusive after this many runs
+	MaxGames int
+	// Seed seeds the random numbers passed to the runner
+	Seed int64
+}
+
+// Candidate is the result of testing one value of the parameter
+type Candidate struct {
+	Value    int
+	Games    int
+	Score    float64
+	LLR      float64
+	Decision Decision
+}
+
+// Sweep tests each value of a parameter against base, set applies the value
+// to a copy of base. The candidates are returned strongest first
+func Sweep(base search.Params, set func(p *search.Params, value int), values []int, run Runner, test SPRT) []Candidate {
+	rng := rand.New(rand.NewSource(test.Seed))
+	lower := math.Log(test.Beta / (1 - test.Alpha))
+	upper := math.Log((1 - test.Beta) / test.Alpha)
+
+	candidates := make([]Candidate, 0, len(values))
+	for _, value := range values {
+		params := base
+		set(&params, value)
+
+		c := Candidate{Value: value}
+		var scores []float64
+		for len(scores) < test.MaxGames {
+			scores = append(scores, run(params, base, rng))
+			c.LLR = llr(scores, test.Elo0, test.Elo1)
+			if c.LLR >= upper {
+				c.Decision = Stronger
+				break
+			}
+			if c.LLR <= lower {
+				c.Decision = NotStronger
+				break
+			}
+		}
+		c.Games = len(scores)
+		c.Score = mean(scores)
+		candidates = append(candidates, c)
+	}
+
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return candidates[i].Score > candidates[j].Score
+	})
+	return candidates
+}
+
+// MatchRunner plays a pair of games from a random opening in opts with
+// each side taking white once, match.DefaultOpenings are used if opts has
+// none
+func MatchRunner(opts match.Options) Runner {
+	openings := opts.Openings
+	if len(openings) == 0 {
+		openings = match.DefaultOpenings
+	}
+	return func(candidate, base search.Params, rng *rand.Rand) float64 {
+		pair := opts
+		pair.Openings = []string{openings[rng.Intn(len(openings))]}
+		return match.Run(candidate, base, pair).Score()
+	}
+}
+
+// llr returns the log likelihood ratio of the scores under the normal
+// approximation, zero until the scores vary
+func llr(scores []float64, elo0, elo1 float64) float64 {
+	m := mean(scores)
+	var variance float64
+	for _, s := range scores {
+		variance += (s - m) * (s - m)
+	}
+	variance /= float64(len(scores))
+	if variance == 0 {
+		return 0
+	}
+	s0, s1 := expectedScore(elo0), expectedScore(elo1)
+	return float64(len(scores)) * (s1 - s0) * (2*m - s0 - s1) / (2 * variance)
+}
+
+// expectedScore converts an Elo difference to the expected share of points
+func expectedScore(elo float64) float64 {
+	return 1 / (1 + math.Pow(10, -elo/400))
+}
+
+func mean(scores []float64) float64 {
+	if len(scores) == 0 {
+		return 0
+	}
+	var sum float64
+	for _, s := range scores {
+		sum += s
+	}
+	return sum / float64(len(scores))
+}
@@ -0,0 +1,49 @@
+package tuning
+
+import (
+	"math/rand"
+	"reflect"
+	"testing"
+
+	"github.com/AdamGriffiths31/ChessEngine/match"
+	"github.com/AdamGriffiths31/ChessEngine/search"
+)
+
+// strengthRunner wins more often the higher the candidate's contempt is
+// above the base's, standing in for real games
+func strengthRunner(candidate, base search.Params, rng *rand.Rand) float64 {
+	win := 0.45 + float64(candidate.Contempt-base.Contempt)/10
+	r := rng.Float64()
+	switch {
+	case r < win:
+		return 1
+	case r < win+0.1:
+		return 0.5
+	}
+	return 0
+}
+
+func TestSweepFindsStrongerValue(t *testing.T) {
+	set := func(p *search.Params, value int) { p.Contempt = value }
+	test := SPRT{Elo0: 0, Elo1: 50, Alpha: 0.05, Beta: 0.05, MaxGames: 1000, Seed: 1}
+	values := []int{-4, 0, 4}
+
+	results := Sweep(search.Params{}, set, values, strengthRunner, test)
+	if results[0].Value != 4 || results[0].Decision != Stronger {
+		t.Errorf("Expected 4 to be stronger but got %+v", results[0])
+	}
+	if last := results[len(results)-1]; last.Value != -4 || last.Decision != NotStronger {
+		t.Errorf("Expected -4 to be not stronger but got %+v", last)
+	}
+
+	if again := Sweep(search.Params{}, set, values, strengthRunner, test); !reflect.DeepEqual(again, results) {
+		t.Errorf("Expected the same seed to give %+v but got %+v", results, again)
+	}
+}
+
+func TestMatchRunnerDefaultsOpenings(t *testing.T) {
+	run := MatchRunner(match.Options{Depth: 1, MaxPlies: 2})
+	if score := run(search.Params{}, search.Params{}, rand.New(rand.NewSource(1))); score != 0.5 {
+		t.Errorf("Expected a pair of drawn games from a default opening but got %v", score)
+	}
+}
@@ -0,0 +1,157 @@
+package uci
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	stdio "io"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/AdamGriffiths31/ChessEngine/data"
+	"github.com/AdamGriffiths31/ChessEngine/engine"
+	"github.com/AdamGriffiths31/ChessEngine/eval"
+	"github.com/AdamGriffiths31/ChessEngine/io"
+	"github.com/AdamGriffiths31/ChessEngine/search"
+	"github.com/AdamGriffiths31/ChessEngine/util"
+)
+
+// defaultBenchDepth is the depth each bench position is searched to when
+// "bench" is not given one
+const defaultBenchDepth = 8
+
+// benchFens are the positions searched by "bench", changing them changes the
+// node total so old and new totals can no longer be compared
+var benchFens = []string{
+	data.StartFEN,
+	"r3k2r/p1ppqpb1/bn2pnp1/3PN3/1p2P3/2N2Q1p/PPPBBPPP/R3K2R w KQkq - 0 1",
+	"r1bqkb1r/4npp1/p1p4p/1p1pP1B1/8/1B6/PPPN1PPP/R2Q1RK1 w kq - 0 1",
+	"2r3k1/pppR1pp1/4p3/4P1P1/5P2/1P4K1/P1P5/8 w - - 0 1",
+	"r1bq1rk1/pp2ppbp/2np2p1/2n5/P3PP2/N1P2N2/1PB3PP/R1B1QRK1 b - - 0 1",
+	"4b3/p3kp2/6p1/3pP2p/2pP1P2/4K1P1/P3N2P/8 w - - 0 1",
+	"8/2p5/3p4/KP5r/1R3p1k/8/4P1P1/8 w - - 0 1",
+	"3rn2k/ppb2rpp/2ppqp2/5N2/2P1P3/1P5Q/PB3PPP/3RR1K1 w - - 0 1",
+}
+
+// parseBench runs "bench" or "bench <depth>"
+func (uci *UCI) parseBench(line string, w stdio.Writer) {
+	depth := defaultBenchDepth
+	if tokens := strings.Fields(line); len(tokens) > 1 {
+		d, err := strconv.Atoi(tokens[1])
+		if err != nil || d < 1 || d > data.MaxDepth {
+			uci.engineHolder.Logger.Printf("Unknown bench depth expected 1 - %d\n", data.MaxDepth)
+			return
+		}
+		depth = d
+	}
+	uci.bench(w, depth)
+}
+
+// BenchPosition is the search of one bench position
+type BenchPosition struct {
+	FEN string
+	// Move is the best move in long algebraic notation
+	Move string
+	// Score is the score of the last depth as it is sent in an info line,
+	// such as "cp 25" or "mate 3"
+	Score string
+	Nodes int
+	Depth int
+	Time  time.Duration
+	// TTHitRate is the share of transposition table probes whose entry was
+	// deep enough to use
+	TTHitRate float64
+}
+
+// BenchResult is the totals of a bench with each position in the order of
+// benchFens
+type BenchResult struct {
+	Depth     int
+	Nodes     int
+	Time      time.Duration
+	NPS       int64
+	Positions []BenchPosition
+}
+
+// bench runs Bench with the UCI params and writes the totals to w
+func (uci *UCI) bench(w stdio.Writer, depth int) BenchResult {
+	r := Bench(depth, uci.engineHolder.Params)
+	fmt.Fprintf(w, "Total time (ms) : %d\n", r.Time.Milliseconds())
+	fmt.Fprintf(w, "Nodes searched  : %d\n", r.Nodes)
+	fmt.Fprintf(w, "Nodes/second    : %d\n", r.NPS)
+	return r
+}
+
+// Bench searches each of the bench positions to depth with a single thread
+// and a table emptied between positions, so the node total only changes when
+// the search does
+func Bench(depth int, params search.Params) BenchResult {
+	h := search.NewEngineHolder(1, eval.Get("custom"))
+	h.Logger = log.New(stdio.Discard, "", 0)
+	h.Params = params
+	h.UseBook = false
+	e := h.Engines[0]
+	tt := h.TranspositionTable
+
+	r := BenchResult{Depth: depth}
+	start := time.Now()
+	for _, fen := range benchFens {
+		h.NewGame()
+		game := engine.ParseFen(fen)
+		e.SetPosition(game.Position())
+		h.Ctx, h.CancelSearch = context.WithCancel(context.Background())
+		probed, hits, searchStart := tt.Probed, tt.Hit, time.Now()
+		h.Search(&data.SearchInfo{Depth: depth, StartTime: util.GetTimeMs()})
+
+		p := BenchPosition{FEN: fen, Nodes: e.NodesVisited, Time: time.Since(searchStart)}
+		if n := len(e.DepthBreakdown); n > 0 {
+			last := e.DepthBreakdown[n-1]
+			p.Move, p.Score, p.Depth = io.PrintMove(last.Move), e.UCIScore(last.Score), last.Depth
+		}
+		if tt.Probed > probed {
+			p.TTHitRate = float64(tt.Hit-hits) / float64(tt.Probed-probed)
+		}
+		r.Positions = append(r.Positions, p)
+		r.Nodes += p.Nodes
+	}
+	r.Time = time.Since(start)
+
+	r.NPS = int64(r.Nodes) * 1000
+	if ms := r.Time.Milliseconds(); ms > 0 {
+		r.NPS /= ms
+	}
+	return r
+}
+
+// benchCSVHeader names the columns written by WriteCSV
+var benchCSVHeader = []string{"fen", "move", "score", "nodes", "depth", "time_ms", "tt_hit_rate"}
+
+// WriteCSV writes a row for each position under a header row
+func (r BenchResult) WriteCSV(w stdio.Writer) error {
+	cw := csv.NewWriter(w)
+	cw.Write(benchCSVHeader)
+	for _, p := range r.Positions {
+		cw.Write([]string{
+			p.FEN,
+			p.Move,
+			p.Score,
+			strconv.Itoa(p.Nodes),
+			strconv.Itoa(p.Depth),
+			strconv.FormatInt(p.Time.Milliseconds(), 10),
+			strconv.FormatFloat(p.TTHitRate, 'f', 4, 64),
+		})
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// WriteJSON writes the result as json, it can be read back with
+// json.Unmarshal
+func (r BenchResult) WriteJSON(w stdio.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(r)
+}
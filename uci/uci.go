@@ -4,6 +4,8 @@ import (
 	"bufio"
 	"context"
 	"fmt"
+	stdio "io"
+	"log"
 	"os"
 	"strconv"
 	"strings"
@@ -11,26 +13,34 @@ import (
 	"github.com/AdamGriffiths31/ChessEngine/data"
 	"github.com/AdamGriffiths31/ChessEngine/engine"
 	"github.com/AdamGriffiths31/ChessEngine/eval"
-	"github.com/AdamGriffiths31/ChessEngine/io"
+	custom "github.com/AdamGriffiths31/ChessEngine/eval/custom"
 	"github.com/AdamGriffiths31/ChessEngine/search"
 	"github.com/AdamGriffiths31/ChessEngine/util"
 )
 
+const maxHashSize = 4096
+
+// maxContempt is the largest contempt accepted from setoption
+const maxContempt = 500
+
+// maxDrawBand is the largest draw band accepted from setoption
+const maxDrawBand = 50
+
 type UCI struct {
 	engineHolder *search.EngineHolder
 }
 
 func NewUCI() *UCI {
-	return &UCI{
-		search.NewEngineHolder(6, eval.Get("custom")),
-	}
+	h := search.NewEngineHolder(6, eval.Get("custom"))
+	h.Logger = log.New(stdio.Discard, "", 0)
+	return &UCI{h}
 }
 
 func (uci *UCI) UCIMode() {
 	search.InitPolyBook(uci.engineHolder)
 	var game engine.Game = engine.ParseFen(data.StartFEN)
 	info := data.SearchInfo{}
-	uci.printUCIok()
+	uci.printUCIok(os.Stdout)
 
 	reader := bufio.NewReader(os.Stdin)
 	inputCh := make(chan string)
@@ -49,13 +59,13 @@ func (uci *UCI) UCIMode() {
 
 	for input := range inputCh {
 		text := strings.TrimSpace(input)
-		fmt.Printf("debug: text %v\n", text)
+		uci.engineHolder.Logger.Printf("debug: text %v\n", text)
 		if text == "uci" {
-			uci.printUCIok()
+			uci.printUCIok(os.Stdout)
 		} else if text == "isready" {
 			fmt.Println("readyok")
 		} else if text == "ucinewgame" {
-			game = engine.ParseFen(data.StartFEN)
+			game = uci.newGame()
 		} else if strings.HasPrefix(text, "setoption") {
 			uci.parseOption(text)
 		} else if strings.HasPrefix(text, "position") {
@@ -64,7 +74,7 @@ func (uci *UCI) UCIMode() {
 			uci.parseGo(text, game, &info)
 		} else if text == "stop" {
 			info.ForceStop = true
-			fmt.Printf("debug: ForceStop %v\n", info.ForceStop)
+			uci.engineHolder.Logger.Printf("debug: ForceStop %v\n", info.ForceStop)
 		} else if text == "run" {
 			uci.engineHolder.UseBook = false
 			uci.parseGo("go infinite", game, &info)
@@ -72,6 +82,10 @@ func (uci *UCI) UCIMode() {
 			uci.parsePosition("position startpos moves d2d4 d7d5 c1f4 g8f6 b1c3 c8f5 e2e3 e7e6 f1d3 f8b4 g1e2 e8g8 e1g1 b8c6 d3f5 e6f5 f4g5 b4e7 g5f6 e7f6 d1d3 c6e7 f2f3 c7c6 e3e4 f5e4 f3e4 d8b6 b2b3 a8d8 e4e5 f6g5 d3g3 g5d2 c3a4 b6b5 g3f3 e7g6 a1d1 d2b4 f3e3 b5a5 g1h1 f8e8 e3f3 e8e7 d1a1 d8f8 a2a3 b4d2 f3h3 f7f6 e5e6 f8e8 e2g3 b7b6 g3f5 e7e6 h3g3 e8c8 g3g4 c8e8 g4g3", game)
 			game.Position().Board.PrintBoard()
 			uci.parseGo("go wtime 93687 btime 51739 winc 5000 binc 5000", game, &info)
+		} else if strings.HasPrefix(text, "bench") {
+			uci.parseBench(text, os.Stdout)
+		} else if text == "eval" {
+			uci.printEval(os.Stdout, game)
 		} else if text == "quit" {
 			info.Quit = data.True
 			break
@@ -80,11 +94,46 @@ func (uci *UCI) UCIMode() {
 
 }
 
-func (uci *UCI) printUCIok() {
-	fmt.Println("id name MyGoEngine")
-	fmt.Println("id author Adam")
-	fmt.Println("uciok")
-	fmt.Printf("option name OwnBook type check default %t\n", uci.engineHolder.UseBook)
+// newGame resets the engine for a game that has nothing to do with the last
+// one and returns the start position
+func (uci *UCI) newGame() engine.Game {
+	uci.engineHolder.NewGame()
+	return engine.ParseFen(data.StartFEN)
+}
+
+// tracer is implemented by evaluators that can break their score down
+type tracer interface {
+	EvaluateTrace(p *engine.Position) custom.EvalTrace
+}
+
+// printEval writes the static evaluation of the game position to w without
+// searching, with the breakdown of the terms when the evaluator has one
+func (uci *UCI) printEval(w stdio.Writer, game engine.Game) {
+	evaluator := uci.engineHolder.EvalBuilder()
+	p := game.Position()
+	if t, ok := evaluator.(tracer); ok {
+		fmt.Fprint(w, t.EvaluateTrace(p))
+	}
+	score := evaluator.(search.IEvaluator).Evaluate(p)
+	if c, ok := evaluator.(search.ICentipawnEvaluator); ok {
+		score = c.Centipawns(p, score)
+	}
+	fmt.Fprintf(w, "eval cp %d\n", score)
+}
+
+// printUCIok writes the engine's id and options to w, ending with uciok
+func (uci *UCI) printUCIok(w stdio.Writer) {
+	fmt.Fprintln(w, "id name MyGoEngine")
+	fmt.Fprintln(w, "id author Adam")
+	fmt.Fprintf(w, "option name OwnBook type check default %t\n", uci.engineHolder.UseBook)
+	fmt.Fprintf(w, "option name Hash type spin default %d min 1 max %d\n", engine.DefaultCacheSize, maxHashSize)
+	fmt.Fprintf(w, "option name Contempt type spin default 0 min -%d max %d\n", maxContempt, maxContempt)
+	fmt.Fprintf(w, "option name EndgameContempt type spin default 0 min -%d max %d\n", maxContempt, maxContempt)
+	fmt.Fprintf(w, "option name Swindle type check default %t\n", uci.engineHolder.Params.Swindle)
+	fmt.Fprintf(w, "option name DrawBand type spin default 0 min 0 max %d\n", maxDrawBand)
+	fmt.Fprintf(w, "option name TimeJitter type spin default 0 min 0 max %d\n", search.MaxTimeJitter)
+	fmt.Fprintf(w, "option name Skill Level type spin default %d min 0 max %d\n", search.MaxWeakness-uci.engineHolder.Params.Weakness, search.MaxWeakness)
+	fmt.Fprintln(w, "uciok")
 }
 
 func (uci *UCI) parseOption(line string) {
@@ -94,6 +143,34 @@ func (uci *UCI) parseOption(line string) {
 		switch tokens[i] {
 		case "book":
 			uci.parseBook(tokens[i+1])
+		case "Hash":
+			if i+2 < len(tokens) && tokens[i+1] == "value" {
+				uci.parseHash(tokens[i+2])
+			}
+		case "Contempt":
+			if i+2 < len(tokens) && tokens[i+1] == "value" {
+				uci.parseContempt(tokens[i+2])
+			}
+		case "EndgameContempt":
+			if i+2 < len(tokens) && tokens[i+1] == "value" {
+				uci.parseEndgameContempt(tokens[i+2])
+			}
+		case "Swindle":
+			if i+2 < len(tokens) && tokens[i+1] == "value" {
+				uci.parseSwindle(tokens[i+2])
+			}
+		case "DrawBand":
+			if i+2 < len(tokens) && tokens[i+1] == "value" {
+				uci.parseDrawBand(tokens[i+2])
+			}
+		case "TimeJitter":
+			if i+2 < len(tokens) && tokens[i+1] == "value" {
+				uci.parseTimeJitter(tokens[i+2])
+			}
+		case "Skill":
+			if i+3 < len(tokens) && tokens[i+1] == "Level" && tokens[i+2] == "value" {
+				uci.parseSkillLevel(tokens[i+3])
+			}
 		}
 	}
 }
@@ -102,13 +179,88 @@ func (uci *UCI) parseBook(line string) {
 	switch line {
 	case "true":
 		uci.engineHolder.UseBook = true
-		fmt.Printf("book turned on\n")
+		uci.engineHolder.Logger.Printf("book turned on\n")
 	case "false":
 		uci.engineHolder.UseBook = false
-		fmt.Printf("book turned off\n")
+		uci.engineHolder.Logger.Printf("book turned off\n")
+	default:
+		uci.engineHolder.Logger.Printf("Unknown book command expected true / false ")
+	}
+}
+
+func (uci *UCI) parseHash(value string) {
+	size, err := strconv.Atoi(value)
+	if err != nil || size < 1 || size > maxHashSize {
+		uci.engineHolder.Logger.Printf("Unknown hash size expected 1 - %d\n", maxHashSize)
+		return
+	}
+	uci.engineHolder.SetTranspositionTableSize(size)
+	uci.engineHolder.Logger.Printf("hash set to %dMB\n", size)
+}
+
+func (uci *UCI) parseContempt(value string) {
+	contempt, err := strconv.Atoi(value)
+	if err != nil || contempt < -maxContempt || contempt > maxContempt {
+		uci.engineHolder.Logger.Printf("Unknown contempt expected -%d - %d\n", maxContempt, maxContempt)
+		return
+	}
+	uci.engineHolder.Params.Contempt = contempt
+	uci.engineHolder.Logger.Printf("contempt set to %d\n", contempt)
+}
+
+func (uci *UCI) parseEndgameContempt(value string) {
+	contempt, err := strconv.Atoi(value)
+	if err != nil || contempt < -maxContempt || contempt > maxContempt {
+		uci.engineHolder.Logger.Printf("Unknown endgame contempt expected -%d - %d\n", maxContempt, maxContempt)
+		return
+	}
+	uci.engineHolder.Params.EndgameContempt = contempt
+	uci.engineHolder.Logger.Printf("endgame contempt set to %d\n", contempt)
+}
+
+func (uci *UCI) parseSwindle(value string) {
+	switch value {
+	case "true":
+		uci.engineHolder.Params.Swindle = true
+		uci.engineHolder.Logger.Printf("swindle turned on\n")
+	case "false":
+		uci.engineHolder.Params.Swindle = false
+		uci.engineHolder.Logger.Printf("swindle turned off\n")
 	default:
-		fmt.Printf("Unknown book command expected true / false ")
+		uci.engineHolder.Logger.Printf("Unknown swindle command expected true / false\n")
+	}
+}
+
+func (uci *UCI) parseDrawBand(value string) {
+	band, err := strconv.Atoi(value)
+	if err != nil || band < 0 || band > maxDrawBand {
+		uci.engineHolder.Logger.Printf("Unknown draw band expected 0 - %d\n", maxDrawBand)
+		return
+	}
+	uci.engineHolder.Params.DrawBand = band
+	uci.engineHolder.Logger.Printf("draw band set to %d\n", band)
+}
+
+func (uci *UCI) parseTimeJitter(value string) {
+	jitter, err := strconv.Atoi(value)
+	if err != nil || jitter < 0 || jitter > search.MaxTimeJitter {
+		uci.engineHolder.Logger.Printf("Unknown time jitter expected 0 - %d\n", search.MaxTimeJitter)
+		return
 	}
+	uci.engineHolder.Params.TimeJitter = jitter
+	uci.engineHolder.Logger.Printf("time jitter set to %d\n", jitter)
+}
+
+// parseSkillLevel sets the skill from 0, the weakest, to full strength at
+// search.MaxWeakness
+func (uci *UCI) parseSkillLevel(value string) {
+	level, err := strconv.Atoi(value)
+	if err != nil || level < 0 || level > search.MaxWeakness {
+		uci.engineHolder.Logger.Printf("Unknown skill level expected 0 - %d\n", search.MaxWeakness)
+		return
+	}
+	uci.engineHolder.Params.Weakness = search.MaxWeakness - level
+	uci.engineHolder.Logger.Printf("skill level set to %d\n", level)
 }
 
 func (uci *UCI) parseGo(line string, game engine.Game, info *data.SearchInfo) {
@@ -117,6 +269,11 @@ func (uci *UCI) parseGo(line string, game engine.Game, info *data.SearchInfo) {
 	info.MovesToGo = 30
 	info.Depth = -1
 	info.Time = -1
+	info.TimeSet = data.False
+	info.Infinite = data.False
+	info.NodeLimit = 0
+	info.SearchMoves = nil
+	info.ExcludeMoves = nil
 
 	for i := 0; i < len(tokens); i++ {
 		switch tokens[i] {
@@ -134,6 +291,18 @@ func (uci *UCI) parseGo(line string, game engine.Game, info *data.SearchInfo) {
 			uci.parseMoveTime(tokens[i+1], info)
 		case "depth":
 			uci.parseDepth(tokens[i+1], info)
+		case "infinite":
+			info.Infinite = data.True
+		case "nodes":
+			uci.parseNodes(tokens[i+1], info)
+		case "searchmoves":
+			moves := uci.parseMoves(tokens[i+1:], game)
+			info.SearchMoves = append(info.SearchMoves, moves...)
+			i += len(moves)
+		case "excludemoves":
+			moves := uci.parseMoves(tokens[i+1:], game)
+			info.ExcludeMoves = append(info.ExcludeMoves, moves...)
+			i += len(moves)
 		}
 	}
 
@@ -156,10 +325,10 @@ func (uci *UCI) parseGo(line string, game engine.Game, info *data.SearchInfo) {
 		info.Depth = data.MaxDepth
 	}
 
-	fmt.Printf("time:%d start:%d stop:%d depth:%d timeset:%v\n", info.Time, info.StartTime, info.StopTime, info.Depth, info.TimeSet)
+	uci.engineHolder.Logger.Printf("time:%d start:%d stop:%d depth:%d timeset:%v\n", info.Time, info.StartTime, info.StopTime, info.Depth, info.TimeSet)
 
 	for _, eng := range uci.engineHolder.Engines {
-		eng.Position = game.Position().Copy()
+		eng.SetPosition(game.Position().Copy())
 	}
 
 	uci.engineHolder.Ctx, uci.engineHolder.CancelSearch = context.WithCancel(context.Background())
@@ -196,41 +365,64 @@ func (uci *UCI) parseDepth(token string, info *data.SearchInfo) {
 	info.Depth = depth
 }
 
-func (uci *UCI) parsePosition(lineIn string, game engine.Game) {
-	parts := strings.Split(lineIn, " ")
-
-	if len(parts) < 2 {
-		panic(fmt.Errorf("UCI parsePosition: unexpected length %v", lineIn))
-	}
+func (uci *UCI) parseNodes(token string, info *data.SearchInfo) {
+	nodes, _ := strconv.ParseInt(token, 10, 64)
+	info.NodeLimit = nodes
+}
 
-	if parts[1] == "startpos" {
-		fmt.Printf("startpos called\n\n")
-		game.Position().ParseFen(data.StartFEN)
+// parseMoves reads the moves at the start of tokens up to the first token
+// that is not a move in the position
+func (uci *UCI) parseMoves(tokens []string, game engine.Game) []int {
+	var moves []int
+	for _, token := range tokens {
+		if len(token) < 4 {
+			break
+		}
+		move := game.Position().ParseMove([]byte(token))
+		if move == data.NoMove {
+			break
+		}
+		moves = append(moves, move)
 	}
+	return moves
+}
 
-	if parts[1] == "fen" {
-		fen := strings.Join(parts[2:], " ")
-		game.Position().ParseFen(fen)
-
+// parsePosition sets up the game from "position startpos" or "position fen"
+// and plays any moves after "moves", stopping at the first illegal move
+func (uci *UCI) parsePosition(lineIn string, game engine.Game) {
+	parts := strings.Fields(lineIn)
+	if len(parts) < 2 {
+		uci.engineHolder.Logger.Printf("UCI parsePosition: unexpected length %v\n", lineIn)
+		return
 	}
 
-	startIndex := 0
+	movesAt := len(parts)
 	for i, v := range parts {
 		if v == "moves" {
-			startIndex = i
+			movesAt = i
+			break
 		}
 	}
 
-	if startIndex != 0 {
-		for i := startIndex + 1; i < len(parts); i++ {
-			move := game.Position().ParseMove([]byte(parts[i]))
-			if move == data.NoMove {
-				fmt.Printf("UCI move error: Parsing UCI (%v) (%v) %v - %v\n", parts[i], lineIn, move, io.PrintMove(move))
-			}
-			game.Position().MakeMove(move)
-			game.Position().Positions[game.Position().PositionKey]++
-			game.Position().Play = 0
-			game.Position().PositionHistory.RemovePositionHistory()
+	switch parts[1] {
+	case "startpos":
+		game.Position().ParseFen(data.StartFEN)
+	case "fen":
+		if movesAt < 6 {
+			uci.engineHolder.Logger.Printf("UCI parsePosition: incomplete fen %v\n", lineIn)
+			return
+		}
+		game.Position().ParseFen(strings.Join(parts[2:movesAt], " "))
+	default:
+		uci.engineHolder.Logger.Printf("UCI parsePosition: unknown position %v\n", lineIn)
+		return
+	}
+
+	for i := movesAt + 1; i < len(parts); i++ {
+		move := game.Position().ParseMove([]byte(parts[i]))
+		if move == data.NoMove || !game.Position().MakeGameMove(move) {
+			uci.engineHolder.Logger.Printf("UCI parsePosition: illegal move %v in %v\n", parts[i], lineIn)
+			return
 		}
 	}
 }
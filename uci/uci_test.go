@@ -0,0 +1,166 @@
+package uci
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	stdio "io"
+	"log"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/AdamGriffiths31/ChessEngine/data"
+	"github.com/AdamGriffiths31/ChessEngine/engine"
+	"github.com/AdamGriffiths31/ChessEngine/eval"
+	"github.com/AdamGriffiths31/ChessEngine/search"
+	"github.com/AdamGriffiths31/ChessEngine/util"
+)
+
+func newTestUCI() *UCI {
+	h := search.NewEngineHolder(1, eval.Get("custom"))
+	h.Logger = log.New(stdio.Discard, "", 0)
+	return &UCI{h}
+}
+
+func TestParsePosition(t *testing.T) {
+	tests := []struct {
+		line      string
+		fen       string
+		positions int
+	}{
+		{"position startpos", data.StartFEN, 1},
+		{"position startpos moves e2e4 e7e5 g1f3 b8c6 f3g1 c6b8",
+			"rnbqkbnr/pppp1ppp/8/4p3/4P3/8/PPPP1PPP/RNBQKBNR w KQkq - 4 4", 7},
+		{"position fen 4k3/P7/8/8/8/8/8/4K3 w - - 0 1 moves a7a8q e8d7",
+			"Q7/3k4/8/8/8/8/8/4K3 w - - 1 2", 3},
+		{"position startpos moves e2e4 e2e4 e7e5", "rnbqkbnr/pppppppp/8/8/4P3/8/PPPP1PPP/RNBQKBNR b KQkq e3 0 1", 2},
+	}
+	for _, test := range tests {
+		uci := newTestUCI()
+		game := engine.ParseFen("8/8/8/8/8/8/8/K6k w - - 0 1")
+		uci.parsePosition(test.line, game)
+
+		p := game.Position()
+		if fen := p.ToFEN(); fen != test.fen {
+			t.Errorf("%v: expected %v but got %v", test.line, test.fen, fen)
+		}
+		positions := 0
+		for _, count := range p.Positions {
+			positions += count
+		}
+		if positions != test.positions {
+			t.Errorf("%v: expected %v positions in the history but got %v", test.line, test.positions, positions)
+		}
+	}
+}
+
+func TestParsePositionCountsRepetitions(t *testing.T) {
+	uci := newTestUCI()
+	game := engine.ParseFen(data.StartFEN)
+	uci.parsePosition("position startpos moves g1f3 g8f6 f3g1 f6g8 g1f3 g8f6 f3g1 f6g8", game)
+	if count := game.Position().Positions.Count(game.Position().PositionKey); count != 3 {
+		t.Errorf("Expected the start position 3 times but got %v", count)
+	}
+}
+
+func TestNewGameClearsSearchState(t *testing.T) {
+	uci := newTestUCI()
+	h := uci.engineHolder
+	h.UseBook = false
+	game := engine.ParseFen(data.StartFEN)
+	uci.parsePosition("position startpos moves e2e4 e7e5 g1f3", game)
+	for _, eng := range h.Engines {
+		eng.SetPosition(game.Position().Copy())
+	}
+	h.Search(&data.SearchInfo{Depth: 6, StartTime: util.GetTimeMs()})
+
+	e := h.Engines[0]
+	key := e.Position.PositionKey
+	if h.TranspositionTable.Probe(key) == data.NoMove || e.Position.MoveHistory.History == [13][120]int{} {
+		t.Fatalf("Expected the search to fill the table and history")
+	}
+
+	tt := h.TranspositionTable
+	game = uci.newGame()
+	if h.TranspositionTable != tt {
+		t.Errorf("Expected the transposition table to be cleared rather than reallocated")
+	}
+	if move := tt.Probe(key); move != data.NoMove {
+		t.Errorf("Expected the transposition table to be empty but found %v", move)
+	}
+	history := e.Position.MoveHistory
+	if history.History != [13][120]int{} || history.Killers != [engine.MaxKillers][data.MaxDepth]int{} || history.CounterMoves != [13][120]int{} {
+		t.Errorf("Expected the history, killer and countermove tables to be empty")
+	}
+	if game.Position().ToFEN() != data.StartFEN {
+		t.Errorf("Expected the start position but got %v", game.Position().ToFEN())
+	}
+}
+
+func TestUCIokFollowsOptions(t *testing.T) {
+	var out bytes.Buffer
+	newTestUCI().printUCIok(&out)
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if last := lines[len(lines)-1]; last != "uciok" {
+		t.Errorf("Expected uciok last but got %q", last)
+	}
+	if !strings.Contains(out.String(), "option name Hash") {
+		t.Errorf("Expected the options to be printed but got %v", out.String())
+	}
+}
+
+func TestPrintEval(t *testing.T) {
+	uci := newTestUCI()
+	game := engine.ParseFen("8/8/8/8/8/8/8/K6k w - - 0 1")
+	uci.parsePosition("position startpos", game)
+
+	var out bytes.Buffer
+	uci.printEval(&out, game)
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	last := lines[len(lines)-1]
+	score, err := strconv.Atoi(strings.TrimPrefix(last, "eval cp "))
+	if err != nil {
+		t.Fatalf("Expected a score but got %q", last)
+	}
+	if score < -50 || score > 50 {
+		t.Errorf("Expected a score near 0 but got %v", score)
+	}
+	if !strings.Contains(out.String(), "Mobility") {
+		t.Errorf("Expected the breakdown to be printed but got %v", out.String())
+	}
+}
+
+func TestBench(t *testing.T) {
+	uci := newTestUCI()
+	var out bytes.Buffer
+	nodes := uci.bench(&out, 3).Nodes
+	if nodes == 0 {
+		t.Fatalf("Expected bench to search some nodes")
+	}
+	if want := fmt.Sprintf("Nodes searched  : %d\n", nodes); !strings.Contains(out.String(), want) {
+		t.Errorf("Expected %q in %v", want, out.String())
+	}
+	if again := uci.bench(stdio.Discard, 3).Nodes; again != nodes {
+		t.Errorf("Expected the same node total twice but got %v and %v", nodes, again)
+	}
+}
+
+func TestBenchResultJSONRoundTrips(t *testing.T) {
+	r := BenchResult{Depth: 3, Nodes: 300, Time: time.Second, NPS: 300, Positions: []BenchPosition{
+		{FEN: data.StartFEN, Move: "e2e4", Score: "cp 30", Nodes: 300, Depth: 3, Time: time.Second, TTHitRate: 0.5},
+	}}
+	var b bytes.Buffer
+	if err := r.WriteJSON(&b); err != nil {
+		t.Fatal(err)
+	}
+	var got BenchResult
+	if err := json.Unmarshal(b.Bytes(), &got); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, r) {
+		t.Errorf("Expected %+v but got %+v", r, got)
+	}
+}
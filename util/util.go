@@ -25,3 +25,17 @@ func Min(l, r int) int {
 	}
 	return r
 }
+
+func Max(l, r int) int {
+	if l > r {
+		return l
+	}
+	return r
+}
+
+func Abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}